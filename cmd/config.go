@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"sentiment-service/internal/app/config"
+)
+
+// runConfigCommand 处理 config 子命令
+func runConfigCommand(ctx context.Context, opts config.LoadOptions, args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "用法: sentiment-service [--config 文件] [--env 环境] config validate")
+		return 2
+	}
+
+	conf, err := config.Load(opts)
+
+	var validationErr *config.ValidationError
+	if err != nil && !errors.As(err, &validationErr) {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return 1
+	}
+
+	// 打印解析后的配置（密钥已隐藏）
+	out, marshalErr := yaml.Marshal(conf.Masked())
+	if marshalErr != nil {
+		fmt.Fprintf(os.Stderr, "序列化配置失败: %v\n", marshalErr)
+		return 1
+	}
+	fmt.Print(string(out))
+
+	if validationErr != nil {
+		fmt.Fprintln(os.Stderr, validationErr.Error())
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "配置校验通过")
+	return 0
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"sentiment-service/internal/app"
+	"sentiment-service/internal/app/config"
+	"sentiment-service/internal/services"
+)
+
+// runExportCommand 导出分析记录到标准输出或文件
+func runExportCommand(ctx context.Context, opts config.LoadOptions, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	var filter filterFlags
+	filter.register(fs)
+	format := fs.String("format", services.ExportFormatJSONL, "导出格式: jsonl 或 csv")
+	output := fs.String("output", "", "输出文件路径（默认标准输出）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	conf, err := app.Bootstrap(opts, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	maintenance, closeAll, err := app.NewMaintenance(conf, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeAll()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建输出文件失败: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+
+	buffered := bufio.NewWriter(out)
+	exported, err := maintenance.Export(ctx, filter.filter(), *format, buffered)
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导出失败（已导出 %d 条）: %v\n", exported, err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "已导出 %d 条记录\n", exported)
+	return 0
+}
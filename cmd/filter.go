@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"sentiment-service/internal/services"
+)
+
+// timeFlag 接受 RFC3339 时间或 2006-01-02 日期的命令行参数
+type timeFlag struct {
+	value *time.Time
+}
+
+// String 实现 flag.Value
+func (f *timeFlag) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return f.value.Format(time.RFC3339)
+}
+
+// Set 实现 flag.Value
+func (f *timeFlag) Set(s string) error {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			f.value = &t
+			return nil
+		}
+	}
+	return fmt.Errorf("无效的时间 %q（格式: 2006-01-02 或 RFC3339）", s)
+}
+
+// filterFlags 选择分析记录的通用参数
+type filterFlags struct {
+	userID    string
+	sentiment string
+	since     timeFlag
+	until     timeFlag
+}
+
+// register 在参数集中注册过滤参数
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.userID, "user", "", "只处理该用户的记录")
+	fs.StringVar(&f.sentiment, "sentiment", "", "只处理该情感标签的记录")
+	fs.Var(&f.since, "since", "只处理该时间之后创建的记录")
+	fs.Var(&f.until, "until", "只处理该时间之前创建的记录")
+}
+
+// filter 转换为服务层的过滤条件
+func (f *filterFlags) filter() services.RecordFilter {
+	return services.RecordFilter{
+		UserID:    f.userID,
+		Sentiment: f.sentiment,
+		Since:     f.since.value,
+		Until:     f.until.value,
+	}
+}
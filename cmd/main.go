@@ -1,36 +1,78 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
-	"github.com/sirupsen/logrus"
-
-	"sentiment-service/internal/app"
+	"sentiment-service/internal/app/config"
 )
 
+// command 子命令
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, opts config.LoadOptions, args []string) int
+}
+
+// commands 所有子命令，按帮助信息中的显示顺序排列
+var commands = []command{
+	{"serve", "启动HTTP服务（默认命令）", runServeCommand},
+	{"worker", "消费异步任务队列并调用算法服务", runWorkerCommand},
+	{"migrate", "管理数据库迁移", runMigrateCommand},
+	{"reanalyze", "使用当前算法服务重新分析已存储的文本", runReanalyzeCommand},
+	{"export", "导出分析记录（jsonl 或 csv）", runExportCommand},
+	{"purge", "删除过期的分析记录", runPurgeCommand},
+	{"config", "校验并打印解析后的配置", runConfigCommand},
+}
+
 func main() {
+	var opts config.LoadOptions
+	flag.StringVar(&opts.ConfigFile, "config", "", "额外叠加的配置文件路径（优先级高于环境配置文件）")
+	flag.StringVar(&opts.Env, "env", "", "环境名称，叠加 configs/config_<env>.yaml（默认读取 APP_ENV）")
+	flag.Usage = usage
+	flag.Parse()
+
 	// 捕获终止信号，用于优雅关闭
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	// 启动服务（在单独的 goroutine 中）
-	errChan := make(chan error, 1)
-	go func() {
-		if err := app.Start(); err != nil {
-			errChan <- err
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 不带子命令时启动HTTP服务，兼容原有的启动方式
+	name, args := "serve", []string{}
+	if flag.NArg() > 0 {
+		name, args = flag.Arg(0), flag.Args()[1:]
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			code := cmd.run(ctx, opts, args)
+			stop()
+			os.Exit(code)
 		}
-	}()
-
-	// 等待终止信号或错误
-	select {
-	case <-c:
-		logrus.Info("收到终止信号，正在优雅关闭...")
-	case err := <-errChan:
-		logrus.WithError(err).Fatal("服务启动失败")
 	}
 
-	// 这里可以添加额外的清理代码
-	logrus.Info("服务已关闭")
+	if name == "help" {
+		usage()
+		os.Exit(0)
+	}
+
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// usage 打印全局帮助信息
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "用法: sentiment-service [--config 文件] [--env 环境] <命令> [参数]")
+	fmt.Fprintln(out, "\n命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s  %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out, "\n全局参数:")
+	flag.PrintDefaults()
+	fmt.Fprintln(out, "\n使用 sentiment-service <命令> -h 查看命令参数")
 }
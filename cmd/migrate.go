@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"sentiment-service/internal/app"
+	"sentiment-service/internal/app/config"
+	"sentiment-service/internal/app/initializer"
+	"sentiment-service/internal/migrations"
+)
+
+const migrateUsage = `用法: sentiment-service migrate <命令>
+
+命令:
+  status        显示所有迁移及执行状态
+  up            执行所有未执行的迁移
+  down [N]      回滚最近的 N 个迁移（默认 1）
+  to <版本>     迁移到指定版本（0 表示回滚全部）`
+
+// runMigrateCommand 处理 migrate 子命令
+func runMigrateCommand(ctx context.Context, opts config.LoadOptions, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	conf, err := app.Bootstrap(opts, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	db, err := initializer.NewDB(conf.Database, conf.Log.Level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer initializer.CloseDB(db)
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	migrator, err := migrations.New(sqlDB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				fmt.Fprintf(os.Stderr, "无效的回滚步数: %s\n", args[1])
+				return 2
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil {
+			fmt.Fprintf(os.Stderr, "无效的版本号: %s\n", args[1])
+			return 2
+		}
+		err = migrator.To(ctx, version)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "迁移失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printMigrationStatus 以表格形式打印迁移状态
+func printMigrationStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"sentiment-service/internal/app"
+	"sentiment-service/internal/app/config"
+)
+
+// runPurgeCommand 删除过期的分析记录
+func runPurgeCommand(ctx context.Context, opts config.LoadOptions, args []string) int {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	var filter filterFlags
+	filter.register(fs)
+	olderThan := fs.Duration("older-than", 0, "删除早于该时长之前创建的记录（例如 720h），与 --until 二选一")
+	hard := fs.Bool("hard", false, "物理删除（默认软删除，只标记 deleted_at）")
+	dryRun := fs.Bool("dry-run", false, "只统计将被删除的记录数")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *olderThan > 0 {
+		if filter.until.value != nil {
+			fmt.Fprintln(os.Stderr, "--older-than 和 --until 不能同时使用")
+			return 2
+		}
+		cutoff := time.Now().Add(-*olderThan)
+		filter.until.value = &cutoff
+	}
+
+	// 防止误删全部数据
+	if filter.until.value == nil {
+		fmt.Fprintln(os.Stderr, "必须指定 --older-than 或 --until")
+		return 2
+	}
+
+	conf, err := app.Bootstrap(opts, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	maintenance, closeAll, err := app.NewMaintenance(conf, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeAll()
+
+	count, err := maintenance.Purge(ctx, filter.filter(), *hard, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "删除失败: %v\n", err)
+		return 1
+	}
+
+	if *dryRun {
+		fmt.Printf("将删除 %d 条记录（截止 %s）\n", count, filter.until.String())
+	} else {
+		fmt.Printf("已删除 %d 条记录（截止 %s）\n", count, filter.until.String())
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"sentiment-service/internal/app"
+	"sentiment-service/internal/app/config"
+	"sentiment-service/internal/services"
+)
+
+// runReanalyzeCommand 使用当前算法服务重新分析已存储的文本
+func runReanalyzeCommand(ctx context.Context, opts config.LoadOptions, args []string) int {
+	fs := flag.NewFlagSet("reanalyze", flag.ContinueOnError)
+	var filter filterFlags
+	filter.register(fs)
+	batchSize := fs.Int("batch-size", 100, "每次从数据库读取的记录数")
+	limit := fs.Int("limit", 0, "最多处理的记录数（0表示不限制）")
+	dryRun := fs.Bool("dry-run", false, "只统计结果变化，不写回数据库")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	conf, err := app.Bootstrap(opts, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	maintenance, closeAll, err := app.NewMaintenance(conf, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeAll()
+
+	summary, err := maintenance.Reanalyze(ctx, services.ReanalyzeOptions{
+		Filter:    filter.filter(),
+		BatchSize: *batchSize,
+		Limit:     *limit,
+		Timeout:   conf.Algorithm.Timeout,
+		DryRun:    *dryRun,
+	})
+	if summary != nil {
+		fmt.Printf("扫描: %d, 结果变化: %d, 已更新: %d, 失败: %d\n",
+			summary.Scanned, summary.Changed, summary.Updated, summary.Failed)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "重新分析失败: %v\n", err)
+		return 1
+	}
+	if summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/app"
+	"sentiment-service/internal/app/config"
+)
+
+// runServeCommand 启动HTTP服务，收到信号后会排空HTTP请求和异步任务再关闭连接
+func runServeCommand(ctx context.Context, opts config.LoadOptions, args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := app.Run(ctx, opts); err != nil {
+		logrus.WithError(err).Error("服务运行失败")
+		return 1
+	}
+
+	logrus.Info("服务已关闭")
+	return 0
+}
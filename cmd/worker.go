@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/app"
+	"sentiment-service/internal/app/config"
+)
+
+// runWorkerCommand 运行异步任务处理器，收到信号后等待进行中的任务完成再退出
+func runWorkerCommand(ctx context.Context, opts config.LoadOptions, args []string) int {
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	concurrency := fs.Int("concurrency", 4, "同时处理的任务数")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	conf, err := app.Bootstrap(opts, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := app.RunWorker(ctx, conf, *concurrency); err != nil {
+		logrus.WithError(err).Error("任务处理器运行失败")
+		return 1
+	}
+	return 0
+}
@@ -3,65 +3,94 @@ package v1
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
 
 	"sentiment-service/internal/app/config"
 	"sentiment-service/internal/controllers"
-	"sentiment-service/internal/repositories"
+	"sentiment-service/internal/middleware"
 	"sentiment-service/internal/services"
 )
 
-// SetupRoutes 设置API路由
-func SetupRoutes(r *gin.Engine, db *gorm.DB) {
-	// 创建存储库
-	repo := repositories.NewSentimentRepository(db)
-
-	// 获取配置信息
-	grpcEndpoint := config.Conf.Algorithm.Endpoint
-	rabbitmqURL := config.Conf.RabbitMQ.URL
-	taskQueue := config.Conf.RabbitMQ.TaskQueue
-	resultQueue := config.Conf.RabbitMQ.ResultQueue
-
-	// 记录配置信息
-	logrus.WithFields(logrus.Fields{
-		"grpc_endpoint": grpcEndpoint,
-		"rabbitmq_url":  rabbitmqURL,
-		"task_queue":    taskQueue,
-		"result_queue":  resultQueue,
-	}).Info("正在设置服务连接")
-
-	// 创建服务
-	service, err := services.NewSentimentService(
-		repo,
-		grpcEndpoint,
-		rabbitmqURL,
-		taskQueue,
-		resultQueue,
-	)
-	if err != nil {
-		logrus.Fatalf("初始化情感分析服务失败: %v", err)
-	}
-
+// SetupRoutes 设置API路由，限流和功能开关读取 runtime 中的最新配置
+// alerts 为nil时不注册告警接口
+func SetupRoutes(r *gin.Engine, service *services.SentimentService, alerts *services.AlertService, runtime *config.Store) {
 	// 创建控制器
 	controller := controllers.NewSentimentController(service)
 
+	// 功能开关
+	features := func() config.FeaturesConfig { return runtime.Current().Features }
+	asyncEnabled := middleware.RequireFeature("async_analysis", func() bool { return features().AsyncAnalysis })
+	batchEnabled := middleware.RequireFeature("batch_analysis", func() bool { return features().BatchAnalysis })
+	historyEnabled := middleware.RequireFeature("history", func() bool { return features().History })
+
 	// 设置API组
 	api := r.Group("/api/v1")
 	{
-		// 情感分析API
+		// 情感分析API受限流保护，健康检查不受影响
 		sentiment := api.Group("/sentiment")
+		sentiment.Use(middleware.RateLimit(func() config.RateLimitConfig { return runtime.Current().RateLimit }))
 		{
 			// 同步分析接口（使用gRPC）
 			sentiment.POST("/analyze", controller.AnalyzeSentiment)
 
 			// 异步分析接口（使用RabbitMQ）
-			sentiment.POST("/analyze/async", controller.AnalyzeSentimentAsync)
+			sentiment.POST("/analyze/async", asyncEnabled, controller.AnalyzeSentimentAsync)
 
 			// 批量分析接口（使用gRPC流）
-			sentiment.POST("/batch", controller.BatchAnalyzeSentiment)
+			sentiment.POST("/batch", batchEnabled, controller.BatchAnalyzeSentiment)
+
+			// 异步批量分析接口（后台分块处理，通过批处理查询接口获取进度）
+			sentiment.POST("/batch/async", batchEnabled, controller.SubmitBatch)
+
+			// 上传CSV/JSONL文件进行异步批量分析
+			uploadLimit := middleware.BodyLimit(func() int64 { return runtime.Current().Batch.MaxUploadSize })
+			sentiment.POST("/batch/upload", batchEnabled, uploadLimit, controller.UploadBatch)
 
 			// 历史记录查询
-			sentiment.GET("/history", controller.GetAnalysisHistory)
+			sentiment.GET("/history", historyEnabled, controller.GetAnalysisHistory)
+
+			// 全文检索
+			sentiment.GET("/search", historyEnabled, controller.SearchAnalyses)
+
+			// 统计分析
+			sentiment.GET("/stats/trend", historyEnabled, controller.GetSentimentTrend)
+			sentiment.GET("/stats/keywords/top", historyEnabled, controller.GetTopKeywords)
+			sentiment.GET("/stats/keywords/sentiment", historyEnabled, controller.GetKeywordSentiment)
+			sentiment.GET("/stats/keywords/rising", historyEnabled, controller.GetRisingKeywords)
+			sentiment.GET("/stats/breakdown", historyEnabled, controller.GetMetadataBreakdown)
+
+			// 单条分析记录查询
+			sentiment.GET("/analyses/:id", historyEnabled, controller.GetAnalysis)
+			sentiment.GET("/analyses/by-request/:request_id", historyEnabled, controller.GetAnalysisByRequestID)
+
+			// 批处理状态和结果查询
+			sentiment.GET("/batches", historyEnabled, controller.ListBatches)
+			sentiment.GET("/batches/:batch_id", historyEnabled, controller.GetBatch)
+			sentiment.GET("/batches/:batch_id/results", historyEnabled, controller.GetBatchResults)
+			sentiment.GET("/batches/:batch_id/results/download", historyEnabled, controller.DownloadBatchResults)
+		}
+
+		// 告警规则和告警管理
+		if alerts != nil {
+			alertController := controllers.NewAlertController(alerts)
+
+			rules := api.Group("/alert-rules")
+			{
+				rules.POST("", alertController.CreateRule)
+				rules.GET("", alertController.ListRules)
+				rules.GET("/:id", alertController.GetRule)
+				rules.PUT("/:id", alertController.UpdateRule)
+				rules.DELETE("/:id", alertController.DeleteRule)
+				// 按当前数据评估规则，不产生告警
+				rules.POST("/:id/evaluate", alertController.EvaluateRule)
+			}
+
+			alertRoutes := api.Group("/alerts")
+			{
+				alertRoutes.GET("", alertController.ListAlerts)
+				alertRoutes.GET("/:id", alertController.GetAlert)
+				alertRoutes.POST("/:id/acknowledge", alertController.AcknowledgeAlert)
+				alertRoutes.POST("/:id/resolve", alertController.ResolveAlert)
+			}
 		}
 
 		// 健康检查API
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/app/config"
+	"sentiment-service/internal/app/initializer"
+	"sentiment-service/internal/grpc"
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/mq"
+	"sentiment-service/internal/repositories"
+	"sentiment-service/internal/services"
+)
+
+// RunWorker 消费任务队列，调用算法服务分析并发布结果，直到ctx结束
+// 与 python-service 的 worker 使用相同的消息格式，可以替代或与之并存
+func RunWorker(ctx context.Context, conf *config.Config, concurrency int) error {
+	analyzer, err := grpc.NewSentimentClient(conf.Algorithm.Endpoint)
+	if err != nil {
+		return fmt.Errorf("初始化gRPC客户端失败: %v", err)
+	}
+	defer analyzer.Close()
+
+	worker, err := mq.NewTaskWorker(
+		conf.RabbitMQ.URL,
+		conf.RabbitMQ.TaskQueue,
+		conf.RabbitMQ.ResultQueue,
+		concurrency,
+	)
+	if err != nil {
+		return err
+	}
+	defer worker.Close()
+
+	return worker.Run(ctx, func(ctx context.Context, task mq.Task) (*models.SentimentResult, error) {
+		if conf.Algorithm.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, conf.Algorithm.Timeout)
+			defer cancel()
+		}
+
+		response, err := analyzer.AnalyzeSentiment(ctx, task.Text, task.Language, task.RequestID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.SentimentResult{
+			Text:             task.Text,
+			Sentiment:        response.Sentiment,
+			Score:            response.Score,
+			ConfidenceScores: response.ConfidenceScores,
+			Keywords:         response.Keywords,
+			RequestID:        task.RequestID,
+		}, nil
+	})
+}
+
+// NewMaintenance 为运维命令创建维护服务，withAnalyzer 为 true 时同时连接算法服务
+// 返回的关闭函数释放所有连接
+func NewMaintenance(conf *config.Config, withAnalyzer bool) (*services.MaintenanceService, func(), error) {
+	db, err := initializer.NewDB(conf.Database, conf.Log.Level)
+	if err != nil {
+		return nil, nil, fmt.Errorf("数据库初始化错误: %v", err)
+	}
+
+	closers := []func(){func() {
+		if err := initializer.CloseDB(db); err != nil {
+			logrus.WithError(err).Warn("关闭数据库连接失败")
+		}
+	}}
+	closeAll := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+
+	var analyzer services.Analyzer
+	if withAnalyzer {
+		client, err := grpc.NewSentimentClient(conf.Algorithm.Endpoint)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("初始化gRPC客户端失败: %v", err)
+		}
+		analyzer = client
+		closers = append(closers, func() { client.Close() })
+	}
+
+	return services.NewMaintenanceService(repositories.NewSentimentRepository(db), analyzer), closeAll, nil
+}
@@ -0,0 +1,441 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"sentiment-service/internal/api/v1"
+	"sentiment-service/internal/app/config"
+	"sentiment-service/internal/app/initializer"
+	"sentiment-service/internal/grpc"
+	"sentiment-service/internal/middleware"
+	"sentiment-service/internal/mq"
+	"sentiment-service/internal/repositories"
+	"sentiment-service/internal/services"
+	"sentiment-service/internal/webhook"
+	"sentiment-service/internal/writebehind"
+)
+
+// defaultShutdownTimeout 未配置关闭超时时使用的默认值
+const defaultShutdownTimeout = 30 * time.Second
+
+// App 应用容器，持有配置和所有依赖
+// 未通过 Option 注入的依赖会根据配置创建，并在 Stop 时由 App 负责关闭；
+// 注入的依赖由调用方管理生命周期
+type App struct {
+	Config     *config.Config
+	Runtime    *config.Store
+	DB         *gorm.DB
+	Redis      *redis.Client
+	Analyzer   services.Analyzer
+	Queue      services.TaskQueue
+	Repository repositories.SentimentRepository
+	Service    *services.SentimentService
+	// 告警服务，仅在有数据库连接时创建（注入存储库时为nil，不注册告警接口）
+	Alerts *services.AlertService
+
+	writer    *writebehind.Writer
+	router    *gin.Engine
+	server    *http.Server
+	listener  net.Listener
+	serveErr  chan error
+	lifecycle *Lifecycle
+}
+
+// Option 配置 App 的依赖注入选项
+type Option func(*App)
+
+// WithRuntimeConfig 注入可热更新的配置存储（默认使用不监听文件的静态存储）
+func WithRuntimeConfig(store *config.Store) Option {
+	return func(a *App) { a.Runtime = store }
+}
+
+// WithDB 注入数据库连接
+func WithDB(db *gorm.DB) Option {
+	return func(a *App) { a.DB = db }
+}
+
+// WithRedis 注入Redis客户端
+func WithRedis(client *redis.Client) Option {
+	return func(a *App) { a.Redis = client }
+}
+
+// WithAnalyzer 注入情感分析算法客户端
+func WithAnalyzer(analyzer services.Analyzer) Option {
+	return func(a *App) { a.Analyzer = analyzer }
+}
+
+// WithQueue 注入异步任务队列
+func WithQueue(queue services.TaskQueue) Option {
+	return func(a *App) { a.Queue = queue }
+}
+
+// WithRepository 注入情感分析存储库（注入后不再需要数据库连接）
+func WithRepository(repo repositories.SentimentRepository) Option {
+	return func(a *App) { a.Repository = repo }
+}
+
+// New 根据配置创建应用，初始化失败时释放已创建的资源
+func New(conf *config.Config, opts ...Option) (*App, error) {
+	a := &App{Config: conf}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.Runtime == nil {
+		a.Runtime = config.NewStore(conf)
+	}
+
+	// 按依赖顺序创建缺失的组件，关闭顺序单独注册
+	var owned ownedComponents
+	if err := a.buildDependencies(&owned); err != nil {
+		owned.close()
+		return nil, err
+	}
+
+	a.Service = services.NewSentimentService(a.Repository, a.Analyzer, a.Queue)
+	a.Service.SetOptions(serviceOptions(a.Runtime.Current()))
+	a.Runtime.OnChange(func(old, new *config.Config) {
+		a.Service.SetOptions(serviceOptions(new))
+	})
+
+	if conf.WriteBehind.Enabled {
+		writer, err := writebehind.New(a.Repository, writeBehindOptions(conf.WriteBehind))
+		if err != nil {
+			owned.close()
+			return nil, fmt.Errorf("初始化异步写入失败: %v", err)
+		}
+		a.writer = writer
+		a.Service.SetResultWriter(writer)
+		logrus.WithField("journal_dir", conf.WriteBehind.JournalDir).Info("分析结果异步写入已启用")
+	}
+
+	if a.DB != nil {
+		a.Alerts = services.NewAlertService(
+			repositories.NewAlertRepository(a.DB),
+			a.Repository,
+			webhook.New(webhookOptions(conf.Alerting)),
+		)
+		if conf.Alerting.Enabled {
+			a.Alerts.Start(conf.Alerting.EvaluationInterval)
+		}
+	}
+
+	// 清理进程崩溃或关闭超时后遗留的未结束批处理，关闭时在 drain-batch-jobs 中停止
+	a.Service.StartBatchJanitor(services.BatchHeartbeatInterval)
+
+	a.router = a.buildRouter()
+	a.lifecycle = a.buildLifecycle(owned)
+
+	return a, nil
+}
+
+// serviceOptions 从配置中提取服务的运行时参数
+func serviceOptions(conf *config.Config) services.Options {
+	return services.Options{
+		AnalyzeTimeout:       conf.Algorithm.Timeout,
+		BatchTimeout:         conf.Algorithm.BatchTimeout,
+		QueueOnAnalyzerError: conf.Fallback.QueueOnAnalyzerError,
+		ChunkSize:            conf.Batch.ChunkSize,
+		Streams:              conf.Batch.Streams,
+		MaxInFlight:          conf.Batch.MaxInFlight,
+		MaxAsyncTexts:        conf.Batch.MaxAsyncTexts,
+	}
+}
+
+// writeBehindOptions 从配置中提取异步写入的参数
+func writeBehindOptions(conf config.WriteBehindConfig) writebehind.Options {
+	return writebehind.Options{
+		QueueSize:      conf.QueueSize,
+		BatchSize:      conf.BatchSize,
+		FlushInterval:  conf.FlushInterval,
+		MaxRetries:     conf.MaxRetries,
+		RetryBackoff:   conf.RetryBackoff,
+		JournalDir:     conf.JournalDir,
+		ReplayInterval: conf.ReplayInterval,
+	}
+}
+
+// webhookOptions 从配置中提取告警通知的参数
+func webhookOptions(conf config.AlertingConfig) webhook.Options {
+	return webhook.Options{
+		Timeout:             conf.WebhookTimeout,
+		MaxRetries:          conf.WebhookMaxRetries,
+		RetryBackoff:        conf.WebhookRetryBackoff,
+		Secret:              conf.WebhookSecret,
+		AllowPrivateTargets: conf.WebhookAllowPrivateTargets,
+	}
+}
+
+// ownedComponents 记录由 App 创建、需要由 App 关闭的组件
+type ownedComponents struct {
+	db       *gorm.DB
+	redis    *redis.Client
+	analyzer services.Analyzer
+	queue    services.TaskQueue
+}
+
+// close 在初始化失败时按创建的逆序释放资源
+func (o ownedComponents) close() {
+	if o.queue != nil {
+		o.queue.Close(context.Background())
+	}
+	if o.analyzer != nil {
+		o.analyzer.Close()
+	}
+	if o.redis != nil {
+		o.redis.Close()
+	}
+	if o.db != nil {
+		initializer.CloseDB(o.db)
+	}
+}
+
+// buildDependencies 创建未注入的依赖
+func (a *App) buildDependencies(owned *ownedComponents) error {
+	if a.Repository == nil {
+		if a.DB == nil {
+			db, err := initializer.NewDB(a.Config.Database, a.Config.Log.Level)
+			if err != nil {
+				return fmt.Errorf("数据库初始化错误: %v", err)
+			}
+			a.DB = db
+			owned.db = db
+
+			if err := initializer.RunStartupMigrations(db, a.Config.App.Mode); err != nil {
+				return err
+			}
+		}
+		a.Repository = repositories.NewSentimentRepository(a.DB)
+	}
+
+	if a.Redis == nil {
+		client, err := initializer.NewRedis(a.Config.Redis)
+		if err != nil {
+			// Redis不是必需的
+			logrus.Warnf("Redis初始化错误（非致命）: %v", err)
+		} else {
+			a.Redis = client
+			owned.redis = client
+		}
+	}
+
+	// 记录配置信息
+	logrus.WithFields(logrus.Fields{
+		"grpc_endpoint": a.Config.Algorithm.Endpoint,
+		"rabbitmq_url":  a.Config.RabbitMQ.URL,
+		"task_queue":    a.Config.RabbitMQ.TaskQueue,
+		"result_queue":  a.Config.RabbitMQ.ResultQueue,
+	}).Info("正在设置服务连接")
+
+	if a.Analyzer == nil {
+		client, err := grpc.NewSentimentClient(a.Config.Algorithm.Endpoint)
+		if err != nil {
+			return fmt.Errorf("初始化gRPC客户端失败: %v", err)
+		}
+		logrus.Info("gRPC客户端初始化成功")
+		a.Analyzer = client
+		owned.analyzer = client
+	}
+
+	if a.Queue == nil {
+		queue, err := mq.NewSentimentMQ(
+			a.Config.RabbitMQ.URL,
+			a.Config.RabbitMQ.TaskQueue,
+			a.Config.RabbitMQ.ResultQueue,
+		)
+		if err != nil {
+			return fmt.Errorf("初始化MQ客户端失败: %v", err)
+		}
+		logrus.Info("RabbitMQ客户端初始化成功")
+		a.Queue = queue
+		owned.queue = queue
+	}
+
+	return nil
+}
+
+// buildRouter 创建Gin引擎并注册中间件和路由
+func (a *App) buildRouter() *gin.Engine {
+	// 设置Gin模式
+	if a.Config.App.Mode == "prod" {
+		gin.SetMode(gin.ReleaseMode)
+	} else {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	// 创建Gin引擎
+	r := gin.New()
+
+	// 应用中间件
+	r.Use(middleware.Logger())
+	r.Use(middleware.Recovery())
+	r.Use(middleware.ErrorHandler())
+
+	// Prometheus 指标
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// 设置路由
+	v1.SetupRoutes(r, a.Service, a.Alerts, a.Runtime)
+
+	return r
+}
+
+// buildLifecycle 注册关闭步骤，顺序即执行顺序
+func (a *App) buildLifecycle(owned ownedComponents) *Lifecycle {
+	lifecycle := NewLifecycle()
+
+	lifecycle.OnShutdown("stop-async-intake", func(ctx context.Context) error {
+		a.Service.StopAcceptingAsync()
+		return nil
+	})
+	lifecycle.OnShutdown("http", func(ctx context.Context) error {
+		if a.server == nil {
+			return nil
+		}
+		return a.server.Shutdown(ctx)
+	})
+	lifecycle.OnShutdown("drain-batch-jobs", func(ctx context.Context) error {
+		if !a.Service.DrainBatchJobs(ctx) {
+			logrus.Warn("关闭超时，已取消进行中的批处理任务")
+		}
+		return nil
+	})
+	lifecycle.OnShutdown("drain-async-callbacks", func(ctx context.Context) error {
+		if abandoned := a.Service.DrainAsync(ctx); len(abandoned) > 0 {
+			logrus.WithFields(logrus.Fields{
+				"count":       len(abandoned),
+				"request_ids": abandoned,
+			}).Warn("关闭超时，放弃等待以下异步任务的结果")
+		}
+		return nil
+	})
+	if a.Alerts != nil {
+		// 在关闭数据库之前停止评估，并等待进行中的告警通知记录结果
+		lifecycle.OnShutdown("stop-alert-evaluator", func(ctx context.Context) error {
+			if !a.Alerts.Stop(ctx) {
+				logrus.Warn("关闭超时，已取消进行中的告警评估和通知")
+			}
+			return nil
+		})
+	}
+	if owned.analyzer != nil {
+		// HTTP请求和批处理任务都已结束，之后不再调用算法服务；结果消费不依赖gRPC
+		lifecycle.OnShutdown("grpc", func(ctx context.Context) error {
+			return owned.analyzer.Close()
+		})
+	}
+	if owned.queue != nil {
+		// 先停止结果消费，之后不会再有回调向写入队列添加记录
+		lifecycle.OnShutdown("mq", func(ctx context.Context) error {
+			return owned.queue.Close(ctx)
+		})
+	}
+	if a.writer != nil {
+		// 在关闭数据库之前写入队列中剩余的分析记录，超时后剩余记录写入本地日志文件
+		lifecycle.OnShutdown("flush-write-behind", func(ctx context.Context) error {
+			return a.writer.Close(ctx)
+		})
+	}
+	if owned.db != nil {
+		lifecycle.OnShutdown("database", func(ctx context.Context) error {
+			return initializer.CloseDB(owned.db)
+		})
+	}
+	if owned.redis != nil {
+		lifecycle.OnShutdown("redis", func(ctx context.Context) error {
+			return owned.redis.Close()
+		})
+	}
+
+	return lifecycle
+}
+
+// Router 返回HTTP处理器，可直接用于 httptest
+func (a *App) Router() http.Handler {
+	return a.router
+}
+
+// Start 开始监听配置的地址并在后台处理请求
+func (a *App) Start() error {
+	addr := fmt.Sprintf("%s:%d", a.Config.App.Addr, a.Config.App.Port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("监听地址 %s 失败: %v", addr, err)
+	}
+
+	a.listener = listener
+	a.server = &http.Server{Handler: a.router}
+	a.serveErr = make(chan error, 1)
+
+	go func() {
+		logrus.Infof("服务器启动，监听地址 %s", listener.Addr())
+		if err := a.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.serveErr <- err
+		}
+		close(a.serveErr)
+	}()
+
+	return nil
+}
+
+// Addr 返回实际监听地址（端口配置为0时用于获取随机端口）
+func (a *App) Addr() string {
+	if a.listener == nil {
+		return ""
+	}
+	return a.listener.Addr().String()
+}
+
+// Stop 执行优雅关闭，ctx 决定最长等待时间
+func (a *App) Stop(ctx context.Context) error {
+	return a.lifecycle.Shutdown(ctx)
+}
+
+// Run 启动服务并阻塞，直到ctx结束或服务器出错，然后在配置的超时内优雅关闭
+func (a *App) Run(ctx context.Context) error {
+	if err := a.Start(); err != nil {
+		a.stopWithTimeout()
+		return err
+	}
+
+	// 等待终止信号或服务器错误
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logrus.Info("收到终止信号，正在优雅关闭...")
+	case err := <-a.serveErr:
+		if err != nil {
+			runErr = fmt.Errorf("HTTP服务器错误: %v", err)
+		}
+	}
+
+	if err := a.stopWithTimeout(); err != nil {
+		logrus.WithError(err).Error("优雅关闭过程中出现错误")
+	}
+
+	return runErr
+}
+
+// stopWithTimeout 在配置的超时时间内执行关闭步骤
+func (a *App) stopWithTimeout() error {
+	timeout := defaultShutdownTimeout
+	if a.Config.App.ShutdownTimeout > 0 {
+		timeout = a.Config.App.ShutdownTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logrus.WithField("timeout", timeout.String()).Info("开始优雅关闭")
+	return a.Stop(ctx)
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"sentiment-service/internal/app/config"
+	sentimentv1 "sentiment-service/internal/gen/sentiment/v1"
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/mq"
+	"sentiment-service/internal/repositories"
+)
+
+// fakeAnalyzer 总是返回正面结果的算法服务
+type fakeAnalyzer struct{}
+
+func (fakeAnalyzer) AnalyzeSentiment(_ context.Context, text, language, requestID string) (*sentimentv1.SentimentResponse, error) {
+	return &sentimentv1.SentimentResponse{
+		RequestId: requestID,
+		Sentiment: "positive",
+		Score:     0.9,
+		Keywords:  []string{text},
+	}, nil
+}
+
+func (fakeAnalyzer) BatchAnalyzeStream(context.Context) (sentimentv1.SentimentAnalyzer_BatchAnalyzeSentimentClient, error) {
+	return nil, errors.New("测试中不支持批量分析")
+}
+
+func (fakeAnalyzer) Close() error {
+	return nil
+}
+
+// fakeQueue 不发布任务的异步任务队列
+type fakeQueue struct{}
+
+func (fakeQueue) PublishTask(_ context.Context, requestID, _, _ string, _ mq.ResultCallback) (string, error) {
+	return requestID, nil
+}
+
+func (fakeQueue) Drain(context.Context) []string {
+	return nil
+}
+
+func (fakeQueue) Close(context.Context) error {
+	return nil
+}
+
+// fakeRepository 没有未结束批处理的存储库，其他方法不应被调用
+type fakeRepository struct {
+	repositories.SentimentRepository
+}
+
+func (fakeRepository) TouchBatches(context.Context, []string) error {
+	return nil
+}
+
+func (fakeRepository) FindStaleBatches(context.Context, time.Time) ([]*models.BatchAnalysis, error) {
+	return nil, nil
+}
+
+// newTestApp 通过 Option 注入所有外部依赖创建应用，不连接数据库、Redis、RabbitMQ 和算法服务
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+
+	conf := &config.Config{
+		App:      config.AppConfig{Mode: "prod"},
+		Features: config.FeaturesConfig{History: true},
+	}
+	a, err := New(conf,
+		WithRepository(fakeRepository{}),
+		WithAnalyzer(fakeAnalyzer{}),
+		WithQueue(fakeQueue{}),
+		// 客户端只在使用时连接
+		WithRedis(redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})),
+	)
+	if err != nil {
+		t.Fatalf("New() 返回错误: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := a.Stop(context.Background()); err != nil {
+			t.Errorf("Stop() 返回错误: %v", err)
+		}
+	})
+	return a
+}
+
+func TestRouter(t *testing.T) {
+	a := newTestApp(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		// 请求头中的请求ID，为空时不设置
+		requestID string
+		status    int
+		// 响应体中应包含的字段和值
+		want map[string]interface{}
+	}{
+		{
+			name:   "同步分析",
+			method: http.MethodPost,
+			path:   "/api/v1/sentiment/analyze",
+			body:   `{"text":"服务很好","language":"zh"}`,
+			status: http.StatusOK,
+			want:   map[string]interface{}{"sentiment": "positive", "text": "服务很好"},
+		},
+		{
+			name:      "无效的请求ID",
+			method:    http.MethodPost,
+			path:      "/api/v1/sentiment/analyze",
+			body:      `{"text":"服务很好"}`,
+			requestID: "order 42",
+			status:    http.StatusBadRequest,
+			want:      map[string]interface{}{"code": float64(400)},
+		},
+		{
+			name:   "历史记录的无效分页参数",
+			method: http.MethodGet,
+			path:   "/api/v1/sentiment/history?limit=0",
+			status: http.StatusBadRequest,
+			want:   map[string]interface{}{"error": "limit 必须是1到1000之间的整数"},
+		},
+		{
+			name:   "功能已关闭",
+			method: http.MethodPost,
+			path:   "/api/v1/sentiment/analyze/async",
+			body:   `{"text":"服务很好"}`,
+			status: http.StatusServiceUnavailable,
+			want:   map[string]interface{}{"code": float64(503)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			a.Router().ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("状态码 = %d，期望 %d，响应: %s", w.Code, tt.status, w.Body.String())
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("无法解析响应 %q: %v", w.Body.String(), err)
+			}
+			for key, want := range tt.want {
+				if body[key] != want {
+					t.Errorf("响应中的 %s = %v，期望 %v", key, body[key], want)
+				}
+			}
+			if w.Header().Get("X-Request-ID") == "" {
+				t.Error("响应头中没有请求ID")
+			}
+		})
+	}
+}
@@ -1,7 +1,13 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
@@ -12,30 +18,162 @@ type Config struct {
 	Log       LogConfig       `yaml:"log" mapstructure:"log"`
 	Redis     RedisConfig     `yaml:"redis" mapstructure:"redis"`
 	RabbitMQ  RabbitMQConfig  `yaml:"rabbitmq" mapstructure:"rabbitmq"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" mapstructure:"rate_limit"`
+	Features  FeaturesConfig  `yaml:"features" mapstructure:"features"`
+	Fallback  FallbackConfig  `yaml:"fallback" mapstructure:"fallback"`
+	Batch     BatchConfig     `yaml:"batch" mapstructure:"batch"`
+	// 单条分析结果的异步写入
+	WriteBehind WriteBehindConfig `yaml:"write_behind" mapstructure:"write_behind"`
+	// 告警规则评估和 webhook 通知
+	Alerting AlertingConfig `yaml:"alerting" mapstructure:"alerting"`
+}
+
+// 配置文件默认位置
+const (
+	DefaultConfigDir  = "./configs"
+	DefaultConfigName = "config"
+)
+
+// LoadOptions 控制配置文件的选择
+type LoadOptions struct {
+	// ConfigDir 配置文件目录，默认 ./configs
+	ConfigDir string
+	// Env 环境名称，用于叠加 config_<env>.yaml，默认读取 APP_ENV
+	Env string
+	// ConfigFile 显式指定的配置文件（--config），最后叠加，优先级最高
+	ConfigFile string
+}
+
+// configLayer 参与叠加的配置文件
+type configLayer struct {
+	path     string
+	required bool
+}
+
+// layers 按叠加顺序返回配置文件
+func (o LoadOptions) layers() []configLayer {
+	if o.ConfigDir == "" {
+		o.ConfigDir = DefaultConfigDir
+	}
+	if o.Env == "" {
+		o.Env = os.Getenv("APP_ENV")
+	}
+
+	// 基础配置文件，显式指定配置文件时可以不存在
+	layers := []configLayer{{
+		path:     filepath.Join(o.ConfigDir, DefaultConfigName+".yaml"),
+		required: o.ConfigFile == "",
+	}}
+
+	// 环境配置文件，指定了环境就必须存在
+	if o.Env != "" {
+		layers = append(layers, configLayer{
+			path:     filepath.Join(o.ConfigDir, fmt.Sprintf("%s_%s.yaml", DefaultConfigName, o.Env)),
+			required: true,
+		})
+	}
+
+	// 显式指定的配置文件
+	if o.ConfigFile != "" {
+		layers = append(layers, configLayer{path: o.ConfigFile, required: true})
+	}
+
+	return layers
+}
+
+// ConfigFiles 返回实际存在的配置文件路径，用于监听文件变化
+func (o LoadOptions) ConfigFiles() []string {
+	var files []string
+	for _, layer := range o.layers() {
+		if _, err := os.Stat(layer.path); err == nil {
+			files = append(files, layer.path)
+		}
+	}
+	return files
 }
 
-var Conf *Config
+// Load 加载配置文件并应用环境变量覆盖，然后校验配置
+// 叠加顺序（后者覆盖前者）：config.yaml -> config_<env>.yaml -> --config 文件 -> 环境变量
+// 每次调用使用独立的viper实例，返回的配置由调用方持有
+// 校验失败时返回 *ValidationError，同时仍返回解析后的配置，便于诊断
+func Load(opts LoadOptions) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	// 功能开关默认开启，配置文件中缺省时不会关闭已有接口
+	v.SetDefault("features.async_analysis", true)
+	v.SetDefault("features.batch_analysis", true)
+	v.SetDefault("features.history", true)
+
+	// 批处理默认值
+	v.SetDefault("batch.chunk_size", 500)
+	v.SetDefault("batch.streams", 4)
+	v.SetDefault("batch.max_in_flight", 100)
+	v.SetDefault("batch.max_async_texts", 100000)
+	v.SetDefault("batch.max_upload_size", 100<<20)
+
+	// 异步写入默认值，默认关闭，开启后只需配置 enabled
+	v.SetDefault("write_behind.queue_size", 10000)
+	v.SetDefault("write_behind.batch_size", 200)
+	v.SetDefault("write_behind.flush_interval", "200ms")
+	v.SetDefault("write_behind.max_retries", 3)
+	v.SetDefault("write_behind.retry_backoff", "500ms")
+	v.SetDefault("write_behind.journal_dir", "data/journal")
+	v.SetDefault("write_behind.replay_interval", "30s")
+
+	// 告警默认值，默认关闭，开启后只需配置 enabled
+	v.SetDefault("alerting.evaluation_interval", "1m")
+	v.SetDefault("alerting.webhook_timeout", "5s")
+	v.SetDefault("alerting.webhook_max_retries", 2)
+	v.SetDefault("alerting.webhook_retry_backoff", "1s")
 
-// LoadConfig 加载配置文件
-func LoadConfig() error {
-	// 设置配置文件路径和名称
-	viper.AddConfigPath("./configs")
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
+	for _, layer := range opts.layers() {
+		if err := mergeConfigFile(v, layer.path, layer.required); err != nil {
+			return nil, err
+		}
+	}
+
+	// 从环境变量获取配置并覆盖配置文件中的值
+	if err := bindEnvironmentVariables(v); err != nil {
+		return nil, err
+	}
+
+	// 将配置内容解析到配置结构体中
+	conf := &Config{}
+	if err := v.Unmarshal(conf); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %v", err)
+	}
+
+	// 校验配置，未知配置项通常是拼写错误或过时的键
+	var issues problems
+	for _, key := range unknownKeys(v) {
+		issues.add(fmt.Sprintf("未知配置项 %s", key))
+	}
+
+	var validationErr *ValidationError
+	if err := conf.Validate(); errors.As(err, &validationErr) {
+		issues = append(issues, validationErr.Problems...)
+	}
 
-	// 读取配置文件
-	err := viper.ReadInConfig()
+	return conf, issues.err()
+}
+
+// mergeConfigFile 将配置文件叠加到已有配置上
+func mergeConfigFile(v *viper.Viper, path string, required bool) error {
+	file, err := os.Open(path)
 	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil
+		}
 		return fmt.Errorf("读取配置文件失败: %v", err)
 	}
+	defer file.Close()
 
-	// 将配置文件内容解析到 Conf 变量中
-	Conf = &Config{}
-	err = viper.Unmarshal(Conf)
-	if err != nil {
-		return fmt.Errorf("解析配置文件失败: %v", err)
+	if err := v.MergeConfig(file); err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
 	}
 
+	logrus.Infof("已加载配置文件: %s", path)
 	return nil
 }
 
@@ -43,6 +181,8 @@ type AppConfig struct {
 	Port int    `mapstructure:"port"`
 	Mode string `mapstructure:"mode"`
 	Addr string `mapstructure:"addr"`
+	// 优雅关闭的最长等待时间（例如 "30s"）
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
 }
 
 type DatabaseConfig struct {
@@ -58,6 +198,10 @@ type DatabaseConfig struct {
 
 type AlgorithmConfig struct {
 	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"`
+	// 单次分析的超时时间，0表示不限制（可热更新）
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// 批量分析的超时时间，0表示不限制（可热更新）
+	BatchTimeout time.Duration `yaml:"batch_timeout" mapstructure:"batch_timeout"`
 }
 
 type LogConfig struct {
@@ -80,3 +224,75 @@ type RabbitMQConfig struct {
 	TaskQueue   string `yaml:"task_queue" mapstructure:"task_queue"`
 	ResultQueue string `yaml:"result_queue" mapstructure:"result_queue"`
 }
+
+// 限流配置（可热更新）
+type RateLimitConfig struct {
+	Enabled           bool    `yaml:"enabled" mapstructure:"enabled"`
+	RequestsPerSecond float64 `yaml:"requests_per_second" mapstructure:"requests_per_second"`
+	Burst             int     `yaml:"burst" mapstructure:"burst"`
+}
+
+// 功能开关（可热更新）
+type FeaturesConfig struct {
+	AsyncAnalysis bool `yaml:"async_analysis" mapstructure:"async_analysis"`
+	BatchAnalysis bool `yaml:"batch_analysis" mapstructure:"batch_analysis"`
+	History       bool `yaml:"history" mapstructure:"history"`
+}
+
+// 降级配置（可热更新）
+type FallbackConfig struct {
+	// 同步分析调用算法服务失败时，改为提交异步任务并返回202
+	QueueOnAnalyzerError bool `yaml:"queue_on_analyzer_error" mapstructure:"queue_on_analyzer_error"`
+}
+
+// 批处理配置（可热更新）
+type BatchConfig struct {
+	// 每个gRPC流负责的文本数，批量分析按此大小分块
+	ChunkSize int `yaml:"chunk_size" mapstructure:"chunk_size"`
+	// 一个批处理同时使用的gRPC流数量
+	Streams int `yaml:"streams" mapstructure:"streams"`
+	// 每个gRPC流上已发送但未收到结果的最大请求数
+	MaxInFlight int `yaml:"max_in_flight" mapstructure:"max_in_flight"`
+	// 异步批处理允许的最大文本数
+	MaxAsyncTexts int `yaml:"max_async_texts" mapstructure:"max_async_texts"`
+	// 上传文件的最大字节数
+	MaxUploadSize int64 `yaml:"max_upload_size" mapstructure:"max_upload_size"`
+}
+
+// 异步写入配置（仅启动时生效）
+type WriteBehindConfig struct {
+	// 开启后 store_result 的单条分析结果异步批量写入数据库，响应不等待写入完成
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// 内存队列容量，队列满时记录直接写入本地日志文件
+	QueueSize int `yaml:"queue_size" mapstructure:"queue_size"`
+	// 每次写入数据库的最大记录数
+	BatchSize int `yaml:"batch_size" mapstructure:"batch_size"`
+	// 未攒满一批时的最长等待时间
+	FlushInterval time.Duration `yaml:"flush_interval" mapstructure:"flush_interval"`
+	// 暂时性错误（连接失败、超时等）的重试次数，之后记录写入本地日志文件
+	MaxRetries int `yaml:"max_retries" mapstructure:"max_retries"`
+	// 第一次重试前的等待时间，之后按次数线性增加
+	RetryBackoff time.Duration `yaml:"retry_backoff" mapstructure:"retry_backoff"`
+	// 数据库不可用时保存记录的本地日志文件目录
+	JournalDir string `yaml:"journal_dir" mapstructure:"journal_dir"`
+	// 检查数据库是否恢复并重放本地日志文件的间隔
+	ReplayInterval time.Duration `yaml:"replay_interval" mapstructure:"replay_interval"`
+}
+
+// 告警配置（仅启动时生效）
+type AlertingConfig struct {
+	// 开启后定期评估告警规则；关闭时仍可管理规则和告警，但不会产生新告警
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// 评估所有启用规则的间隔
+	EvaluationInterval time.Duration `yaml:"evaluation_interval" mapstructure:"evaluation_interval"`
+	// 单次 webhook 请求的超时时间
+	WebhookTimeout time.Duration `yaml:"webhook_timeout" mapstructure:"webhook_timeout"`
+	// 网络错误或 5xx 响应的重试次数
+	WebhookMaxRetries int `yaml:"webhook_max_retries" mapstructure:"webhook_max_retries"`
+	// 第一次重试前的等待时间，之后按次数线性增加
+	WebhookRetryBackoff time.Duration `yaml:"webhook_retry_backoff" mapstructure:"webhook_retry_backoff"`
+	// 签名密钥，设置后请求头 X-Sentiment-Signature 包含请求体的 HMAC-SHA256 签名
+	WebhookSecret string `yaml:"webhook_secret" mapstructure:"webhook_secret"`
+	// 允许 webhook 地址指向本机和内网地址，仅用于开发和测试环境
+	WebhookAllowPrivateTargets bool `yaml:"webhook_allow_private_targets" mapstructure:"webhook_allow_private_targets"`
+}
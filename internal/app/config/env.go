@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// fileEnvSuffix 以文件方式挂载的密钥使用的环境变量后缀，例如 DATABASE_PASSWORD_FILE
+const fileEnvSuffix = "_FILE"
+
+// legacyEnvAliases 旧版本使用的环境变量名，继续兼容 docker-compose 等部署配置
+var legacyEnvAliases = map[string][]string{
+	"database.host":     {"DB_HOST"},
+	"database.port":     {"DB_PORT"},
+	"database.user":     {"DB_USER"},
+	"database.password": {"DB_PASSWORD"},
+	"database.dbname":   {"DB_NAME"},
+}
+
+// bindEnvironmentVariables 将 Config 的每个字段绑定到环境变量
+// 环境变量名为配置键的大写形式，层级用下划线连接，例如 database.host -> DATABASE_HOST
+func bindEnvironmentVariables(v *viper.Viper) error {
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		envNames := append([]string{envName(key)}, legacyEnvAliases[key]...)
+
+		bindArgs := append([]string{key}, envNames...)
+		if err := v.BindEnv(bindArgs...); err != nil {
+			return fmt.Errorf("绑定环境变量 %s 失败: %v", key, err)
+		}
+
+		if err := applyFileEnv(v, key, envNames); err != nil {
+			return err
+		}
+
+		logEnvOverride(key, envNames)
+	}
+
+	return nil
+}
+
+// applyFileEnv 当环境变量未直接设置但存在 *_FILE 变量时，从文件读取值
+func applyFileEnv(v *viper.Viper, key string, envNames []string) error {
+	for _, name := range envNames {
+		if _, ok := os.LookupEnv(name); ok {
+			return nil
+		}
+	}
+
+	for _, name := range envNames {
+		path, ok := os.LookupEnv(name + fileEnvSuffix)
+		if !ok || path == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取 %s 指定的文件失败: %v", name+fileEnvSuffix, err)
+		}
+
+		v.Set(key, strings.TrimRight(string(content), "\r\n"))
+		logrus.Infof("从文件加载配置 %s（%s）", key, name+fileEnvSuffix)
+		return nil
+	}
+
+	return nil
+}
+
+// logEnvOverride 记录来自环境变量的配置项，不输出值以免泄露密钥
+func logEnvOverride(key string, envNames []string) {
+	for _, name := range envNames {
+		if _, ok := os.LookupEnv(name); ok {
+			logrus.Infof("从环境变量加载配置 %s（%s）", key, name)
+			return
+		}
+	}
+}
+
+// configKeys 根据 mapstructure 标签递归列出所有配置键
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, configKeys(field.Type, key)...)
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// envName 将配置键转换为环境变量名
+func envName(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// maskedValue 替代密钥的显示值
+const maskedValue = "******"
+
+// secretKeys 需要隐藏值的配置键
+var secretKeys = map[string]bool{
+	"database.password":       true,
+	"redis.password":          true,
+	"alerting.webhook_secret": true,
+}
+
+// urlKeys 可能在用户信息中包含密码的URL配置键
+var urlKeys = map[string]bool{
+	"rabbitmq.url": true,
+}
+
+// Masked 返回按配置键组织、隐藏密钥后的配置，用于打印
+func (c *Config) Masked() map[string]interface{} {
+	return maskedSection(reflect.ValueOf(*c), "")
+}
+
+// maskedSection 根据 mapstructure 标签递归构建配置映射
+func maskedSection(value reflect.Value, prefix string) map[string]interface{} {
+	section := make(map[string]interface{})
+	t := value.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+
+		fieldValue := value.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			section[tag] = maskedSection(fieldValue, key)
+		case secretKeys[key]:
+			if fieldValue.String() != "" {
+				section[tag] = maskedValue
+			} else {
+				section[tag] = ""
+			}
+		case urlKeys[key]:
+			section[tag] = maskURL(fieldValue.String())
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			section[tag] = time.Duration(fieldValue.Int()).String()
+		default:
+			section[tag] = fieldValue.Interface()
+		}
+	}
+
+	return section
+}
+
+// maskURL 隐藏URL中的密码
+func maskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return maskedValue
+	}
+	return u.Redacted()
+}
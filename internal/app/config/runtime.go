@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// ChangeHandler 配置热更新后的回调，old 和 new 均不可修改
+type ChangeHandler func(old, new *Config)
+
+// Store 持有当前生效的配置，支持热更新可在运行时调整的配置子集
+// 可热更新：日志级别/格式、算法超时、限流、功能开关、降级开关、批处理参数
+// 其余配置（连接地址、端口、队列名等）仅在重启后生效
+type Store struct {
+	current atomic.Pointer[Config]
+
+	mu       sync.Mutex
+	handlers []ChangeHandler
+	opts     LoadOptions
+	watching bool
+	stopped  bool
+}
+
+// NewStore 使用已加载的配置创建配置存储
+func NewStore(conf *Config) *Store {
+	s := &Store{}
+	s.current.Store(conf)
+	return s
+}
+
+// Current 返回当前生效的配置快照，调用方不得修改
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// OnChange 注册配置热更新回调
+func (s *Store) OnChange(handler ChangeHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+// Watch 监听所有参与叠加的配置文件，文件变化时重新加载
+func (s *Store) Watch(opts LoadOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.watching {
+		return
+	}
+	s.watching = true
+	s.opts = opts
+
+	for _, path := range opts.ConfigFiles() {
+		watcher := viper.New()
+		watcher.SetConfigFile(path)
+		watcher.OnConfigChange(func(e fsnotify.Event) {
+			logrus.WithField("file", e.Name).Info("检测到配置文件变化")
+			if err := s.Reload(); err != nil {
+				logrus.WithError(err).Error("配置热更新失败，继续使用当前配置")
+			}
+		})
+		watcher.WatchConfig()
+		logrus.WithField("file", path).Info("正在监听配置文件变化")
+	}
+}
+
+// Stop 停止处理配置变化（viper 的文件监听无法停止，之后的变化会被忽略）
+func (s *Store) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+}
+
+// Reload 重新加载配置，只应用可热更新的配置项
+// 新配置校验失败时保留当前配置并返回错误
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return nil
+	}
+
+	loaded, err := Load(s.opts)
+	if err != nil {
+		return err
+	}
+
+	old := s.Current()
+	next := applyReloadable(old, loaded)
+
+	// 仅重启后生效的配置变化只提示，不应用
+	for _, change := range diffConfig(next, loaded) {
+		logrus.WithField("key", change.key).Warn("该配置项需要重启服务才能生效，已忽略")
+	}
+
+	changes := diffConfig(old, next)
+	if len(changes) == 0 {
+		logrus.Info("配置文件已变化，但可热更新的配置没有变化")
+		return nil
+	}
+
+	// 审计日志：每个变化的配置项一行（密钥已隐藏）
+	for _, change := range changes {
+		logrus.WithFields(logrus.Fields{
+			"audit": "config_reload",
+			"key":   change.key,
+			"old":   change.old,
+			"new":   change.new,
+		}).Info("配置已热更新")
+	}
+
+	s.current.Store(next)
+	for _, handler := range s.handlers {
+		handler(old, next)
+	}
+
+	return nil
+}
+
+// applyReloadable 以 old 为基础，复制 loaded 中可热更新的配置项
+func applyReloadable(old, loaded *Config) *Config {
+	next := *old
+	next.Log = loaded.Log
+	next.Algorithm.Timeout = loaded.Algorithm.Timeout
+	next.Algorithm.BatchTimeout = loaded.Algorithm.BatchTimeout
+	next.RateLimit = loaded.RateLimit
+	next.Features = loaded.Features
+	next.Fallback = loaded.Fallback
+	next.Batch = loaded.Batch
+	return &next
+}
+
+// configChange 单个配置项的变化
+type configChange struct {
+	key string
+	old interface{}
+	new interface{}
+}
+
+// diffConfig 比较两份配置（隐藏密钥后），返回按键排序的变化列表
+func diffConfig(a, b *Config) []configChange {
+	before := flatten(a.Masked(), "")
+	after := flatten(b.Masked(), "")
+
+	var changes []configChange
+	for key, oldValue := range before {
+		if newValue := after[key]; !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, configChange{key: key, old: oldValue, new: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].key < changes[j].key })
+	return changes
+}
+
+// flatten 将嵌套的配置映射展开为 "section.key" 形式
+func flatten(section map[string]interface{}, prefix string) map[string]interface{} {
+	flat := make(map[string]interface{})
+	for key, value := range section {
+		fullKey := key
+		if prefix != "" {
+			fullKey = fmt.Sprintf("%s.%s", prefix, key)
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			for k, v := range flatten(nested, fullKey) {
+				flat[k] = v
+			}
+			continue
+		}
+
+		flat[fullKey] = value
+	}
+	return flat
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 允许的枚举值
+var (
+	validModes     = []string{"dev", "test", "prod"}
+	validDrivers   = []string{"postgres"}
+	validSSLModes  = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	validLogLevels = []string{"debug", "info", "warn", "error", "fatal", "panic"}
+	validLogFormat = []string{"json", "text"}
+)
+
+// ValidationError 配置校验失败，包含所有发现的问题
+type ValidationError struct {
+	Problems []string
+}
+
+// Error 实现error接口
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("配置校验失败（%d 个问题）:\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate 检查配置是否合法，返回所有问题而不是遇到第一个就停止
+func (c *Config) Validate() error {
+	var p problems
+
+	// 应用配置
+	p.portRange("app.port", c.App.Port)
+	p.oneOf("app.mode", c.App.Mode, validModes)
+	if c.App.ShutdownTimeout < 0 {
+		p.add("app.shutdown_timeout 不能为负数")
+	}
+
+	// 数据库配置
+	p.oneOf("database.driver", c.Database.Driver, validDrivers)
+	p.required("database.host", c.Database.Host)
+	p.portRange("database.port", c.Database.Port)
+	p.required("database.user", c.Database.User)
+	p.required("database.dbname", c.Database.DBName)
+	if c.Database.SSLMode != "" {
+		p.oneOf("database.sslmode", c.Database.SSLMode, validSSLModes)
+	}
+	if c.Database.TimeZone != "" {
+		if _, err := time.LoadLocation(c.Database.TimeZone); err != nil {
+			p.add(fmt.Sprintf("database.timezone 无效: %q", c.Database.TimeZone))
+		}
+	}
+
+	// 算法服务配置
+	if p.required("algorithm.endpoint", c.Algorithm.Endpoint) {
+		p.hostPort("algorithm.endpoint", c.Algorithm.Endpoint)
+	}
+
+	if c.Algorithm.Timeout < 0 {
+		p.add("algorithm.timeout 不能为负数")
+	}
+	if c.Algorithm.BatchTimeout < 0 {
+		p.add("algorithm.batch_timeout 不能为负数")
+	}
+
+	// 日志配置
+	p.oneOf("log.level", c.Log.Level, validLogLevels)
+	p.oneOf("log.format", c.Log.Format, validLogFormat)
+
+	// Redis配置（可选）
+	if c.Redis.Host != "" {
+		p.portRange("redis.port", c.Redis.Port)
+	}
+	if c.Redis.DB < 0 {
+		p.add("redis.db 不能为负数")
+	}
+
+	// RabbitMQ配置
+	if p.required("rabbitmq.url", c.RabbitMQ.URL) {
+		p.amqpURL("rabbitmq.url", c.RabbitMQ.URL)
+	}
+	p.required("rabbitmq.task_queue", c.RabbitMQ.TaskQueue)
+	p.required("rabbitmq.result_queue", c.RabbitMQ.ResultQueue)
+
+	// 限流配置
+	if c.RateLimit.Enabled {
+		if c.RateLimit.RequestsPerSecond <= 0 {
+			p.add("启用限流时 rate_limit.requests_per_second 必须大于0")
+		}
+		if c.RateLimit.Burst < 1 {
+			p.add("启用限流时 rate_limit.burst 必须至少为1")
+		}
+	}
+
+	// 批处理配置
+	if c.Batch.ChunkSize < 1 {
+		p.add("batch.chunk_size 必须至少为1")
+	}
+	if c.Batch.Streams < 1 {
+		p.add("batch.streams 必须至少为1")
+	}
+	if c.Batch.MaxInFlight < 1 {
+		p.add("batch.max_in_flight 必须至少为1")
+	}
+	if c.Batch.MaxAsyncTexts < 1 {
+		p.add("batch.max_async_texts 必须至少为1")
+	}
+	if c.Batch.MaxUploadSize < 1 {
+		p.add("batch.max_upload_size 必须至少为1")
+	}
+
+	// 异步写入配置
+	if c.WriteBehind.Enabled {
+		if c.WriteBehind.QueueSize < 1 {
+			p.add("启用异步写入时 write_behind.queue_size 必须至少为1")
+		}
+		if c.WriteBehind.BatchSize < 1 {
+			p.add("启用异步写入时 write_behind.batch_size 必须至少为1")
+		}
+		if c.WriteBehind.FlushInterval <= 0 {
+			p.add("启用异步写入时 write_behind.flush_interval 必须大于0")
+		}
+		if c.WriteBehind.MaxRetries < 0 {
+			p.add("write_behind.max_retries 不能为负数")
+		}
+		if c.WriteBehind.RetryBackoff < 0 {
+			p.add("write_behind.retry_backoff 不能为负数")
+		}
+		p.required("write_behind.journal_dir", c.WriteBehind.JournalDir)
+		if c.WriteBehind.ReplayInterval <= 0 {
+			p.add("启用异步写入时 write_behind.replay_interval 必须大于0")
+		}
+	}
+
+	// 告警配置
+	if c.Alerting.Enabled {
+		if c.Alerting.EvaluationInterval <= 0 {
+			p.add("启用告警时 alerting.evaluation_interval 必须大于0")
+		}
+		if c.Alerting.WebhookTimeout <= 0 {
+			p.add("启用告警时 alerting.webhook_timeout 必须大于0")
+		}
+		if c.Alerting.WebhookMaxRetries < 0 {
+			p.add("alerting.webhook_max_retries 不能为负数")
+		}
+		if c.Alerting.WebhookRetryBackoff < 0 {
+			p.add("alerting.webhook_retry_backoff 不能为负数")
+		}
+	}
+
+	return p.err()
+}
+
+// unknownKeys 返回配置来源中存在但 Config 中没有对应字段的键
+func unknownKeys(v *viper.Viper) []string {
+	known := make(map[string]bool)
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		known[strings.ToLower(key)] = true
+	}
+
+	var unknown []string
+	for _, key := range v.AllKeys() {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+
+	sort.Strings(unknown)
+	return unknown
+}
+
+// problems 收集校验问题
+type problems []string
+
+func (p *problems) add(problem string) {
+	*p = append(*p, problem)
+}
+
+func (p *problems) err() error {
+	if len(*p) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: *p}
+}
+
+// required 检查必填字段，返回字段是否非空
+func (p *problems) required(key, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		p.add(fmt.Sprintf("%s 为必填项", key))
+		return false
+	}
+	return true
+}
+
+func (p *problems) portRange(key string, port int) {
+	if port < 1 || port > 65535 {
+		p.add(fmt.Sprintf("%s 必须在 1-65535 之间，当前为 %d", key, port))
+	}
+}
+
+func (p *problems) oneOf(key, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	p.add(fmt.Sprintf("%s 必须是 %s 之一，当前为 %q", key, strings.Join(allowed, "/"), value))
+}
+
+func (p *problems) hostPort(key, value string) {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil || host == "" {
+		p.add(fmt.Sprintf("%s 必须是 host:port 格式，当前为 %q", key, value))
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		p.add(fmt.Sprintf("%s 端口无效: %q", key, port))
+	}
+}
+
+func (p *problems) amqpURL(key, value string) {
+	// 错误信息不包含原始URL，避免泄露其中的密码
+	u, err := url.Parse(value)
+	if err != nil {
+		p.add(fmt.Sprintf("%s 不是合法的URL", key))
+		return
+	}
+	if u.Scheme != "amqp" && u.Scheme != "amqps" {
+		p.add(fmt.Sprintf("%s 必须使用 amqp:// 或 amqps:// 协议，当前为 %q", key, u.Scheme))
+	}
+	if u.Host == "" {
+		p.add(fmt.Sprintf("%s 缺少主机地址", key))
+	}
+}
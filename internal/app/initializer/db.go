@@ -1,6 +1,7 @@
 package initializer
 
 import (
+	"context"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
@@ -11,24 +12,21 @@ import (
 	"time"
 
 	"sentiment-service/internal/app/config"
-	"sentiment-service/internal/models"
+	"sentiment-service/internal/migrations"
 )
 
-// DB 全局数据库连接
-var DB *gorm.DB
-
-// InitializeDB 初始化数据库连接
-func InitializeDB() error {
+// NewDB 创建数据库连接（不执行迁移）
+func NewDB(dbConf config.DatabaseConfig, logLevel string) (*gorm.DB, error) {
 	// 构建DSN
 	dsn := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
-		config.Conf.Database.Host,
-		config.Conf.Database.Port,
-		config.Conf.Database.User,
-		config.Conf.Database.Password,
-		config.Conf.Database.DBName,
-		config.Conf.Database.SSLMode,
-		config.Conf.Database.TimeZone,
+		dbConf.Host,
+		dbConf.Port,
+		dbConf.User,
+		dbConf.Password,
+		dbConf.DBName,
+		dbConf.SSLMode,
+		dbConf.TimeZone,
 	)
 
 	logrus.Debugf("数据库连接DSN: %s", dsn)
@@ -38,25 +36,24 @@ func InitializeDB() error {
 		log.New(os.Stdout, "\r\n", log.LstdFlags),
 		logger.Config{
 			SlowThreshold:             time.Second,
-			LogLevel:                  getGormLogLevel(),
+			LogLevel:                  getGormLogLevel(logLevel),
 			IgnoreRecordNotFoundError: true,
 			Colorful:                  true,
 		},
 	)
 
 	// 创建数据库连接
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: gormLogger,
 	})
 	if err != nil {
-		return fmt.Errorf("无法连接到数据库: %v", err)
+		return nil, fmt.Errorf("无法连接到数据库: %v", err)
 	}
 
 	// 获取底层SQL DB
-	sqlDB, err := DB.DB()
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("无法获取数据库实例: %v", err)
+		return nil, fmt.Errorf("无法获取数据库实例: %v", err)
 	}
 
 	// 配置连接池
@@ -66,43 +63,64 @@ func InitializeDB() error {
 
 	// 测试连接
 	if err := sqlDB.Ping(); err != nil {
-		return fmt.Errorf("数据库连接测试失败: %v", err)
+		sqlDB.Close()
+		return nil, fmt.Errorf("数据库连接测试失败: %v", err)
 	}
 
 	logrus.Info("数据库连接成功")
+	return db, nil
+}
 
-	// 运行自动迁移
-	if err := migrateDatabase(DB); err != nil {
-		return fmt.Errorf("数据库迁移失败: %v", err)
+// CloseDB 关闭数据库连接
+func CloseDB(db *gorm.DB) error {
+	if db == nil {
+		return nil
 	}
 
-	logrus.Info("数据库初始化完成")
-	return nil
-}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("无法获取数据库实例: %v", err)
+	}
 
-// migrateDatabase 运行数据库迁移
-func migrateDatabase(db *gorm.DB) error {
-	logrus.Info("正在执行数据库迁移...")
+	return sqlDB.Close()
+}
 
-	// 自动迁移模型
-	err := db.AutoMigrate(
-		&models.SentimentAnalysis{},
-		&models.AnalysisMetadata{},
-		&models.BatchAnalysis{},
-		&models.BatchItem{},
-	)
+// RunStartupMigrations 启动时的迁移策略
+// 非生产模式自动执行未完成的迁移；生产模式只检查并提示，迁移需通过 migrate 命令显式执行
+func RunStartupMigrations(db *gorm.DB, mode string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("无法获取数据库实例: %v", err)
+	}
 
+	migrator, err := migrations.New(sqlDB)
 	if err != nil {
 		return err
 	}
 
+	ctx := context.Background()
+	if mode == "prod" {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return fmt.Errorf("检查数据库迁移状态失败: %v", err)
+		}
+		if pending > 0 {
+			logrus.WithField("pending", pending).Warn("存在未执行的数据库迁移，请运行 migrate up")
+		}
+		return nil
+	}
+
+	logrus.Info("正在执行数据库迁移...")
+	if err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("数据库迁移失败: %v", err)
+	}
 	logrus.Info("数据库迁移完成")
 	return nil
 }
 
 // getGormLogLevel 根据应用日志级别获取GORM日志级别
-func getGormLogLevel() logger.LogLevel {
-	switch config.Conf.Log.Level {
+func getGormLogLevel(level string) logger.LogLevel {
+	switch level {
 	case "debug":
 		return logger.Info
 	case "info":
@@ -12,10 +12,41 @@ import (
 	"sentiment-service/internal/app/config"
 )
 
-// InitializeLogger 初始化日志系统
-func InitializeLogger() error {
+// InitializeLogger 初始化日志系统（logrus为进程级全局日志记录器）
+// console 为控制台输出，命令行工具输出数据到标准输出时应传入 os.Stderr
+func InitializeLogger(logConf config.LogConfig, console io.Writer) error {
+	ApplyLogSettings(logConf)
+
+	// 确保日志目录存在
+	logDir := "logs"
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return err
+	}
+
+	// 配置日志轮转
+	logPath := filepath.Join(logDir, "sentiment-service.%Y%m%d.log")
+	writer, err := rotatelogs.New(
+		logPath,
+		rotatelogs.WithLinkName(filepath.Join(logDir, "sentiment-service.log")),
+		rotatelogs.WithMaxAge(7*24*time.Hour),     // 保留7天
+		rotatelogs.WithRotationTime(24*time.Hour), // 每天轮转一次
+	)
+	if err != nil {
+		return err
+	}
+
+	// 设置多输出，同时输出到控制台和文件
+	multiWriter := io.MultiWriter(console, writer)
+	logrus.SetOutput(multiWriter)
+
+	logrus.Info("日志系统初始化完成")
+	return nil
+}
+
+// ApplyLogSettings 应用日志格式、级别和调用者报告设置，可在运行时重复调用
+func ApplyLogSettings(logConf config.LogConfig) {
 	// 设置日志格式
-	switch config.Conf.Log.Format {
+	switch logConf.Format {
 	case "json":
 		logrus.SetFormatter(&logrus.JSONFormatter{})
 	case "text":
@@ -27,7 +58,7 @@ func InitializeLogger() error {
 	}
 
 	// 设置日志级别
-	switch config.Conf.Log.Level {
+	switch logConf.Level {
 	case "debug":
 		logrus.SetLevel(logrus.DebugLevel)
 	case "info":
@@ -45,30 +76,5 @@ func InitializeLogger() error {
 	}
 
 	// 设置调用者报告
-	logrus.SetReportCaller(config.Conf.Log.ReportCaller)
-
-	// 确保日志目录存在
-	logDir := "logs"
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return err
-	}
-
-	// 配置日志轮转
-	logPath := filepath.Join(logDir, "sentiment-service.%Y%m%d.log")
-	writer, err := rotatelogs.New(
-		logPath,
-		rotatelogs.WithLinkName(filepath.Join(logDir, "sentiment-service.log")),
-		rotatelogs.WithMaxAge(7*24*time.Hour),     // 保留7天
-		rotatelogs.WithRotationTime(24*time.Hour), // 每天轮转一次
-	)
-	if err != nil {
-		return err
-	}
-
-	// 设置多输出，同时输出到控制台和文件
-	multiWriter := io.MultiWriter(os.Stdout, writer)
-	logrus.SetOutput(multiWriter)
-
-	logrus.Info("日志系统初始化完成")
-	return nil
+	logrus.SetReportCaller(logConf.ReportCaller)
 }
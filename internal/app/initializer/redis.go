@@ -9,26 +9,24 @@ import (
 	"sentiment-service/internal/app/config"
 )
 
-// Redis 全局Redis客户端
-var Redis *redis.Client
-
-// InitializeRedis 初始化Redis连接
-func InitializeRedis() error {
+// NewRedis 创建Redis客户端并测试连接
+func NewRedis(redisConf config.RedisConfig) (*redis.Client, error) {
 	// 创建Redis客户端
-	addr := fmt.Sprintf("%s:%d", config.Conf.Redis.Host, config.Conf.Redis.Port)
-	Redis = redis.NewClient(&redis.Options{
+	addr := fmt.Sprintf("%s:%d", redisConf.Host, redisConf.Port)
+	client := redis.NewClient(&redis.Options{
 		Addr:     addr,
-		Password: config.Conf.Redis.Password,
-		DB:       config.Conf.Redis.DB, // 注意这里使用大写的DB
+		Password: redisConf.Password,
+		DB:       redisConf.DB, // 注意这里使用大写的DB
 	})
 
 	// 测试连接
 	ctx := context.Background()
-	_, err := Redis.Ping(ctx).Result()
+	_, err := client.Ping(ctx).Result()
 	if err != nil {
-		return fmt.Errorf("Redis连接测试失败: %v", err)
+		client.Close()
+		return nil, fmt.Errorf("Redis连接测试失败: %v", err)
 	}
 
 	logrus.Info("Redis初始化完成")
-	return nil
+	return client, nil
 }
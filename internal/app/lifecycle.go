@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ShutdownHook 关闭时执行的单个步骤
+type ShutdownHook func(ctx context.Context) error
+
+// namedHook 带名称的关闭步骤，便于日志记录
+type namedHook struct {
+	name string
+	hook ShutdownHook
+}
+
+// Lifecycle 按注册顺序执行关闭步骤
+// 所有步骤共享同一个超时上下文，某一步失败不会阻止后续步骤执行
+type Lifecycle struct {
+	hooks []namedHook
+}
+
+// NewLifecycle 创建生命周期管理器
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// OnShutdown 注册关闭步骤
+func (l *Lifecycle) OnShutdown(name string, hook ShutdownHook) {
+	l.hooks = append(l.hooks, namedHook{name: name, hook: hook})
+}
+
+// Shutdown 依次执行所有关闭步骤，返回所有步骤的错误
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	for _, h := range l.hooks {
+		start := time.Now()
+		logger := logrus.WithField("step", h.name)
+		logger.Info("正在执行关闭步骤")
+
+		if err := h.hook(ctx); err != nil {
+			logger.WithError(err).Error("关闭步骤失败")
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			continue
+		}
+
+		logger.WithField("elapsed_ms", time.Since(start).Milliseconds()).Info("关闭步骤完成")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,720 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/middleware"
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/services"
+)
+
+// 告警规则的限制
+const (
+	maxRuleNameLength      = 100
+	minRuleWindow          = time.Minute
+	maxRuleWindow          = 7 * 24 * time.Hour
+	defaultBaselineWindows = 24
+	maxBaselineWindows     = 500
+	defaultRuleMinCount    = 10
+	maxAlertActorLength    = 50
+	maxAlertNoteLength     = 1000
+	maxAlertListLimit      = 500
+)
+
+// AlertController 处理告警规则和告警相关的HTTP请求
+type AlertController struct {
+	alertService *services.AlertService
+}
+
+// NewAlertController 创建告警控制器
+func NewAlertController(alertService *services.AlertService) *AlertController {
+	return &AlertController{alertService: alertService}
+}
+
+// CreateRule 创建告警规则
+// @Summary 创建告警规则
+// @Description 创建按窗口统计情感指标的告警规则：threshold 规则在指标越过阈值时告警，zscore 规则在指标偏离之前若干窗口的均值超过 threshold 个标准差时告警；webhook_url 不能指向本机或内网地址
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param request body AlertRuleRequest true "告警规则"
+// @Success 201 {object} AlertRuleResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alert-rules [post]
+func (ac *AlertController) CreateRule(c *gin.Context) {
+	var request AlertRuleRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的请求参数: " + err.Error()))
+		return
+	}
+
+	rule, err := request.rule()
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	err = ac.alertService.CreateRule(c.Request.Context(), rule)
+	if errors.Is(err, services.ErrInvalidWebhookURL) {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("创建告警规则失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, toAlertRuleResponse(rule))
+}
+
+// ListRules 获取告警规则列表
+// @Summary 获取告警规则列表
+// @Description 返回告警规则，按创建时间倒序
+// @Tags alerts
+// @Produce json
+// @Param user_id query string false "用户ID"
+// @Param enabled query bool false "是否启用"
+// @Param limit query int false "每页结果数量" default(50)
+// @Param offset query int false "分页偏移量" default(0)
+// @Success 200 {object} AlertRuleListResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alert-rules [get]
+func (ac *AlertController) ListRules(c *gin.Context) {
+	query := services.AlertRuleQuery{
+		UserID: c.Query("user_id"),
+		Offset: parseIntParam(c.DefaultQuery("offset", "0")),
+	}
+
+	var err error
+	if query.Limit, err = limitParam(c, "limit", 50, maxAlertListLimit); err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+	if value := c.Query("enabled"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			c.Error(middleware.ErrBadRequest("enabled 必须是 true 或 false"))
+			return
+		}
+		query.Enabled = &enabled
+	}
+
+	result, err := ac.alertService.ListRules(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取告警规则列表失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	response := AlertRuleListResponse{
+		Rules:      make([]AlertRuleResponse, len(result.Rules)),
+		TotalCount: result.TotalCount,
+	}
+	for i, rule := range result.Rules {
+		response.Rules[i] = toAlertRuleResponse(rule)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRule 获取告警规则
+// @Summary 获取告警规则
+// @Tags alerts
+// @Produce json
+// @Param id path string true "规则ID"
+// @Success 200 {object} AlertRuleResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alert-rules/{id} [get]
+func (ac *AlertController) GetRule(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的规则ID"))
+		return
+	}
+
+	rule, err := ac.alertService.GetRule(c.Request.Context(), id)
+	if errors.Is(err, services.ErrAlertRuleNotFound) {
+		c.Error(middleware.ErrNotFound("告警规则不存在"))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取告警规则失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, toAlertRuleResponse(rule))
+}
+
+// UpdateRule 更新告警规则
+// @Summary 更新告警规则
+// @Description 用请求中的内容替换规则（未提供的可选字段恢复默认值）；停用规则时自动解决其未解决的告警
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "规则ID"
+// @Param request body AlertRuleRequest true "告警规则"
+// @Success 200 {object} AlertRuleResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alert-rules/{id} [put]
+func (ac *AlertController) UpdateRule(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的规则ID"))
+		return
+	}
+
+	var request AlertRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的请求参数: " + err.Error()))
+		return
+	}
+
+	rule, err := request.rule()
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	err = ac.alertService.UpdateRule(c.Request.Context(), id, rule)
+	if errors.Is(err, services.ErrAlertRuleNotFound) {
+		c.Error(middleware.ErrNotFound("告警规则不存在"))
+		return
+	}
+	if errors.Is(err, services.ErrInvalidWebhookURL) {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("更新告警规则失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, toAlertRuleResponse(rule))
+}
+
+// DeleteRule 删除告警规则
+// @Summary 删除告警规则
+// @Description 删除规则并自动解决其未解决的告警，告警历史保留
+// @Tags alerts
+// @Param id path string true "规则ID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alert-rules/{id} [delete]
+func (ac *AlertController) DeleteRule(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的规则ID"))
+		return
+	}
+
+	err := ac.alertService.DeleteRule(c.Request.Context(), id)
+	if errors.Is(err, services.ErrAlertRuleNotFound) {
+		c.Error(middleware.ErrNotFound("告警规则不存在"))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("删除告警规则失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// EvaluateRule 按当前数据评估告警规则
+// @Summary 评估告警规则
+// @Description 按当前数据计算规则的指标并判断是否触发，不产生或解决告警，用于调整阈值
+// @Tags alerts
+// @Produce json
+// @Param id path string true "规则ID"
+// @Success 200 {object} AlertEvaluationResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alert-rules/{id}/evaluate [post]
+func (ac *AlertController) EvaluateRule(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的规则ID"))
+		return
+	}
+
+	eval, err := ac.alertService.PreviewRule(c.Request.Context(), id)
+	if errors.Is(err, services.ErrAlertRuleNotFound) {
+		c.Error(middleware.ErrNotFound("告警规则不存在"))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("评估告警规则失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, AlertEvaluationResponse{
+		RuleID:          eval.RuleID,
+		WindowStart:     eval.WindowStart.Unix(),
+		WindowEnd:       eval.WindowEnd.Unix(),
+		SampleCount:     eval.SampleCount,
+		Evaluable:       eval.Evaluable,
+		Reason:          eval.Reason,
+		Breached:        eval.Breached,
+		Value:           eval.Value,
+		Threshold:       eval.Threshold,
+		BaselineWindows: eval.BaselineWindows,
+		BaselineMean:    eval.BaselineMean,
+		BaselineStddev:  eval.BaselineStddev,
+		ZScore:          eval.ZScore,
+		Message:         eval.Message,
+	})
+}
+
+// ListAlerts 获取告警历史
+// @Summary 获取告警历史
+// @Description 返回告警，按产生时间倒序
+// @Tags alerts
+// @Produce json
+// @Param rule_id query string false "规则ID"
+// @Param status query string false "告警状态（open, acknowledged, resolved）"
+// @Param limit query int false "每页结果数量" default(50)
+// @Param offset query int false "分页偏移量" default(0)
+// @Success 200 {object} AlertListResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alerts [get]
+func (ac *AlertController) ListAlerts(c *gin.Context) {
+	query := services.AlertQuery{
+		RuleID: c.Query("rule_id"),
+		Status: c.Query("status"),
+		Offset: parseIntParam(c.DefaultQuery("offset", "0")),
+	}
+
+	if query.RuleID != "" {
+		if _, err := uuid.Parse(query.RuleID); err != nil {
+			c.Error(middleware.ErrBadRequest("无效的规则ID"))
+			return
+		}
+	}
+	switch query.Status {
+	case "", models.AlertStatusOpen, models.AlertStatusAcknowledged, models.AlertStatusResolved:
+	default:
+		c.Error(middleware.ErrBadRequest("status 必须是 open、acknowledged 或 resolved"))
+		return
+	}
+
+	var err error
+	if query.Limit, err = limitParam(c, "limit", 50, maxAlertListLimit); err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	result, err := ac.alertService.ListAlerts(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取告警列表失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	response := AlertListResponse{
+		Alerts:     make([]AlertResponse, len(result.Alerts)),
+		TotalCount: result.TotalCount,
+	}
+	for i, alert := range result.Alerts {
+		response.Alerts[i] = toAlertResponse(alert)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAlert 获取告警
+// @Summary 获取告警
+// @Tags alerts
+// @Produce json
+// @Param id path string true "告警ID"
+// @Success 200 {object} AlertResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alerts/{id} [get]
+func (ac *AlertController) GetAlert(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的告警ID"))
+		return
+	}
+
+	alert, err := ac.alertService.GetAlert(c.Request.Context(), id)
+	if errors.Is(err, services.ErrAlertNotFound) {
+		c.Error(middleware.ErrNotFound("告警不存在"))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取告警失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, toAlertResponse(alert))
+}
+
+// AcknowledgeAlert 确认告警
+// @Summary 确认告警
+// @Description 确认 open 状态的告警；确认后指标恢复时告警仍会自动解决
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "告警ID"
+// @Param request body AlertActionRequest false "操作人和备注"
+// @Success 200 {object} AlertResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 409 {object} map[string]interface{} "code, message（告警不是 open 状态）"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alerts/{id}/acknowledge [post]
+func (ac *AlertController) AcknowledgeAlert(c *gin.Context) {
+	ac.updateAlert(c, ac.alertService.AcknowledgeAlert)
+}
+
+// ResolveAlert 解决告警
+// @Summary 解决告警
+// @Description 手动解决未解决的告警；规则的冷却时间从解决时开始计算
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "告警ID"
+// @Param request body AlertActionRequest false "操作人和备注"
+// @Success 200 {object} AlertResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 409 {object} map[string]interface{} "code, message（告警已解决）"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/alerts/{id}/resolve [post]
+func (ac *AlertController) ResolveAlert(c *gin.Context) {
+	ac.updateAlert(c, ac.alertService.ResolveAlert)
+}
+
+// updateAlert 解析确认或解决告警的请求并执行操作
+func (ac *AlertController) updateAlert(c *gin.Context, action func(ctx context.Context, id, by, note string) (*models.Alert, error)) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的告警ID"))
+		return
+	}
+
+	// 请求体可以为空
+	var request AlertActionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.Error(middleware.ErrBadRequest("无效的请求参数: " + err.Error()))
+			return
+		}
+	}
+	if utf8.RuneCountInString(request.By) > maxAlertActorLength {
+		c.Error(middleware.ErrBadRequest(fmt.Sprintf("by 不能超过 %d 个字符", maxAlertActorLength)))
+		return
+	}
+	if utf8.RuneCountInString(request.Note) > maxAlertNoteLength {
+		c.Error(middleware.ErrBadRequest(fmt.Sprintf("note 不能超过 %d 个字符", maxAlertNoteLength)))
+		return
+	}
+
+	alert, err := action(c.Request.Context(), id, request.By, request.Note)
+	if errors.Is(err, services.ErrAlertNotFound) {
+		c.Error(middleware.ErrNotFound("告警不存在"))
+		return
+	}
+	if errors.Is(err, services.ErrInvalidAlertTransition) {
+		c.Error(middleware.ErrConflict(err.Error()))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("更新告警状态失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, toAlertResponse(alert))
+}
+
+// AlertRuleRequest 创建或更新告警规则的请求
+type AlertRuleRequest struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`      // threshold 或 zscore
+	Metric    string   `json:"metric"`    // negative_share、average_score 或 volume
+	Direction string   `json:"direction"` // above 或 below，默认 above
+	Threshold *float64 `json:"threshold"` // 指标阈值；zscore 规则为标准差倍数
+	Window    string   `json:"window"`    // 统计窗口长度，如 15m
+	// zscore 规则用作基准的之前窗口数，默认24
+	BaselineWindows int `json:"baseline_windows"`
+	// 窗口内记录少于该值时不评估（volume 指标不受限制），默认10
+	MinCount *int `json:"min_count"`
+	// 告警解决后再次告警的最短间隔，默认与 window 相同
+	Cooldown   string            `json:"cooldown"`
+	UserID     string            `json:"user_id"`
+	Language   string            `json:"language"`
+	Metadata   map[string]string `json:"metadata"` // 只统计元数据全部匹配的记录
+	WebhookURL string            `json:"webhook_url"`
+	Enabled    *bool             `json:"enabled"` // 默认 true
+}
+
+// rule 校验请求并转换为告警规则，未提供的可选字段使用默认值
+func (r *AlertRuleRequest) rule() (*models.AlertRule, error) {
+	if r.Name == "" || utf8.RuneCountInString(r.Name) > maxRuleNameLength {
+		return nil, fmt.Errorf("name 长度必须在1到%d之间", maxRuleNameLength)
+	}
+
+	switch r.Type {
+	case models.AlertRuleTypeThreshold, models.AlertRuleTypeZScore:
+	default:
+		return nil, errors.New("type 必须是 threshold 或 zscore")
+	}
+
+	switch r.Metric {
+	case models.AlertMetricNegativeShare, models.AlertMetricAverageScore, models.AlertMetricVolume:
+	default:
+		return nil, errors.New("metric 必须是 negative_share、average_score 或 volume")
+	}
+
+	if r.Direction == "" {
+		r.Direction = models.AlertDirectionAbove
+	}
+	if r.Direction != models.AlertDirectionAbove && r.Direction != models.AlertDirectionBelow {
+		return nil, errors.New("direction 必须是 above 或 below")
+	}
+
+	if r.Threshold == nil {
+		return nil, errors.New("threshold 不能为空")
+	}
+	threshold := *r.Threshold
+	switch {
+	case r.Type == models.AlertRuleTypeZScore && threshold <= 0:
+		return nil, errors.New("zscore 规则的 threshold 必须大于0")
+	case r.Type == models.AlertRuleTypeThreshold && r.Metric == models.AlertMetricNegativeShare && (threshold < 0 || threshold > 1):
+		return nil, errors.New("negative_share 的 threshold 必须在0到1之间")
+	case r.Type == models.AlertRuleTypeThreshold && r.Metric == models.AlertMetricVolume && threshold < 0:
+		return nil, errors.New("volume 的 threshold 不能为负数")
+	}
+
+	window, err := time.ParseDuration(r.Window)
+	if err != nil || window < minRuleWindow || window > maxRuleWindow || window%time.Second != 0 {
+		return nil, fmt.Errorf("window 必须是 %s 到 %s 之间的整秒时长（如 15m）", minRuleWindow, maxRuleWindow)
+	}
+
+	baselineWindows := 0
+	if r.Type == models.AlertRuleTypeZScore {
+		baselineWindows = r.BaselineWindows
+		if baselineWindows == 0 {
+			baselineWindows = defaultBaselineWindows
+		}
+		if baselineWindows < services.MinBaselineWindows || baselineWindows > maxBaselineWindows {
+			return nil, fmt.Errorf("baseline_windows 必须在%d到%d之间", services.MinBaselineWindows, maxBaselineWindows)
+		}
+	}
+
+	minCount := defaultRuleMinCount
+	if r.MinCount != nil {
+		minCount = *r.MinCount
+	}
+	if minCount < 0 {
+		return nil, errors.New("min_count 不能为负数")
+	}
+
+	cooldown := window
+	if r.Cooldown != "" {
+		cooldown, err = time.ParseDuration(r.Cooldown)
+		if err != nil || cooldown < 0 || cooldown > maxRuleWindow {
+			return nil, fmt.Errorf("cooldown 必须是0到%s之间的时长", maxRuleWindow)
+		}
+	}
+
+	for key := range r.Metadata {
+		if key == "" || len(key) > maxMetadataKeyLength {
+			return nil, fmt.Errorf("元数据键长度必须在1到%d之间", maxMetadataKeyLength)
+		}
+	}
+	if len(r.Metadata) == 0 {
+		r.Metadata = nil
+	}
+
+	webhookURL, err := url.Parse(r.WebhookURL)
+	if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+		return nil, errors.New("webhook_url 必须是 http 或 https 地址")
+	}
+
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+
+	return &models.AlertRule{
+		Name:            r.Name,
+		Type:            r.Type,
+		Metric:          r.Metric,
+		Direction:       r.Direction,
+		Threshold:       threshold,
+		WindowSeconds:   int(window / time.Second),
+		BaselineWindows: baselineWindows,
+		MinCount:        minCount,
+		CooldownSeconds: int(cooldown / time.Second),
+		UserID:          r.UserID,
+		Language:        r.Language,
+		Metadata:        r.Metadata,
+		WebhookURL:      r.WebhookURL,
+		Enabled:         enabled,
+	}, nil
+}
+
+// AlertActionRequest 确认或解决告警的请求
+type AlertActionRequest struct {
+	By   string `json:"by"`   // 操作人
+	Note string `json:"note"` // 备注
+}
+
+// AlertRuleResponse 告警规则
+type AlertRuleResponse struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Type            string            `json:"type"`
+	Metric          string            `json:"metric"`
+	Direction       string            `json:"direction"`
+	Threshold       float64           `json:"threshold"`
+	Window          string            `json:"window"`
+	BaselineWindows int               `json:"baseline_windows,omitempty"`
+	MinCount        int               `json:"min_count"`
+	Cooldown        string            `json:"cooldown"`
+	UserID          string            `json:"user_id,omitempty"`
+	Language        string            `json:"language,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	WebhookURL      string            `json:"webhook_url"`
+	Enabled         bool              `json:"enabled"`
+	CreatedAt       int64             `json:"created_at"`
+	UpdatedAt       int64             `json:"updated_at"`
+}
+
+// AlertRuleListResponse 告警规则列表响应
+type AlertRuleListResponse struct {
+	Rules      []AlertRuleResponse `json:"rules"`
+	TotalCount int                 `json:"total_count"`
+}
+
+// AlertResponse 告警
+type AlertResponse struct {
+	ID             string   `json:"id"`
+	RuleID         string   `json:"rule_id"`
+	Status         string   `json:"status"`
+	Value          float64  `json:"value"`
+	Threshold      float64  `json:"threshold"`
+	BaselineMean   *float64 `json:"baseline_mean,omitempty"`
+	BaselineStddev *float64 `json:"baseline_stddev,omitempty"`
+	ZScore         *float64 `json:"z_score,omitempty"`
+	SampleCount    int      `json:"sample_count"`
+	WindowStart    int64    `json:"window_start"`
+	WindowEnd      int64    `json:"window_end"`
+	Message        string   `json:"message"`
+	AcknowledgedAt *int64   `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string   `json:"acknowledged_by,omitempty"`
+	ResolvedAt     *int64   `json:"resolved_at,omitempty"`
+	ResolvedBy     string   `json:"resolved_by,omitempty"` // 自动解决时为 system
+	Note           string   `json:"note,omitempty"`
+	NotifiedAt     *int64   `json:"notified_at,omitempty"`
+	NotifyError    string   `json:"notify_error,omitempty"`
+	CreatedAt      int64    `json:"created_at"`
+	UpdatedAt      int64    `json:"updated_at"`
+}
+
+// AlertListResponse 告警列表响应
+type AlertListResponse struct {
+	Alerts     []AlertResponse `json:"alerts"`
+	TotalCount int             `json:"total_count"`
+}
+
+// AlertEvaluationResponse 规则的评估结果
+type AlertEvaluationResponse struct {
+	RuleID      string `json:"rule_id"`
+	WindowStart int64  `json:"window_start"`
+	WindowEnd   int64  `json:"window_end"`
+	SampleCount int    `json:"sample_count"`
+	// Evaluable 为 false 时数据不足，不会触发或自动解决告警
+	Evaluable       bool     `json:"evaluable"`
+	Reason          string   `json:"reason,omitempty"`
+	Breached        bool     `json:"breached"`
+	Value           *float64 `json:"value"`
+	Threshold       float64  `json:"threshold"`
+	BaselineWindows int      `json:"baseline_windows,omitempty"`
+	BaselineMean    *float64 `json:"baseline_mean,omitempty"`
+	BaselineStddev  *float64 `json:"baseline_stddev,omitempty"`
+	ZScore          *float64 `json:"z_score,omitempty"`
+	Message         string   `json:"message,omitempty"`
+}
+
+// toAlertRuleResponse 将告警规则转换为响应
+func toAlertRuleResponse(rule *models.AlertRule) AlertRuleResponse {
+	return AlertRuleResponse{
+		ID:              rule.ID,
+		Name:            rule.Name,
+		Type:            rule.Type,
+		Metric:          rule.Metric,
+		Direction:       rule.Direction,
+		Threshold:       rule.Threshold,
+		Window:          rule.Window().String(),
+		BaselineWindows: rule.BaselineWindows,
+		MinCount:        rule.MinCount,
+		Cooldown:        rule.Cooldown().String(),
+		UserID:          rule.UserID,
+		Language:        rule.Language,
+		Metadata:        rule.Metadata,
+		WebhookURL:      rule.WebhookURL,
+		Enabled:         rule.Enabled,
+		CreatedAt:       rule.CreatedAt.Unix(),
+		UpdatedAt:       rule.UpdatedAt.Unix(),
+	}
+}
+
+// toAlertResponse 将告警转换为响应
+func toAlertResponse(alert *models.Alert) AlertResponse {
+	return AlertResponse{
+		ID:             alert.ID,
+		RuleID:         alert.RuleID,
+		Status:         alert.Status,
+		Value:          alert.Value,
+		Threshold:      alert.Threshold,
+		BaselineMean:   alert.BaselineMean,
+		BaselineStddev: alert.BaselineStddev,
+		ZScore:         alert.ZScore,
+		SampleCount:    alert.SampleCount,
+		WindowStart:    alert.WindowStart.Unix(),
+		WindowEnd:      alert.WindowEnd.Unix(),
+		Message:        alert.Message,
+		AcknowledgedAt: unixOrNil(alert.AcknowledgedAt),
+		AcknowledgedBy: alert.AcknowledgedBy,
+		ResolvedAt:     unixOrNil(alert.ResolvedAt),
+		ResolvedBy:     alert.ResolvedBy,
+		Note:           alert.Note,
+		NotifiedAt:     unixOrNil(alert.NotifiedAt),
+		NotifyError:    alert.NotifyError,
+		CreatedAt:      alert.CreatedAt.Unix(),
+		UpdatedAt:      alert.UpdatedAt.Unix(),
+	}
+}
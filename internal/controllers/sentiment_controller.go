@@ -1,13 +1,22 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
+	"github.com/google/uuid"
 
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/middleware"
+	"sentiment-service/internal/models"
 	"sentiment-service/internal/services"
 )
 
@@ -28,8 +37,11 @@ func NewSentimentController(sentimentService *services.SentimentService) *Sentim
 // @Accept json
 // @Produce json
 // @Param request body AnalyzeSentimentRequest true "分析请求"
+// @Param X-Request-ID header string false "请求ID，存储结果时作为分析记录的请求ID（最多50个字符，字母、数字和 - _ . :）"
 // @Success 200 {object} SentimentResponse
+// @Success 202 {object} AsyncResponse "启用降级时，分析服务不可用会转为异步处理"
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "X-Request-ID 已被其他分析记录使用"
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/sentiment/analyze [post]
 func (sc *SentimentController) AnalyzeSentiment(c *gin.Context) {
@@ -53,8 +65,23 @@ func (sc *SentimentController) AnalyzeSentiment(c *gin.Context) {
 		request.StoreResult,
 		request.Metadata,
 	)
+
+	// 已降级为异步任务
+	var queuedErr *services.QueuedError
+	if errors.As(err, &queuedErr) {
+		c.JSON(http.StatusAccepted, AsyncResponse{
+			RequestID: queuedErr.RequestID,
+			Status:    "pending",
+			Message:   "分析服务暂时不可用，请求已转为异步处理",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrDuplicateRequestID) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
-		logrus.WithError(err).Error("分析情感失败")
+		logging.FromContext(c.Request.Context()).WithError(err).Error("分析情感失败")
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "处理请求失败"})
 		return
 	}
@@ -80,9 +107,12 @@ func (sc *SentimentController) AnalyzeSentiment(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body AnalyzeSentimentRequest true "分析请求"
+// @Param X-Request-ID header string false "请求ID，作为异步任务和分析记录的请求ID（最多50个字符，字母、数字和 - _ . :）"
 // @Success 202 {object} AsyncResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "X-Request-ID 已被其他分析记录或进行中的任务使用"
 // @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
 // @Router /api/v1/sentiment/analyze/async [post]
 func (sc *SentimentController) AnalyzeSentimentAsync(c *gin.Context) {
 	var request AnalyzeSentimentRequest
@@ -106,8 +136,16 @@ func (sc *SentimentController) AnalyzeSentimentAsync(c *gin.Context) {
 		request.StoreResult,
 		request.Metadata,
 	)
+	if errors.Is(err, services.ErrShuttingDown) {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrDuplicateRequestID) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
-		logrus.WithError(err).Error("提交异步分析失败")
+		logging.FromContext(c.Request.Context()).WithError(err).Error("提交异步分析失败")
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "处理请求失败"})
 		return
 	}
@@ -128,10 +166,13 @@ func (sc *SentimentController) AnalyzeSentimentAsync(c *gin.Context) {
 // @Tags sentiment
 // @Accept json
 // @Produce json
+// @Produce application/x-ndjson
 // @Param request body BatchAnalyzeSentimentRequest true "批量分析请求"
-// @Success 200 {object} BatchSentimentResponse
+// @Param Accept header string false "application/x-ndjson 时逐条输出 BatchStreamItem，最后一行为 BatchStreamSummary"
+// @Success 200 {object} BatchSentimentResponse "全部或部分项目成功，每个项目带有状态"
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} BatchSentimentResponse "所有项目都分析失败"
 // @Router /api/v1/sentiment/batch [post]
 func (sc *SentimentController) BatchAnalyzeSentiment(c *gin.Context) {
 	var request BatchAnalyzeSentimentRequest
@@ -142,20 +183,27 @@ func (sc *SentimentController) BatchAnalyzeSentiment(c *gin.Context) {
 	}
 
 	// 验证必要参数
-	if len(request.Texts) == 0 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "文本列表不能为空"})
+	inputs, err := request.inputs()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// 客户端接受 NDJSON 时逐条输出结果
+	if c.NegotiateFormat(gin.MIMEJSON, mimeNDJSON) == mimeNDJSON {
+		sc.streamBatchResults(c, &request, inputs)
 		return
 	}
 
 	result, err := sc.sentimentService.BatchAnalyzeSentiment(
 		c.Request.Context(),
-		request.Texts,
+		inputs,
 		request.Language,
 		request.StoreResults,
 		request.Metadata,
 	)
 	if err != nil {
-		logrus.WithError(err).Error("批量分析情感失败")
+		logging.FromContext(c.Request.Context()).WithError(err).Error("批量分析情感失败")
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "处理请求失败"})
 		return
 	}
@@ -163,73 +211,283 @@ func (sc *SentimentController) BatchAnalyzeSentiment(c *gin.Context) {
 	// 构建响应
 	response := BatchSentimentResponse{
 		BatchID: result.BatchID,
-		Results: make([]SentimentResponse, len(result.Results)),
+		Status:  result.Status,
+		Summary: toBatchSummaryResponse(result.Summary),
+		Results: make([]BatchItemResponse, len(result.Results)),
+	}
+	for i, item := range result.Results {
+		response.Results[i] = toBatchItemResponse(item)
 	}
 
-	// 转换每个结果
-	for i, res := range result.Results {
-		response.Results[i] = SentimentResponse{
-			Text:             res.Text,
-			Sentiment:        res.Sentiment,
-			Score:            res.Score,
-			ConfidenceScores: res.ConfidenceScores,
-			Keywords:         res.Keywords,
-			RequestID:        res.RequestID,
-			Timestamp:        res.Timestamp.Unix(),
-		}
+	// 所有项目都失败时返回502，响应中仍包含每个项目的失败原因
+	if result.Status == models.BatchStatusFailed {
+		c.JSON(http.StatusBadGateway, response)
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// mimeNDJSON 换行分隔的JSON流
+const mimeNDJSON = "application/x-ndjson"
+
+// streamBatchResults 以 NDJSON 输出批量分析结果：每个项目完成后立即输出一行并刷新，
+// 行的顺序为完成顺序（用 index 对应输入），最后输出一行汇总。
+// 需要存储结果时成功的项目先以 analyzed 状态输出，存储失败的项目在汇总前再输出一行 failed，
+// 同一 index 以最后一行为准
+// 响应开始后状态码固定为200，失败信息只体现在各行和汇总中
+func (sc *SentimentController) streamBatchResults(c *gin.Context, request *BatchAnalyzeSentimentRequest, inputs []services.BatchInput) {
+	ctx := c.Request.Context()
+	logger := logging.FromContext(ctx)
+
+	c.Header("Content-Type", mimeNDJSON)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	writeLine := func(line interface{}) {
+		if err := encoder.Encode(line); err != nil {
+			// 客户端断开时请求上下文随之取消，分析会提前结束
+			logger.WithError(err).Debug("写入批量分析结果失败")
+			return
+		}
+		c.Writer.Flush()
+	}
+
+	result, err := sc.sentimentService.StreamBatchAnalyzeSentiment(
+		ctx,
+		inputs,
+		request.Language,
+		request.StoreResults,
+		request.Metadata,
+		func(index int, item models.BatchItemResult) {
+			writeLine(BatchStreamItem{
+				Type:              batchStreamTypeResult,
+				Index:             index,
+				BatchItemResponse: toBatchItemResponse(item),
+			})
+		},
+	)
+	if err != nil {
+		logger.WithError(err).Error("批量分析情感失败")
+		writeLine(BatchStreamError{Type: batchStreamTypeError, Error: "处理请求失败"})
+		return
+	}
+
+	writeLine(BatchStreamSummary{
+		Type:    batchStreamTypeSummary,
+		BatchID: result.BatchID,
+		Status:  result.Status,
+		Summary: toBatchSummaryResponse(result.Summary),
+	})
+}
+
+// SubmitBatch 提交异步批量分析
+// @Summary 提交异步批量分析
+// @Description 立即返回批处理ID，文本在后台分块分析并存储，通过批处理查询接口获取进度和结果
+// @Tags sentiment
+// @Accept json
+// @Produce json
+// @Param request body BatchAnalyzeSentimentRequest true "批量分析请求"
+// @Success 202 {object} AsyncBatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/v1/sentiment/batch/async [post]
+func (sc *SentimentController) SubmitBatch(c *gin.Context) {
+	var request BatchAnalyzeSentimentRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	// 验证必要参数
+	inputs, err := request.inputs()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	batch, err := sc.sentimentService.SubmitBatch(c.Request.Context(), services.BatchJob{
+		Source:   services.NewInputSource(inputs),
+		Count:    len(inputs),
+		Language: request.Language,
+		Metadata: request.Metadata,
+	})
+	if errors.Is(err, services.ErrBatchTooLarge) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrShuttingDown) {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("提交异步批处理失败")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "处理请求失败"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, AsyncBatchResponse{
+		BatchID: batch.ID,
+		Status:  batch.Status,
+		Count:   batch.Count,
+		Message: "批处理已提交，正在后台处理",
+	})
+}
+
+// UploadBatch 上传CSV或JSONL文件进行异步批量分析
+// @Summary 上传文件进行异步批量分析
+// @Description 上传CSV（指定文本列，其余列作为元数据）或JSONL文件，文件被完整校验后在后台分块分析
+// @Tags sentiment
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV或JSONL文件"
+// @Param format formData string false "文件格式（csv 或 jsonl），默认根据扩展名推断"
+// @Param text_column formData string false "文本所在的列或字段" default(text)
+// @Param language formData string false "文本语言"
+// @Param user_id formData string false "用户ID"
+// @Success 202 {object} AsyncBatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/v1/sentiment/batch/upload [post]
+func (sc *SentimentController) UploadBatch(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: fmt.Sprintf("上传文件超过 %d 字节", maxBytesErr.Limit)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "缺少上传文件: " + err.Error()})
+		return
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = services.UploadFormatFromFilename(fileHeader.Filename)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.WithError(err).Error("打开上传文件失败")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "处理请求失败"})
+		return
+	}
+	defer file.Close()
+
+	// 暂存到临时文件并完整校验，后台任务逐行读取，不会把整个文件读入内存
+	upload, err := services.SpoolUpload(file, format, c.PostForm("text_column"))
+	if errors.Is(err, services.ErrInvalidUpload) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("暂存上传文件失败")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "处理请求失败"})
+		return
+	}
+	source, err := upload.Source()
+	if err != nil {
+		upload.Discard()
+		logger.WithError(err).Error("读取上传文件失败")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "处理请求失败"})
+		return
+	}
+
+	metadata := map[string]string{}
+	if userID := c.PostForm("user_id"); userID != "" {
+		metadata["user_id"] = userID
+	}
+
+	batch, err := sc.sentimentService.SubmitBatch(c.Request.Context(), services.BatchJob{
+		Source:   source,
+		Count:    upload.Count,
+		Language: c.PostForm("language"),
+		Metadata: metadata,
+		Upload:   upload,
+	})
+	if errors.Is(err, services.ErrBatchTooLarge) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrShuttingDown) {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("提交上传批处理失败")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "处理请求失败"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, AsyncBatchResponse{
+		BatchID: batch.ID,
+		Status:  batch.Status,
+		Count:   batch.Count,
+		Message: "文件已接收，正在后台处理",
+	})
+}
+
 // GetAnalysisHistory 获取情感分析历史记录
 // @Summary 获取情感分析历史记录
-// @Description 返回指定用户的情感分析历史记录
+// @Description 返回符合条件的情感分析历史记录，所有条件同时生效
 // @Tags sentiment
 // @Accept json
 // @Produce json
 // @Param user_id query string false "用户ID"
-// @Param start_time query int false "开始时间戳"
-// @Param end_time query int false "结束时间戳"
-// @Param limit query int false "每页结果数量" default(50)
-// @Param offset query int false "分页偏移量" default(0)
+// @Param start_time query int false "开始时间戳（秒），0表示不限制"
+// @Param end_time query int false "结束时间戳（秒），0表示不限制"
+// @Param sentiment query string false "情感标签（positive, negative, neutral）"
+// @Param language query string false "语言代码"
+// @Param min_score query number false "最低分数（含）"
+// @Param max_score query number false "最高分数（含）"
+// @Param keyword query string false "包含的关键词（完整匹配，不区分大小写）"
+// @Param batch_id query string false "批处理ID"
+// @Param metadata[key] query string false "元数据过滤，例如 metadata[channel]=email，可以指定多个"
+// @Param q query string false "全文检索条件，语法与 /search 相同"
+// @Param sort query string false "排序字段（created_at, score）" default(created_at)
+// @Param order query string false "排序方向（asc, desc）" default(desc)
+// @Param limit query int false "每页结果数量（1-1000）" default(50)
+// @Param offset query int false "分页偏移量（不能与 cursor 同时使用）" default(0)
+// @Param cursor query string false "上一次响应中的 next_cursor 或 prev_cursor"
+// @Param include_total query bool false "是否统计总数，默认只在第一页统计"
 // @Success 200 {object} GetAnalysisHistoryResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/sentiment/history [get]
 func (sc *SentimentController) GetAnalysisHistory(c *gin.Context) {
-	// 获取查询参数
-	userID := c.Query("user_id")
-
-	// 解析时间参数
-	startTimeStr := c.DefaultQuery("start_time", "0")
-	endTimeStr := c.DefaultQuery("end_time", "0")
-
-	startTime := time.Unix(parseTimestamp(startTimeStr), 0)
-	endTime := time.Unix(parseTimestamp(endTimeStr), 0)
-
-	// 解析分页参数
-	limit := parseIntParam(c.DefaultQuery("limit", "50"))
-	offset := parseIntParam(c.DefaultQuery("offset", "0"))
+	// 已有接口，错误响应保持 ErrorResponse 格式
+	query, err := historyQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
 
 	// 调用服务获取历史记录
-	result, err := sc.sentimentService.GetAnalysisHistory(
-		c.Request.Context(),
-		userID,
-		startTime,
-		endTime,
-		limit,
-		offset,
-	)
+	result, err := sc.sentimentService.GetAnalysisHistory(c.Request.Context(), query)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
-		logrus.WithError(err).Error("获取情感分析历史失败")
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取情感分析历史失败")
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "处理请求失败"})
 		return
 	}
 
 	// 构建响应
 	response := GetAnalysisHistoryResponse{
-		TotalCount: result.TotalCount,
+		TotalCount: totalCount(result.TotalCount),
 		Records:    make([]SentimentRecord, len(result.Records)),
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
 	}
 
 	// 转换每个记录
@@ -247,6 +505,314 @@ func (sc *SentimentController) GetAnalysisHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// SearchAnalyses 全文检索情感分析记录
+// @Summary 全文检索情感分析记录
+// @Description 按 q 检索分析文本（英文按词干匹配，中文按字短语匹配），支持历史记录的所有过滤条件
+// @Description snippet 中的命中词用 <mark></mark> 包裹，其余部分已做HTML转义
+// @Tags sentiment
+// @Produce json
+// @Param q query string true "检索词，支持 websearch 语法：空格表示同时包含，引号表示短语，or，-排除"
+// @Param user_id query string false "用户ID"
+// @Param start_time query int false "开始时间戳（秒），0表示不限制"
+// @Param end_time query int false "结束时间戳（秒），0表示不限制"
+// @Param sentiment query string false "情感标签（positive, negative, neutral）"
+// @Param language query string false "语言代码（en 或 zh 开头时只按该语言检索）"
+// @Param min_score query number false "最低分数（含）"
+// @Param max_score query number false "最高分数（含）"
+// @Param keyword query string false "包含的关键词（完整匹配，不区分大小写）"
+// @Param batch_id query string false "批处理ID"
+// @Param metadata[key] query string false "元数据过滤，例如 metadata[channel]=email，可以指定多个"
+// @Param sort query string false "排序字段（relevance, created_at, score）" default(relevance)
+// @Param order query string false "排序方向（asc, desc），按相关度排序时忽略" default(desc)
+// @Param limit query int false "每页结果数量（1-1000）" default(50)
+// @Param offset query int false "分页偏移量" default(0)
+// @Param include_total query bool false "是否统计总数" default(true)
+// @Success 200 {object} SearchAnalysesResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/search [get]
+func (sc *SentimentController) SearchAnalyses(c *gin.Context) {
+	query, err := searchQuery(c)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	result, err := sc.sentimentService.SearchAnalyses(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("全文检索情感分析失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	response := SearchAnalysesResponse{
+		TotalCount: totalCount(result.TotalCount),
+		Records:    make([]SearchRecord, len(result.Hits)),
+	}
+	for i, hit := range result.Hits {
+		response.Records[i] = SearchRecord{
+			SentimentRecord: SentimentRecord{
+				ID:        hit.ID,
+				Text:      hit.Text,
+				Sentiment: hit.Sentiment,
+				Score:     hit.Score,
+				Timestamp: hit.Timestamp.Unix(),
+				Metadata:  hit.Metadata,
+			},
+			Rank:    hit.Rank,
+			Snippet: hit.Snippet,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAnalysis 根据ID获取单条情感分析记录
+// @Summary 根据ID获取情感分析记录
+// @Description 返回完整的分析记录，包括语言、关键词和元数据
+// @Tags sentiment
+// @Produce json
+// @Param id path string true "分析记录ID"
+// @Success 200 {object} AnalysisResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/analyses/{id} [get]
+func (sc *SentimentController) GetAnalysis(c *gin.Context) {
+	id := c.Param("id")
+
+	// 分析记录ID是UUID，提前校验避免数据库类型错误
+	if _, err := uuid.Parse(id); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的分析记录ID"))
+		return
+	}
+
+	record, err := sc.sentimentService.GetAnalysis(c.Request.Context(), id)
+	sc.respondAnalysis(c, record, err)
+}
+
+// GetAnalysisByRequestID 根据请求ID获取单条情感分析记录
+// @Summary 根据请求ID获取情感分析记录
+// @Description 返回完整的分析记录，包括语言、关键词和元数据；可用于查询异步分析的结果
+// @Tags sentiment
+// @Produce json
+// @Param request_id path string true "请求ID"
+// @Success 200 {object} AnalysisResponse
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/analyses/by-request/{request_id} [get]
+func (sc *SentimentController) GetAnalysisByRequestID(c *gin.Context) {
+	record, err := sc.sentimentService.GetAnalysisByRequestID(c.Request.Context(), c.Param("request_id"))
+	sc.respondAnalysis(c, record, err)
+}
+
+// respondAnalysis 输出单条分析记录，错误交由 ErrorHandler 中间件处理
+func (sc *SentimentController) respondAnalysis(c *gin.Context, record *models.AnalysisRecord, err error) {
+	if errors.Is(err, services.ErrAnalysisNotFound) {
+		c.Error(middleware.ErrNotFound("分析记录不存在"))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取分析记录失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, toAnalysisResponse(record))
+}
+
+// ListBatches 获取批处理列表
+// @Summary 获取批处理列表
+// @Description 返回批处理分析列表，按创建时间倒序
+// @Tags sentiment
+// @Produce json
+// @Param user_id query string false "用户ID"
+// @Param status query string false "批处理状态"
+// @Param limit query int false "每页结果数量" default(50)
+// @Param offset query int false "分页偏移量" default(0)
+// @Success 200 {object} BatchListResponse
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/batches [get]
+func (sc *SentimentController) ListBatches(c *gin.Context) {
+	limit := parseIntParam(c.DefaultQuery("limit", "50"))
+	offset := parseIntParam(c.DefaultQuery("offset", "0"))
+
+	result, err := sc.sentimentService.ListBatches(
+		c.Request.Context(),
+		c.Query("user_id"),
+		c.Query("status"),
+		limit,
+		offset,
+	)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取批处理列表失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	response := BatchListResponse{
+		Batches:    make([]BatchStatusResponse, len(result.Batches)),
+		TotalCount: result.TotalCount,
+	}
+	for i, batch := range result.Batches {
+		response.Batches[i] = toBatchStatusResponse(batch)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBatch 获取批处理状态
+// @Summary 获取批处理状态
+// @Description 返回批处理的状态、数量和时间戳
+// @Tags sentiment
+// @Produce json
+// @Param batch_id path string true "批处理ID"
+// @Success 200 {object} BatchStatusResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/batches/{batch_id} [get]
+func (sc *SentimentController) GetBatch(c *gin.Context) {
+	batchID := c.Param("batch_id")
+	if _, err := uuid.Parse(batchID); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的批处理ID"))
+		return
+	}
+
+	batch, err := sc.sentimentService.GetBatch(c.Request.Context(), batchID)
+	if errors.Is(err, services.ErrBatchNotFound) {
+		c.Error(middleware.ErrNotFound("批处理记录不存在"))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取批处理状态失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, toBatchStatusResponse(*batch))
+}
+
+// GetBatchResults 获取批处理结果
+// @Summary 获取批处理结果
+// @Description 按输入顺序分页返回批处理中每个文本的分析结果
+// @Tags sentiment
+// @Produce json
+// @Param batch_id path string true "批处理ID"
+// @Param limit query int false "每页结果数量（1-1000）" default(50)
+// @Param offset query int false "分页偏移量（不能与 cursor 同时使用）" default(0)
+// @Param cursor query string false "上一次响应中的 next_cursor 或 prev_cursor"
+// @Param include_total query bool false "是否统计总数，默认只在第一页统计"
+// @Success 200 {object} BatchResultsResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/batches/{batch_id}/results [get]
+func (sc *SentimentController) GetBatchResults(c *gin.Context) {
+	batchID := c.Param("batch_id")
+	if _, err := uuid.Parse(batchID); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的批处理ID"))
+		return
+	}
+
+	query, err := pageQuery(c)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	page, err := sc.sentimentService.GetBatchResults(c.Request.Context(), batchID, query)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+	if errors.Is(err, services.ErrBatchNotFound) {
+		c.Error(middleware.ErrNotFound("批处理记录不存在"))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取批处理结果失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	response := BatchResultsResponse{
+		BatchID:    page.BatchID,
+		Results:    make([]BatchResultResponse, len(page.Records)),
+		TotalCount: totalCount(page.TotalCount),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+	for i, record := range page.Records {
+		response.Results[i] = BatchResultResponse{
+			Order:            record.Order,
+			ItemID:           record.ItemID,
+			AnalysisResponse: toAnalysisResponse(&record.AnalysisRecord),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DownloadBatchResults 下载批处理结果文件
+// @Summary 下载批处理结果
+// @Description 按输入顺序导出批处理的每个项目（含失败和跳过的项目），上传文件产生的批处理原样保留原始列，并追加 status、error、sentiment、score、keywords 列（与原始列同名时加 result_ 前缀）
+// @Tags sentiment
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param batch_id path string true "批处理ID"
+// @Param format query string false "导出格式（csv 或 jsonl），默认与上传格式相同"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 404 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/batches/{batch_id}/results/download [get]
+func (sc *SentimentController) DownloadBatchResults(c *gin.Context) {
+	batchID := c.Param("batch_id")
+	if _, err := uuid.Parse(batchID); err != nil {
+		c.Error(middleware.ErrBadRequest("无效的批处理ID"))
+		return
+	}
+
+	batch, err := sc.sentimentService.GetBatch(c.Request.Context(), batchID)
+	if errors.Is(err, services.ErrBatchNotFound) {
+		c.Error(middleware.ErrNotFound("批处理记录不存在"))
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("获取批处理状态失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = batch.SourceFormat
+	}
+	if format == "" {
+		format = services.UploadFormatCSV
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case services.UploadFormatCSV:
+	case services.UploadFormatJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		c.Error(middleware.ErrBadRequest("不支持的导出格式: " + format))
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="batch_%s_results.%s"`, batchID, format))
+	c.Status(http.StatusOK)
+
+	// 响应已开始输出，出错时只能记录日志
+	if err := sc.sentimentService.ExportBatchResults(c.Request.Context(), batchID, format, c.Writer); err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("导出批处理结果失败")
+	}
+}
+
 // 以下是请求和响应结构体定义
 
 // AnalyzeSentimentRequest 单个文本情感分析请求
@@ -257,12 +823,57 @@ type AnalyzeSentimentRequest struct {
 	Metadata    map[string]string `json:"metadata"`
 }
 
-// BatchAnalyzeSentimentRequest 批量文本情感分析请求
+// BatchAnalyzeSentimentRequest 批量文本情感分析请求，texts 和 items 二选一
 type BatchAnalyzeSentimentRequest struct {
-	Texts        []string          `json:"texts" binding:"required"`
-	Language     string            `json:"language"`
-	StoreResults bool              `json:"store_results"`
-	Metadata     map[string]string `json:"metadata"`
+	Texts        []string           `json:"texts"`
+	Items        []BatchItemRequest `json:"items"`
+	Language     string             `json:"language"`
+	StoreResults bool               `json:"store_results"`
+	Metadata     map[string]string  `json:"metadata"`
+}
+
+// BatchItemRequest 批量分析中的单个项目
+type BatchItemRequest struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// maxItemIDLength 项目ID的最大长度，受 batch_items.item_id 限制
+const maxItemIDLength = 100
+
+// inputs 校验请求并转换为批处理输入
+func (r *BatchAnalyzeSentimentRequest) inputs() ([]services.BatchInput, error) {
+	if len(r.Texts) > 0 && len(r.Items) > 0 {
+		return nil, errors.New("texts 和 items 不能同时提供")
+	}
+
+	if len(r.Items) == 0 {
+		if len(r.Texts) == 0 {
+			return nil, errors.New("文本列表不能为空")
+		}
+		inputs := make([]services.BatchInput, len(r.Texts))
+		for i, text := range r.Texts {
+			inputs[i] = services.BatchInput{Text: text}
+		}
+		return inputs, nil
+	}
+
+	inputs := make([]services.BatchInput, len(r.Items))
+	seen := make(map[string]bool, len(r.Items))
+	for i, item := range r.Items {
+		if len(item.ID) > maxItemIDLength {
+			return nil, fmt.Errorf("第 %d 个项目的ID超过 %d 个字符", i+1, maxItemIDLength)
+		}
+		if item.ID != "" {
+			if seen[item.ID] {
+				return nil, fmt.Errorf("项目ID重复: %s", item.ID)
+			}
+			seen[item.ID] = true
+		}
+		inputs[i] = services.BatchInput{ID: item.ID, Text: item.Text, Metadata: item.Metadata}
+	}
+	return inputs, nil
 }
 
 // SentimentResponse 情感分析响应
@@ -283,10 +894,65 @@ type AsyncResponse struct {
 	Message   string `json:"message"`
 }
 
-// BatchSentimentResponse 批量情感分析响应
+// BatchSentimentResponse 批量情感分析响应，结果按输入顺序排列
 type BatchSentimentResponse struct {
-	Results []SentimentResponse `json:"results"`
-	BatchID string              `json:"batch_id"`
+	Results []BatchItemResponse  `json:"results"`
+	BatchID string               `json:"batch_id"`
+	Status  string               `json:"status"`
+	Summary BatchSummaryResponse `json:"summary"`
+}
+
+// BatchItemResponse 批量分析中单个项目的结果，失败或跳过的项目只有状态和原因
+type BatchItemResponse struct {
+	ItemID   string            `json:"item_id"`
+	Status   string            `json:"status"`
+	Error    string            `json:"error,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	*SentimentResponse
+}
+
+// NDJSON 流中各行的类型
+const (
+	batchStreamTypeResult  = "result"
+	batchStreamTypeSummary = "summary"
+	batchStreamTypeError   = "error"
+)
+
+// BatchStreamItem NDJSON 流中的单个结果行，index 为项目在输入中的位置
+type BatchStreamItem struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+	BatchItemResponse
+}
+
+// BatchStreamSummary NDJSON 流的最后一行
+type BatchStreamSummary struct {
+	Type    string               `json:"type"`
+	BatchID string               `json:"batch_id"`
+	Status  string               `json:"status"`
+	Summary BatchSummaryResponse `json:"summary"`
+}
+
+// BatchStreamError NDJSON 流中途无法继续时的最后一行
+type BatchStreamError struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// BatchSummaryResponse 批量分析的项目统计
+type BatchSummaryResponse struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+// AsyncBatchResponse 异步批处理提交响应
+type AsyncBatchResponse struct {
+	BatchID string `json:"batch_id"`
+	Status  string `json:"status"`
+	Count   int    `json:"count"`
+	Message string `json:"message"`
 }
 
 // SentimentRecord 情感分析历史记录
@@ -299,10 +965,40 @@ type SentimentRecord struct {
 	Metadata  map[string]string `json:"metadata"`
 }
 
+// AnalysisResponse 单条分析记录的完整信息
+type AnalysisResponse struct {
+	ID        string            `json:"id"`
+	RequestID string            `json:"request_id"`
+	UserID    string            `json:"user_id"`
+	Text      string            `json:"text"`
+	Sentiment string            `json:"sentiment"`
+	Score     float64           `json:"score"`
+	Language  string            `json:"language"`
+	Keywords  []string          `json:"keywords"`
+	Metadata  map[string]string `json:"metadata"`
+	CreatedAt int64             `json:"created_at"`
+	UpdatedAt int64             `json:"updated_at"`
+}
+
 // GetAnalysisHistoryResponse 获取历史记录响应
 type GetAnalysisHistoryResponse struct {
 	Records    []SentimentRecord `json:"records"`
-	TotalCount int               `json:"total_count"`
+	TotalCount *int              `json:"total_count,omitempty"` // 未统计时省略
+	NextCursor string            `json:"next_cursor,omitempty"`
+	PrevCursor string            `json:"prev_cursor,omitempty"`
+}
+
+// SearchRecord 全文检索命中的记录
+type SearchRecord struct {
+	SentimentRecord
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"` // 命中词用 <mark></mark> 包裹
+}
+
+// SearchAnalysesResponse 全文检索响应
+type SearchAnalysesResponse struct {
+	Records    []SearchRecord `json:"records"`
+	TotalCount *int           `json:"total_count,omitempty"` // 未统计时省略
 }
 
 // ErrorResponse 错误响应
@@ -310,15 +1006,329 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// BatchStatusResponse 批处理状态
+type BatchStatusResponse struct {
+	BatchID        string  `json:"batch_id"`
+	UserID         string  `json:"user_id"`
+	Status         string  `json:"status"`
+	Count          int     `json:"count"`
+	ItemCount      int     `json:"item_count,omitempty"`
+	ProcessedCount int     `json:"processed_count"`
+	FailedCount    int     `json:"failed_count"`
+	SkippedCount   int     `json:"skipped_count"`
+	Progress       float64 `json:"progress"`
+	Error          string  `json:"error,omitempty"`
+	StartedAt      *int64  `json:"started_at,omitempty"`
+	CompletedAt    *int64  `json:"completed_at,omitempty"`
+	EstimatedAt    *int64  `json:"estimated_at,omitempty"`
+	CreatedAt      int64   `json:"created_at"`
+	UpdatedAt      int64   `json:"updated_at"`
+}
+
+// BatchListResponse 批处理列表响应
+type BatchListResponse struct {
+	Batches    []BatchStatusResponse `json:"batches"`
+	TotalCount int                   `json:"total_count"`
+}
+
+// BatchResultResponse 批处理中的单个结果
+type BatchResultResponse struct {
+	Order  int    `json:"order"`
+	ItemID string `json:"item_id,omitempty"`
+	AnalysisResponse
+}
+
+// BatchResultsResponse 批处理结果响应
+type BatchResultsResponse struct {
+	BatchID    string                `json:"batch_id"`
+	Results    []BatchResultResponse `json:"results"`
+	TotalCount *int                  `json:"total_count,omitempty"` // 未统计时省略
+	NextCursor string                `json:"next_cursor,omitempty"`
+	PrevCursor string                `json:"prev_cursor,omitempty"`
+}
+
 // 辅助函数
 
-// parseTimestamp 解析时间戳字符串为int64
-func parseTimestamp(timestampStr string) int64 {
-	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+// toAnalysisResponse 转换单条分析记录
+func toAnalysisResponse(record *models.AnalysisRecord) AnalysisResponse {
+	return AnalysisResponse{
+		ID:        record.ID,
+		RequestID: record.RequestID,
+		UserID:    record.UserID,
+		Text:      record.Text,
+		Sentiment: record.Sentiment,
+		Score:     record.Score,
+		Language:  record.Language,
+		Keywords:  record.Keywords,
+		Metadata:  record.Metadata,
+		CreatedAt: record.Timestamp.Unix(),
+		UpdatedAt: record.UpdatedAt.Unix(),
+	}
+}
+
+// toBatchItemResponse 转换批量分析中单个项目的结果
+func toBatchItemResponse(item models.BatchItemResult) BatchItemResponse {
+	response := BatchItemResponse{
+		ItemID:   item.ItemID,
+		Status:   item.Status,
+		Error:    item.Error,
+		Metadata: item.Metadata,
+	}
+	if res := item.Result; res != nil {
+		response.SentimentResponse = &SentimentResponse{
+			Text:             res.Text,
+			Sentiment:        res.Sentiment,
+			Score:            res.Score,
+			ConfidenceScores: res.ConfidenceScores,
+			Keywords:         res.Keywords,
+			RequestID:        res.RequestID,
+			Timestamp:        res.Timestamp.Unix(),
+		}
+	}
+	return response
+}
+
+// toBatchSummaryResponse 转换批量分析的项目统计
+func toBatchSummaryResponse(summary models.BatchSummary) BatchSummaryResponse {
+	return BatchSummaryResponse{
+		Total:     summary.Total,
+		Succeeded: summary.Succeeded,
+		Failed:    summary.Failed,
+		Skipped:   summary.Skipped,
+	}
+}
+
+// toBatchStatusResponse 转换批处理状态
+func toBatchStatusResponse(batch models.BatchRecord) BatchStatusResponse {
+	response := BatchStatusResponse{
+		BatchID:        batch.ID,
+		UserID:         batch.UserID,
+		Status:         batch.Status,
+		Count:          batch.Count,
+		ItemCount:      batch.ItemCount,
+		ProcessedCount: batch.ProcessedCount,
+		FailedCount:    batch.FailedCount,
+		SkippedCount:   batch.SkippedCount,
+		Error:          batch.Error,
+		StartedAt:      unixOrNil(batch.StartedAt),
+		CompletedAt:    unixOrNil(batch.CompletedAt),
+		EstimatedAt:    unixOrNil(batch.EstimatedAt),
+		CreatedAt:      batch.CreatedAt.Unix(),
+		UpdatedAt:      batch.UpdatedAt.Unix(),
+	}
+
+	if batch.Count > 0 {
+		response.Progress = float64(batch.ProcessedCount) / float64(batch.Count)
+	}
+
+	return response
+}
+
+// unixOrNil 转换可选时间为Unix时间戳
+func unixOrNil(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	unix := t.Unix()
+	return &unix
+}
+
+// 可以过滤的情感标签
+var sentimentLabels = map[string]bool{
+	"positive": true,
+	"negative": true,
+	"neutral":  true,
+}
+
+// maxMetadataKeyLength 元数据键的最大长度，与 analysis_metadata.key 列一致
+const maxMetadataKeyLength = 50
+
+// maxSearchQueryLength 全文检索查询的最大字符数
+const maxSearchQueryLength = 256
+
+// maxPageLimit 历史记录、检索和批处理结果每页最多返回的记录数
+const maxPageLimit = 1000
+
+// historyQuery 解析并校验历史记录的查询参数
+func historyQuery(c *gin.Context) (services.HistoryQuery, error) {
+	return analysisQuery(c, services.SortByCreatedAt, services.SortByScore)
+}
+
+// searchQuery 解析并校验全文检索的查询参数，q 必填，默认按相关度排序，不支持游标
+func searchQuery(c *gin.Context) (services.HistoryQuery, error) {
+	query, err := analysisQuery(c, services.SortByRelevance, services.SortByCreatedAt, services.SortByScore)
 	if err != nil {
-		return 0
+		return query, err
+	}
+	if query.Query == "" {
+		return query, errors.New("q 不能为空")
+	}
+	if query.Cursor != "" {
+		return query, errors.New("检索不支持 cursor，请使用 offset 分页")
+	}
+	return query, nil
+}
+
+// analysisQuery 解析分析记录的过滤、排序和分页参数，sorts 是允许的排序字段，第一个为默认值
+func analysisQuery(c *gin.Context, sorts ...string) (services.HistoryQuery, error) {
+	query := services.HistoryQuery{
+		UserID:    c.Query("user_id"),
+		Language:  c.Query("language"),
+		Keyword:   strings.TrimSpace(c.Query("keyword")),
+		BatchID:   c.Query("batch_id"),
+		Query:     strings.TrimSpace(c.Query("q")),
+		SortBy:    c.DefaultQuery("sort", sorts[0]),
+		SortOrder: c.DefaultQuery("order", services.SortDesc),
+	}
+
+	var err error
+	if query.PageQuery, err = pageQuery(c); err != nil {
+		return query, err
+	}
+	if query.StartTime, err = optionalTimestamp(c, "start_time"); err != nil {
+		return query, err
+	}
+	if query.EndTime, err = optionalTimestamp(c, "end_time"); err != nil {
+		return query, err
+	}
+	if query.StartTime != nil && query.EndTime != nil && query.StartTime.After(*query.EndTime) {
+		return query, errors.New("start_time 不能晚于 end_time")
+	}
+
+	if query.MinScore, err = optionalFloat(c, "min_score"); err != nil {
+		return query, err
+	}
+	if query.MaxScore, err = optionalFloat(c, "max_score"); err != nil {
+		return query, err
+	}
+	if query.MinScore != nil && query.MaxScore != nil && *query.MinScore > *query.MaxScore {
+		return query, errors.New("min_score 不能大于 max_score")
+	}
+
+	if sentiment := c.Query("sentiment"); sentiment != "" {
+		if !sentimentLabels[sentiment] {
+			return query, fmt.Errorf("无效的情感标签: %s", sentiment)
+		}
+		query.Sentiment = sentiment
+	}
+
+	if query.BatchID != "" {
+		if _, err := uuid.Parse(query.BatchID); err != nil {
+			return query, errors.New("无效的批处理ID")
+		}
+	}
+
+	if utf8.RuneCountInString(query.Query) > maxSearchQueryLength {
+		return query, fmt.Errorf("q 不能超过%d个字符", maxSearchQueryLength)
+	}
+
+	validSort := false
+	for _, sort := range sorts {
+		validSort = validSort || query.SortBy == sort
+	}
+	if !validSort {
+		return query, fmt.Errorf("无效的排序字段: %s（可选 %s）", query.SortBy, strings.Join(sorts, ", "))
+	}
+	if query.SortOrder != services.SortAsc && query.SortOrder != services.SortDesc {
+		return query, fmt.Errorf("无效的排序方向: %s（可选 asc, desc）", query.SortOrder)
+	}
+
+	metadata, err := metadataFilter(c)
+	if err != nil {
+		return query, err
+	}
+	// user_id 和 batch_id 同时以参数和元数据形式保存，两种写法不能互相矛盾
+	if value, ok := metadata["user_id"]; ok && query.UserID != "" && value != query.UserID {
+		return query, errors.New("user_id 与 metadata[user_id] 不一致")
+	}
+	if value, ok := metadata["batch_id"]; ok && query.BatchID != "" && value != query.BatchID {
+		return query, errors.New("batch_id 与 metadata[batch_id] 不一致")
+	}
+	if len(metadata) > 0 {
+		query.Metadata = metadata
+	}
+
+	return query, nil
+}
+
+// metadataFilter 解析 metadata[key]=value 形式的元数据过滤条件，没有条件时返回nil
+func metadataFilter(c *gin.Context) (map[string]string, error) {
+	metadata := c.QueryMap("metadata")
+	for key := range metadata {
+		if key == "" || len(key) > maxMetadataKeyLength {
+			return nil, fmt.Errorf("元数据键长度必须在1到%d之间", maxMetadataKeyLength)
+		}
+	}
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	return metadata, nil
+}
+
+// pageQuery 解析分页参数：limit 与 offset 或 cursor 分页，include_total 控制是否统计总数
+// 未指定 include_total 时，偏移分页和游标分页的第一页统计总数，之后的游标分页不统计
+func pageQuery(c *gin.Context) (services.PageQuery, error) {
+	page := services.PageQuery{
+		Offset: parseIntParam(c.DefaultQuery("offset", "0")),
+		Cursor: c.Query("cursor"),
+	}
+
+	var err error
+	if page.Limit, err = limitParam(c, "limit", 50, maxPageLimit); err != nil {
+		return page, err
+	}
+	if page.Cursor != "" && page.Offset > 0 {
+		return page, errors.New("cursor 和 offset 不能同时使用")
+	}
+
+	page.IncludeTotal = page.Cursor == ""
+	if value := c.Query("include_total"); value != "" {
+		includeTotal, err := strconv.ParseBool(value)
+		if err != nil {
+			return page, errors.New("include_total 必须是 true 或 false")
+		}
+		page.IncludeTotal = includeTotal
+	}
+
+	return page, nil
+}
+
+// totalCount 转换服务层的总数，-1（未统计）时不输出
+func totalCount(count int) *int {
+	if count < 0 {
+		return nil
+	}
+	return &count
+}
+
+// optionalTimestamp 解析可选的Unix时间戳（秒）参数，缺省或为0时返回nil
+func optionalTimestamp(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || timestamp < 0 {
+		return nil, fmt.Errorf("%s 必须是非负的Unix时间戳", name)
+	}
+	if timestamp == 0 {
+		return nil, nil
+	}
+	t := time.Unix(timestamp, 0)
+	return &t, nil
+}
+
+// optionalFloat 解析可选的数值参数，缺省时返回nil
+func optionalFloat(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("%s 必须是数字", name)
 	}
-	return timestamp
+	return &f, nil
 }
 
 // parseIntParam 解析整数参数字符串为int
@@ -0,0 +1,524 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/middleware"
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/services"
+)
+
+// maxTrendBuckets 一次趋势统计最多返回的时间段数量
+const maxTrendBuckets = 1000
+
+// 关键词和分组统计未指定 start_time 时的时间范围，一次最多返回的关键词或分组数量，最多查询的关键词数量
+const (
+	defaultStatsRange = 24 * time.Hour
+	maxStatsLimit     = 100
+	maxKeywordParams  = 50
+)
+
+// defaultTrendBuckets 未指定 start_time 时统计的时间段数量
+var defaultTrendBuckets = map[string]int{
+	services.IntervalMinute: 60,
+	services.IntervalHour:   24,
+	services.IntervalDay:    30,
+	services.IntervalWeek:   12,
+}
+
+// GetSentimentTrend 按时间段统计情感趋势
+// @Summary 情感趋势统计
+// @Description 按分钟、小时、天或周统计各情感标签的数量和平均分数，没有记录的时间段也会返回（计数为0）
+// @Description 时间段按 timezone 的本地时间对齐，第一个时间段从 start_time 所在时间段的开头开始
+// @Tags stats
+// @Produce json
+// @Param interval query string false "时间粒度（minute, hour, day, week）" default(hour)
+// @Param timezone query string false "IANA 时区名，例如 Asia/Shanghai" default(UTC)
+// @Param start_time query int false "开始时间戳（秒），默认为 end_time 之前24个时间段（分钟60个，天30个，周12个）"
+// @Param end_time query int false "结束时间戳（秒，不含），默认为当前时间"
+// @Param user_id query string false "用户ID"
+// @Param language query string false "语言代码"
+// @Param metadata[key] query string false "元数据过滤，例如 metadata[channel]=email，可以指定多个"
+// @Success 200 {object} TrendResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/stats/trend [get]
+func (sc *SentimentController) GetSentimentTrend(c *gin.Context) {
+	query, err := trendQuery(c)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	result, err := sc.sentimentService.SentimentTrend(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("统计情感趋势失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	response := TrendResponse{
+		Interval:  query.Interval,
+		Timezone:  query.Location.String(),
+		StartTime: query.StartTime.Unix(),
+		EndTime:   query.EndTime.Unix(),
+		Buckets:   make([]TrendBucketResponse, len(result.Buckets)),
+	}
+	for i, bucket := range result.Buckets {
+		response.Buckets[i] = TrendBucketResponse{
+			Start:        bucket.Start.Unix(),
+			Time:         bucket.Start.Format(time.RFC3339),
+			Total:        bucket.Total,
+			Counts:       bucket.Counts,
+			AverageScore: bucket.AverageScore,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// TrendResponse 情感趋势统计响应
+type TrendResponse struct {
+	Interval  string                `json:"interval"`
+	Timezone  string                `json:"timezone"`
+	StartTime int64                 `json:"start_time"`
+	EndTime   int64                 `json:"end_time"`
+	Buckets   []TrendBucketResponse `json:"buckets"`
+}
+
+// TrendBucketResponse 一个时间段的统计结果
+type TrendBucketResponse struct {
+	Start        int64          `json:"start"` // 时间段开始的Unix时间戳（秒）
+	Time         string         `json:"time"`  // 时间段开始时间，RFC3339 格式，带时区偏移
+	Total        int            `json:"total"`
+	Counts       map[string]int `json:"counts"`
+	AverageScore *float64       `json:"average_score"` // 没有记录时为 null
+}
+
+// GetTopKeywords 获取热门关键词
+// @Summary 热门关键词
+// @Description 返回时间范围内出现在最多分析记录中的关键词，以及每个关键词的情感分布和平均分数
+// @Tags stats
+// @Produce json
+// @Param start_time query int false "开始时间戳（秒），默认为 end_time 之前24小时"
+// @Param end_time query int false "结束时间戳（秒，不含），默认为当前时间"
+// @Param user_id query string false "用户ID"
+// @Param language query string false "语言代码"
+// @Param metadata[key] query string false "元数据过滤，例如 metadata[channel]=email，可以指定多个"
+// @Param limit query int false "返回的关键词数量（1-100）" default(20)
+// @Success 200 {object} KeywordStatsResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/stats/keywords/top [get]
+func (sc *SentimentController) GetTopKeywords(c *gin.Context) {
+	filter, err := statsFilter(c, defaultStatsRange)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+	limit, err := limitParam(c, "limit", 20, maxStatsLimit)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	stats, err := sc.sentimentService.TopKeywords(c.Request.Context(), filter, limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("统计热门关键词失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, toKeywordStatsResponse(filter, stats))
+}
+
+// GetKeywordSentiment 获取指定关键词的情感分布
+// @Summary 关键词情感分布
+// @Description 返回每个指定关键词在时间范围内的情感分布和平均分数，顺序与参数一致，关键词不区分大小写
+// @Tags stats
+// @Produce json
+// @Param keyword query []string true "关键词，可以指定多个（最多50个）" collectionFormat(multi)
+// @Param start_time query int false "开始时间戳（秒），默认为 end_time 之前24小时"
+// @Param end_time query int false "结束时间戳（秒，不含），默认为当前时间"
+// @Param user_id query string false "用户ID"
+// @Param language query string false "语言代码"
+// @Param metadata[key] query string false "元数据过滤，例如 metadata[channel]=email，可以指定多个"
+// @Success 200 {object} KeywordStatsResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/stats/keywords/sentiment [get]
+func (sc *SentimentController) GetKeywordSentiment(c *gin.Context) {
+	keywords := c.QueryArray("keyword")
+	if len(keywords) == 0 {
+		c.Error(middleware.ErrBadRequest("keyword 不能为空"))
+		return
+	}
+	if len(keywords) > maxKeywordParams {
+		c.Error(middleware.ErrBadRequest(fmt.Sprintf("最多指定%d个关键词", maxKeywordParams)))
+		return
+	}
+	filter, err := statsFilter(c, defaultStatsRange)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	stats, err := sc.sentimentService.KeywordSentiment(c.Request.Context(), filter, keywords)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("统计关键词情感分布失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, toKeywordStatsResponse(filter, stats))
+}
+
+// GetRisingKeywords 获取增长最快的关键词
+// @Summary 增长最快的关键词
+// @Description 比较时间范围与紧邻的上一个等长时间范围，返回出现次数增长最快的关键词
+// @Description 按 (count+1)/(previous_count+1) 排序，上一个时间范围没有出现的关键词 growth 为 null
+// @Tags stats
+// @Produce json
+// @Param start_time query int false "开始时间戳（秒），默认为 end_time 之前24小时"
+// @Param end_time query int false "结束时间戳（秒，不含），默认为当前时间"
+// @Param user_id query string false "用户ID"
+// @Param language query string false "语言代码"
+// @Param metadata[key] query string false "元数据过滤，例如 metadata[channel]=email，可以指定多个"
+// @Param min_count query int false "当前时间范围内的最少出现次数" default(3)
+// @Param limit query int false "返回的关键词数量（1-100）" default(20)
+// @Success 200 {object} RisingKeywordsResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/stats/keywords/rising [get]
+func (sc *SentimentController) GetRisingKeywords(c *gin.Context) {
+	filter, err := statsFilter(c, defaultStatsRange)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+	limit, err := limitParam(c, "limit", 20, maxStatsLimit)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+	minCount, err := limitParam(c, "min_count", 3, math.MaxInt32)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	keywords, err := sc.sentimentService.RisingKeywords(c.Request.Context(), filter, minCount, limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("统计增长最快的关键词失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	previousStart := filter.StartTime.Add(-filter.EndTime.Sub(filter.StartTime))
+	response := RisingKeywordsResponse{
+		StartTime:         filter.StartTime.Unix(),
+		EndTime:           filter.EndTime.Unix(),
+		PreviousStartTime: previousStart.Unix(),
+		Keywords:          make([]RisingKeywordResponse, len(keywords)),
+	}
+	for i, keyword := range keywords {
+		response.Keywords[i] = RisingKeywordResponse{
+			Keyword:       keyword.Keyword,
+			Count:         keyword.Count,
+			PreviousCount: keyword.PreviousCount,
+			Growth:        keyword.Growth,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetMetadataBreakdown 按元数据分组统计
+// @Summary 按元数据分组统计
+// @Description 按元数据键 key 的值分组，返回每组的记录数、情感分布、平均分数和分数分位数，按记录数倒序
+// @Description 指定 baseline=previous（紧邻的上一个等长时间范围）或 baseline_start_time/baseline_end_time 时，
+// @Description 同时返回每组在基准时间范围内的统计和变化
+// @Tags stats
+// @Produce json
+// @Param key query string true "元数据键，例如 product_id"
+// @Param start_time query int false "开始时间戳（秒），默认为 end_time 之前24小时"
+// @Param end_time query int false "结束时间戳（秒，不含），默认为当前时间"
+// @Param user_id query string false "用户ID"
+// @Param language query string false "语言代码"
+// @Param metadata[key] query string false "元数据过滤，例如 metadata[channel]=email，可以指定多个"
+// @Param limit query int false "返回的分组数量（1-100）" default(20)
+// @Param baseline query string false "previous 表示与上一个等长时间范围比较"
+// @Param baseline_start_time query int false "基准时间范围的开始时间戳（秒），与 baseline_end_time 一起使用"
+// @Param baseline_end_time query int false "基准时间范围的结束时间戳（秒，不含）"
+// @Success 200 {object} BreakdownResponse
+// @Failure 400 {object} map[string]interface{} "code, message"
+// @Failure 500 {object} map[string]interface{} "code, message"
+// @Router /api/v1/sentiment/stats/breakdown [get]
+func (sc *SentimentController) GetMetadataBreakdown(c *gin.Context) {
+	query, err := breakdownQuery(c)
+	if err != nil {
+		c.Error(middleware.ErrBadRequest(err.Error()))
+		return
+	}
+
+	result, err := sc.sentimentService.MetadataBreakdown(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).WithError(err).Error("按元数据分组统计失败")
+		c.Error(middleware.ErrInternalServer("处理请求失败"))
+		return
+	}
+
+	response := BreakdownResponse{
+		Key:       result.Key,
+		StartTime: query.StartTime.Unix(),
+		EndTime:   query.EndTime.Unix(),
+		Truncated: result.Truncated,
+		Groups:    make([]BreakdownGroupResponse, len(result.Groups)),
+	}
+	if query.Baseline != nil {
+		start, end := query.Baseline.Start.Unix(), query.Baseline.End.Unix()
+		response.BaselineStartTime, response.BaselineEndTime = &start, &end
+	}
+	for i, group := range result.Groups {
+		response.Groups[i] = BreakdownGroupResponse{
+			Value:              group.Value,
+			GroupStatsResponse: toGroupStatsResponse(group.Stats),
+		}
+		if group.Baseline != nil {
+			baseline := toGroupStatsResponse(*group.Baseline)
+			response.Groups[i].Baseline = &baseline
+		}
+		if change := group.Change; change != nil {
+			response.Groups[i].Change = &GroupChangeResponse{
+				TotalChange:       change.TotalChange,
+				AverageScoreDelta: change.AverageScoreDelta,
+				ShareDeltas:       change.ShareDeltas,
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// BreakdownResponse 按元数据分组统计响应
+type BreakdownResponse struct {
+	Key               string                   `json:"key"`
+	StartTime         int64                    `json:"start_time"`
+	EndTime           int64                    `json:"end_time"`
+	BaselineStartTime *int64                   `json:"baseline_start_time,omitempty"`
+	BaselineEndTime   *int64                   `json:"baseline_end_time,omitempty"`
+	Truncated         bool                     `json:"truncated"` // 分组数超过 limit，只返回了记录最多的分组
+	Groups            []BreakdownGroupResponse `json:"groups"`
+}
+
+// BreakdownGroupResponse 一个元数据值的统计
+type BreakdownGroupResponse struct {
+	Value string `json:"value"`
+	GroupStatsResponse
+	Baseline *GroupStatsResponse  `json:"baseline,omitempty"`
+	Change   *GroupChangeResponse `json:"change,omitempty"`
+}
+
+// GroupStatsResponse 一组分析记录的统计
+type GroupStatsResponse struct {
+	Total        int                `json:"total"`
+	Counts       map[string]int     `json:"counts"`
+	Shares       map[string]float64 `json:"shares,omitempty"` // 各情感标签的占比
+	AverageScore *float64           `json:"average_score"`
+	Percentiles  map[string]float64 `json:"percentiles,omitempty"` // p10, p25, p50, p75, p90
+}
+
+// GroupChangeResponse 相对基准时间范围的变化，无法计算的项为 null
+type GroupChangeResponse struct {
+	TotalChange       *float64           `json:"total_change"`        // (total-baseline.total)/baseline.total
+	AverageScoreDelta *float64           `json:"average_score_delta"` // average_score-baseline.average_score
+	ShareDeltas       map[string]float64 `json:"share_deltas"`        // 各情感标签占比之差
+}
+
+// toGroupStatsResponse 转换一组分析记录的统计
+func toGroupStatsResponse(stats models.GroupStats) GroupStatsResponse {
+	return GroupStatsResponse{
+		Total:        stats.Total,
+		Counts:       stats.Counts,
+		Shares:       stats.Shares,
+		AverageScore: stats.AverageScore,
+		Percentiles:  stats.Percentiles,
+	}
+}
+
+// KeywordStatsResponse 关键词统计响应
+type KeywordStatsResponse struct {
+	StartTime int64              `json:"start_time"`
+	EndTime   int64              `json:"end_time"`
+	Keywords  []KeywordStatsItem `json:"keywords"`
+}
+
+// KeywordStatsItem 一个关键词的情感分布
+type KeywordStatsItem struct {
+	Keyword      string         `json:"keyword"`
+	Total        int            `json:"total"`
+	Counts       map[string]int `json:"counts"`
+	AverageScore *float64       `json:"average_score"` // 没有记录时为 null
+}
+
+// RisingKeywordsResponse 增长最快的关键词响应
+type RisingKeywordsResponse struct {
+	StartTime         int64                   `json:"start_time"`
+	EndTime           int64                   `json:"end_time"`
+	PreviousStartTime int64                   `json:"previous_start_time"` // 上一个时间范围为 [previous_start_time, start_time)
+	Keywords          []RisingKeywordResponse `json:"keywords"`
+}
+
+// RisingKeywordResponse 一个关键词在两个时间范围内的出现次数
+type RisingKeywordResponse struct {
+	Keyword       string   `json:"keyword"`
+	Count         int      `json:"count"`
+	PreviousCount int      `json:"previous_count"`
+	Growth        *float64 `json:"growth"` // (count-previous_count)/previous_count，上一个时间范围没有出现时为 null
+}
+
+// toKeywordStatsResponse 转换关键词统计结果
+func toKeywordStatsResponse(filter services.StatsFilter, stats []models.KeywordStats) KeywordStatsResponse {
+	response := KeywordStatsResponse{
+		StartTime: filter.StartTime.Unix(),
+		EndTime:   filter.EndTime.Unix(),
+		Keywords:  make([]KeywordStatsItem, len(stats)),
+	}
+	for i, keyword := range stats {
+		response.Keywords[i] = KeywordStatsItem{
+			Keyword:      keyword.Keyword,
+			Total:        keyword.Total,
+			Counts:       keyword.Counts,
+			AverageScore: keyword.AverageScore,
+		}
+	}
+	return response
+}
+
+// trendQuery 解析并校验趋势统计的查询参数
+func trendQuery(c *gin.Context) (services.TrendQuery, error) {
+	query := services.TrendQuery{Interval: c.DefaultQuery("interval", services.IntervalHour)}
+
+	step, ok := services.IntervalDurations[query.Interval]
+	if !ok {
+		return query, fmt.Errorf("无效的时间粒度: %s（可选 minute, hour, day, week）", query.Interval)
+	}
+
+	// Local 只在本进程中有意义，数据库无法识别
+	timezone := c.DefaultQuery("timezone", "UTC")
+	location, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "Local" || timezone == "" {
+		return query, fmt.Errorf("无效的时区: %s", timezone)
+	}
+	query.Location = location
+
+	defaultRange := time.Duration(defaultTrendBuckets[query.Interval]) * step
+	if query.StatsFilter, err = statsFilter(c, defaultRange); err != nil {
+		return query, err
+	}
+	if query.EndTime.Sub(query.StartTime)/step >= maxTrendBuckets {
+		return query, fmt.Errorf("时间范围过大，按 %s 统计最多 %d 个时间段", query.Interval, maxTrendBuckets)
+	}
+
+	return query, nil
+}
+
+// statsFilter 解析统计接口共用的时间范围和过滤条件
+// end_time 默认为当前时间，start_time 默认为 end_time 之前 defaultRange
+func statsFilter(c *gin.Context, defaultRange time.Duration) (services.StatsFilter, error) {
+	filter := services.StatsFilter{
+		UserID:   c.Query("user_id"),
+		Language: c.Query("language"),
+	}
+
+	end, err := optionalTimestamp(c, "end_time")
+	if err != nil {
+		return filter, err
+	}
+	start, err := optionalTimestamp(c, "start_time")
+	if err != nil {
+		return filter, err
+	}
+	filter.EndTime = time.Now()
+	if end != nil {
+		filter.EndTime = *end
+	}
+	filter.StartTime = filter.EndTime.Add(-defaultRange)
+	if start != nil {
+		filter.StartTime = *start
+	}
+	if !filter.StartTime.Before(filter.EndTime) {
+		return filter, errors.New("start_time 必须早于 end_time")
+	}
+
+	if filter.Metadata, err = metadataFilter(c); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// breakdownQuery 解析并校验按元数据分组统计的查询参数
+func breakdownQuery(c *gin.Context) (services.BreakdownQuery, error) {
+	query := services.BreakdownQuery{Key: c.Query("key")}
+	if query.Key == "" || len(query.Key) > maxMetadataKeyLength {
+		return query, fmt.Errorf("key 长度必须在1到%d之间", maxMetadataKeyLength)
+	}
+
+	var err error
+	if query.StatsFilter, err = statsFilter(c, defaultStatsRange); err != nil {
+		return query, err
+	}
+	if query.Limit, err = limitParam(c, "limit", 20, maxStatsLimit); err != nil {
+		return query, err
+	}
+
+	baselineStart, err := optionalTimestamp(c, "baseline_start_time")
+	if err != nil {
+		return query, err
+	}
+	baselineEnd, err := optionalTimestamp(c, "baseline_end_time")
+	if err != nil {
+		return query, err
+	}
+
+	switch baseline := c.Query("baseline"); {
+	case baseline != "" && baseline != "previous":
+		return query, fmt.Errorf("无效的基准: %s（可选 previous）", baseline)
+	case baseline != "" && (baselineStart != nil || baselineEnd != nil):
+		return query, errors.New("baseline 不能与 baseline_start_time、baseline_end_time 同时使用")
+	case baseline != "":
+		length := query.EndTime.Sub(query.StartTime)
+		query.Baseline = &services.TimeRange{Start: query.StartTime.Add(-length), End: query.StartTime}
+	case baselineStart != nil && baselineEnd != nil:
+		if !baselineStart.Before(*baselineEnd) {
+			return query, errors.New("baseline_start_time 必须早于 baseline_end_time")
+		}
+		query.Baseline = &services.TimeRange{Start: *baselineStart, End: *baselineEnd}
+	case baselineStart != nil || baselineEnd != nil:
+		return query, errors.New("baseline_start_time 和 baseline_end_time 必须同时指定")
+	}
+
+	return query, nil
+}
+
+// limitParam 解析 1 到 max 之间的数量参数，缺省时返回 def
+func limitParam(c *gin.Context, name string, def, max int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("%s 必须是1到%d之间的整数", name, max)
+	}
+	return n, nil
+}
@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// contextKey 上下文键类型，避免与其他包的键冲突
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// WithLogger 将请求级日志记录器附加到上下文
+func WithLogger(ctx context.Context, logger *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext 从上下文获取日志记录器，不存在时返回全局日志记录器
+func FromContext(ctx context.Context) *logrus.Entry {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey).(*logrus.Entry); ok && logger != nil {
+			return logger
+		}
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// WithRequestID 将客户端提供的HTTP请求ID附加到上下文
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext 从上下文获取客户端提供的HTTP请求ID，没有时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// Detach 返回一个不随原请求取消的新上下文，但保留日志记录器和请求ID
+// 用于请求结束后仍需执行的回调（如异步结果存储）
+func Detach(ctx context.Context) context.Context {
+	detached := WithLogger(context.Background(), FromContext(ctx))
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		detached = WithRequestID(detached, requestID)
+	}
+	return detached
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit 限制请求体大小，每次请求时读取最新的上限（字节）
+// 超过上限时读取请求体会返回 *http.MaxBytesError，由处理器转换为413
+func BodyLimit(limit func() int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if max := limit(); max > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		}
+		c.Next()
+	}
+}
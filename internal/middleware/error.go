@@ -74,6 +74,10 @@ var (
 		return NewAppError(http.StatusNotFound, 404, message)
 	}
 
+	ErrConflict = func(message string) *AppError {
+		return NewAppError(http.StatusConflict, 409, message)
+	}
+
 	ErrInternalServer = func(message string) *AppError {
 		return NewAppError(http.StatusInternalServerError, 500, message)
 	}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFeature 功能开关关闭时拒绝请求，每个请求读取最新的开关状态，支持热更新
+func RequireFeature(name string, enabled func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"code":    503,
+				"message": "功能已关闭: " + name,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,14 +1,21 @@
 package middleware
 
 import (
+	"fmt"
 	"math/rand"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
 )
 
+// maxRequestIDLength 客户端提供的请求ID的最大长度，与 SentimentAnalysis.RequestID 列长度一致
+const maxRequestIDLength = 50
+
 // Logger 记录HTTP请求的日志中间件
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -16,15 +23,27 @@ func Logger() gin.HandlerFunc {
 		startTime := time.Now()
 
 		// 创建请求ID（如果不存在）
+		// 客户端提供的请求ID会作为分析记录和异步任务的请求ID，格式无效时拒绝请求
+		ctx := c.Request.Context()
 		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
+		invalidRequestID := requestID != "" && !validRequestID(requestID)
+		if requestID == "" || invalidRequestID {
 			requestID = generateRequestID()
-			c.Header("X-Request-ID", requestID)
+		} else {
+			ctx = logging.WithRequestID(ctx, requestID)
 		}
+		c.Header("X-Request-ID", requestID)
 
-		// 准备日志字段
-		logger := logrus.WithFields(logrus.Fields{
+		// 请求级日志记录器，服务层、存储层和MQ回调都通过上下文使用它
+		requestLogger := logrus.WithFields(logrus.Fields{
 			"request_id": requestID,
+			"user_id":    requestUserID(c),
+			"route":      c.FullPath(),
+		})
+		c.Request = c.Request.WithContext(logging.WithLogger(ctx, requestLogger))
+
+		// 准备日志字段
+		logger := requestLogger.WithFields(logrus.Fields{
 			"client_ip":  c.ClientIP(),
 			"method":     c.Request.Method,
 			"path":       c.Request.URL.Path,
@@ -37,7 +56,15 @@ func Logger() gin.HandlerFunc {
 		logger.Info("API请求开始")
 
 		// 继续处理请求
-		c.Next()
+		if invalidRequestID {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"code": 400,
+				"message": fmt.Sprintf("X-Request-ID 无效：最多 %d 个字符，只能包含字母、数字和 - _ . :",
+					maxRequestIDLength),
+			})
+		} else {
+			c.Next()
+		}
 
 		// 记录请求结束时间
 		endTime := time.Now()
@@ -63,6 +90,31 @@ func Logger() gin.HandlerFunc {
 	}
 }
 
+// requestUserID 从请求头或查询参数获取用户ID
+func requestUserID(c *gin.Context) string {
+	if userID := c.GetHeader("X-User-ID"); userID != "" {
+		return userID
+	}
+	return c.Query("user_id")
+}
+
+// validRequestID 判断客户端提供的请求ID是否可以使用
+// 请求ID会写入日志、响应头和数据库，只允许有限长度的字母、数字和 - _ . :
+func validRequestID(id string) bool {
+	if len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // generateRequestID 生成请求ID
 func generateRequestID() string {
 	// 使用UUID替代自定义随机字符串生成方法
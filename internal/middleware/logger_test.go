@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"sentiment-service/internal/logging"
+)
+
+func TestLoggerRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+		// 上下文中的请求ID，客户端未提供时为空
+		contextID string
+	}{
+		{"未提供", "", http.StatusOK, ""},
+		{"UUID", "6f1c2d3e-0000-4000-8000-000000000001", http.StatusOK, "6f1c2d3e-0000-4000-8000-000000000001"},
+		{"允许的符号", "order_42.retry:1", http.StatusOK, "order_42.retry:1"},
+		{"最大长度", strings.Repeat("a", maxRequestIDLength), http.StatusOK, strings.Repeat("a", maxRequestIDLength)},
+		{"超过最大长度", strings.Repeat("a", maxRequestIDLength+1), http.StatusBadRequest, ""},
+		{"包含空格", "order 42", http.StatusBadRequest, ""},
+		{"包含非ASCII字符", "请求42", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextID string
+			r := gin.New()
+			r.Use(Logger())
+			r.GET("/", func(c *gin.Context) {
+				contextID = logging.RequestIDFromContext(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("状态码 = %d，期望 %d", w.Code, tt.status)
+			}
+			if contextID != tt.contextID {
+				t.Errorf("上下文中的请求ID = %q，期望 %q", contextID, tt.contextID)
+			}
+
+			// 响应头总是带有请求ID，客户端提供的有效请求ID原样返回
+			responseID := w.Header().Get("X-Request-ID")
+			if responseID == "" || (tt.status == http.StatusOK && tt.header != "" && responseID != tt.header) {
+				t.Errorf("响应头中的请求ID = %q", responseID)
+			}
+		})
+	}
+}
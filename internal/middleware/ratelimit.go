@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sentiment-service/internal/app/config"
+)
+
+// RateLimit 全局令牌桶限流中间件，每个请求读取最新的限流配置，支持热更新
+func RateLimit(settings func() config.RateLimitConfig) gin.HandlerFunc {
+	bucket := &tokenBucket{}
+
+	return func(c *gin.Context) {
+		limit := settings()
+		if !limit.Enabled {
+			c.Next()
+			return
+		}
+
+		if !bucket.allow(limit.RequestsPerSecond, limit.Burst, time.Now()) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"code":    429,
+				"message": "请求过于频繁，请稍后重试",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// tokenBucket 令牌桶，速率和容量在每次调用时传入
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	capacity int
+}
+
+// allow 尝试取出一个令牌
+func (b *tokenBucket) allow(rate float64, burst int, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// 首次使用或容量变化时重新装满
+	if b.last.IsZero() || b.capacity != burst {
+		b.tokens = float64(burst)
+		b.capacity = burst
+	} else {
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
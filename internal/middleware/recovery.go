@@ -8,6 +8,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
 )
 
 // Recovery 从 panic 中恢复并记录堆栈跟踪
@@ -38,7 +40,7 @@ func Recovery() gin.HandlerFunc {
 				finalStack := strings.Join(cleanedStack, "\n")
 
 				// 记录错误和堆栈跟踪
-				logrus.WithFields(logrus.Fields{
+				logging.FromContext(c.Request.Context()).WithFields(logrus.Fields{
 					"error":       err,
 					"method":      c.Request.Method,
 					"path":        c.Request.URL.Path,
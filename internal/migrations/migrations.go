@@ -0,0 +1,337 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed sql/*.sql
+var migrationFiles embed.FS
+
+// advisoryLockKey 迁移使用的PostgreSQL咨询锁，保证多个副本同时启动时只有一个执行迁移
+const advisoryLockKey int64 = 7242019011
+
+// noTransactionMarker 迁移文件首行包含该标记时不在事务中执行（例如 CREATE INDEX CONCURRENTLY）
+const noTransactionMarker = "-- migrate:no-transaction"
+
+// fileNamePattern 迁移文件名格式：<版本>_<名称>.<up|down>.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration 单个版本的迁移脚本
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Migrator 执行嵌入的版本化SQL迁移
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New 创建迁移器并加载嵌入的迁移脚本
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := load(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// load 解析迁移文件，按版本排序
+func load(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移文件失败: %v", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("迁移文件名不合法: %s", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(files, path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移文件 %s 失败: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("迁移版本 %d 存在多个名称: %s, %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("迁移版本 %d 缺少 up 或 down 脚本", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest 返回最新的迁移版本
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status 返回所有迁移的执行状态
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Pending 返回尚未执行的迁移数量
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// Up 执行所有未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down 回滚最近执行的 steps 个迁移
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("回滚步数必须大于0")
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// To 迁移到指定版本：执行不超过该版本的未执行迁移，回滚高于该版本的已执行迁移
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && !m.hasVersion(version) {
+		return fmt.Errorf("未知的迁移版本: %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		// 先回滚高于目标版本的迁移（从新到旧）
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				if err := m.apply(ctx, conn, migration, false); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 再执行不超过目标版本的未执行迁移（从旧到新）
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.apply(ctx, conn, migration, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// hasVersion 检查版本是否存在
+func (m *Migrator) hasVersion(version int64) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// withLock 在持有咨询锁的专用连接上执行操作
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		return fmt.Errorf("获取迁移锁失败: %v", err)
+	}
+	defer func() {
+		// 使用独立上下文，保证ctx取消后也能释放锁
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey); err != nil {
+			logrus.WithError(err).Warn("释放迁移锁失败")
+		}
+	}()
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// ensureVersionTable 创建迁移版本表
+func ensureVersionTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    bigint PRIMARY KEY,
+			name       text        NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return fmt.Errorf("创建 schema_migrations 表失败: %v", err)
+	}
+	return nil
+}
+
+// appliedVersions 返回已执行的迁移版本及执行时间
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("查询已执行的迁移失败: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// apply 执行单个迁移的 up 或 down 脚本并更新版本表
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	script := migration.Down
+	record := "DELETE FROM schema_migrations WHERE version = $1"
+	args := []interface{}{migration.Version}
+	direction := "down"
+	if up {
+		script = migration.Up
+		record = "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)"
+		args = append(args, migration.Name)
+		direction = "up"
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"version":   migration.Version,
+		"name":      migration.Name,
+		"direction": direction,
+	})
+	logger.Info("正在执行数据库迁移")
+	start := time.Now()
+
+	if strings.HasPrefix(strings.TrimSpace(script), noTransactionMarker) {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return fmt.Errorf("迁移 %d_%s (%s) 失败: %v", migration.Version, migration.Name, direction, err)
+		}
+		if _, err := conn.ExecContext(ctx, record, args...); err != nil {
+			return fmt.Errorf("记录迁移版本失败: %v", err)
+		}
+	} else {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("开始迁移事务失败: %v", err)
+		}
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("迁移 %d_%s (%s) 失败: %v", migration.Version, migration.Name, direction, err)
+		}
+		if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("记录迁移版本失败: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("提交迁移事务失败: %v", err)
+		}
+	}
+
+	logger.WithField("elapsed_ms", time.Since(start).Milliseconds()).Info("数据库迁移完成")
+	return nil
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS batch_items;
+DROP TABLE IF EXISTS batch_analyses;
+DROP TABLE IF EXISTS analysis_metadata;
+DROP TABLE IF EXISTS sentiment_analyses;
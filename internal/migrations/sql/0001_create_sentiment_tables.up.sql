@@ -0,0 +1,59 @@
+-- 初始表结构，与之前 GORM AutoMigrate 生成的结构一致
+-- 使用 IF NOT EXISTS，已由 AutoMigrate 建表的数据库可以直接执行
+
+CREATE TABLE IF NOT EXISTS sentiment_analyses (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    text       text          NOT NULL,
+    sentiment  varchar(20)   NOT NULL,
+    score      decimal(5, 4) NOT NULL,
+    user_id    varchar(50),
+    language   varchar(10),
+    keywords   text,
+    request_id varchar(50),
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_sentiment_analyses_user_id ON sentiment_analyses (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sentiment_analyses_request_id ON sentiment_analyses (request_id);
+CREATE INDEX IF NOT EXISTS idx_sentiment_analyses_deleted_at ON sentiment_analyses (deleted_at);
+
+CREATE TABLE IF NOT EXISTS analysis_metadata (
+    id          bigserial PRIMARY KEY,
+    analysis_id uuid        NOT NULL,
+    key         varchar(50) NOT NULL,
+    value       text        NOT NULL,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_analysis_metadata_analysis_id ON analysis_metadata (analysis_id);
+CREATE INDEX IF NOT EXISTS idx_analysis_metadata_deleted_at ON analysis_metadata (deleted_at);
+
+CREATE TABLE IF NOT EXISTS batch_analyses (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    varchar(50),
+    count      integer     NOT NULL,
+    status     varchar(20) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_batch_analyses_user_id ON batch_analyses (user_id);
+CREATE INDEX IF NOT EXISTS idx_batch_analyses_deleted_at ON batch_analyses (deleted_at);
+
+CREATE TABLE IF NOT EXISTS batch_items (
+    id          bigserial PRIMARY KEY,
+    batch_id    uuid    NOT NULL,
+    analysis_id uuid    NOT NULL,
+    "order"     integer NOT NULL,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_batch_items_batch_id ON batch_items (batch_id);
+CREATE INDEX IF NOT EXISTS idx_batch_items_deleted_at ON batch_items (deleted_at);
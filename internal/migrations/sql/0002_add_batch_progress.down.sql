@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_batch_items_batch_id_order;
+DROP INDEX IF EXISTS idx_batch_analyses_status;
+
+ALTER TABLE batch_analyses
+    DROP COLUMN IF EXISTS estimated_at,
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS started_at,
+    DROP COLUMN IF EXISTS error,
+    DROP COLUMN IF EXISTS failed_count,
+    DROP COLUMN IF EXISTS processed_count;
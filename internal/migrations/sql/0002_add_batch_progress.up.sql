@@ -0,0 +1,15 @@
+-- 异步批处理的进度跟踪
+
+ALTER TABLE batch_analyses
+    ADD COLUMN processed_count integer NOT NULL DEFAULT 0,
+    ADD COLUMN failed_count    integer NOT NULL DEFAULT 0,
+    ADD COLUMN error           text,
+    ADD COLUMN started_at      timestamptz,
+    ADD COLUMN completed_at    timestamptz,
+    ADD COLUMN estimated_at    timestamptz;
+
+-- 按用户和状态查询批处理列表
+CREATE INDEX idx_batch_analyses_status ON batch_analyses (status);
+
+-- 按顺序分页读取批处理结果
+CREATE INDEX idx_batch_items_batch_id_order ON batch_items (batch_id, "order");
@@ -0,0 +1,4 @@
+ALTER TABLE batch_analyses
+    DROP COLUMN IF EXISTS source_columns,
+    DROP COLUMN IF EXISTS text_column,
+    DROP COLUMN IF EXISTS source_format;
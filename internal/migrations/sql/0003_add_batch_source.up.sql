@@ -0,0 +1,6 @@
+-- 记录上传文件批处理的来源，用于按原始列导出结果
+
+ALTER TABLE batch_analyses
+    ADD COLUMN source_format  varchar(10),
+    ADD COLUMN text_column    varchar(50),
+    ADD COLUMN source_columns text;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_batch_items_batch_id_item_id;
+
+ALTER TABLE batch_items
+    DROP COLUMN IF EXISTS item_id;
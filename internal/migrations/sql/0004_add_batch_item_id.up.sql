@@ -0,0 +1,6 @@
+-- 批处理项目的标识，调用方可用它对应输入和结果（同一批处理中的重复文本各自保留）
+
+ALTER TABLE batch_items
+    ADD COLUMN item_id varchar(100);
+
+CREATE INDEX idx_batch_items_batch_id_item_id ON batch_items (batch_id, item_id);
@@ -0,0 +1,2 @@
+ALTER TABLE batch_analyses
+    DROP COLUMN IF EXISTS skipped_count;
@@ -0,0 +1,4 @@
+-- 记录因文本为空而跳过的批处理项目，与失败的项目区分
+
+ALTER TABLE batch_analyses
+    ADD COLUMN skipped_count integer NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_batch_items_batch_id_order_live;
+DROP INDEX IF EXISTS idx_sentiment_analyses_score_id;
+DROP INDEX IF EXISTS idx_sentiment_analyses_user_id_created_at_id;
+DROP INDEX IF EXISTS idx_sentiment_analyses_created_at_id;
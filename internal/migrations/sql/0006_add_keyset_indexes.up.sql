@@ -0,0 +1,15 @@
+-- 键集分页的复合索引：历史记录按 (created_at, id) 或 (score, id) 排序，批处理结果按 (batch_id, order) 排序
+-- 只索引未删除的记录，与查询中的 deleted_at IS NULL 条件一致
+-- batch_items 已有 0002 创建的完整索引 idx_batch_items_batch_id_order，部分索引使用单独的名称
+
+CREATE INDEX IF NOT EXISTS idx_sentiment_analyses_created_at_id
+    ON sentiment_analyses (created_at, id) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sentiment_analyses_user_id_created_at_id
+    ON sentiment_analyses (user_id, created_at, id) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sentiment_analyses_score_id
+    ON sentiment_analyses (score, id) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_batch_items_batch_id_order_live
+    ON batch_items (batch_id, "order") WHERE deleted_at IS NULL;
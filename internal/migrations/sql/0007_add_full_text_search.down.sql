@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_sentiment_analyses_search_vector;
+
+DROP TRIGGER IF EXISTS trg_sentiment_analyses_search_vector ON sentiment_analyses;
+DROP FUNCTION IF EXISTS sentiment_analyses_search_vector_update();
+
+ALTER TABLE sentiment_analyses
+    DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS sentiment_search_document(text, text);
+DROP FUNCTION IF EXISTS sentiment_cjk_spaced(text);
@@ -0,0 +1,38 @@
+-- 分析文本的全文检索
+-- 英文使用 english 配置（词干化、停用词）；中文没有空格分词，把每个汉字当作一个词并保留位置，
+-- 查询时连续的汉字改写为短语（"情 感"），不依赖 zhparser 等扩展；其他语言同时按两种方式索引
+
+-- 在每个汉字两侧加空格，使 simple 解析器逐字切分
+CREATE OR REPLACE FUNCTION sentiment_cjk_spaced(body text) RETURNS text AS $$
+    SELECT regexp_replace(body, '([㐀-䶿一-鿿豈-﫿])', ' \1 ', 'g')
+$$ LANGUAGE sql IMMUTABLE;
+
+-- 按语言生成检索向量
+CREATE OR REPLACE FUNCTION sentiment_search_document(lang text, body text) RETURNS tsvector AS $$
+    SELECT CASE
+        WHEN lang LIKE 'en%' THEN to_tsvector('english', body)
+        WHEN lang LIKE 'zh%' THEN to_tsvector('simple', sentiment_cjk_spaced(body))
+        ELSE to_tsvector('english', body) || to_tsvector('simple', sentiment_cjk_spaced(body))
+    END
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE sentiment_analyses
+    ADD COLUMN search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION sentiment_analyses_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := sentiment_search_document(NEW.language, NEW.text);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_sentiment_analyses_search_vector
+    BEFORE INSERT OR UPDATE OF text, language ON sentiment_analyses
+    FOR EACH ROW EXECUTE FUNCTION sentiment_analyses_search_vector_update();
+
+-- 回填已有记录
+UPDATE sentiment_analyses
+SET search_vector = sentiment_search_document(language, text);
+
+CREATE INDEX IF NOT EXISTS idx_sentiment_analyses_search_vector
+    ON sentiment_analyses USING gin (search_vector);
@@ -0,0 +1,28 @@
+-- 关键词单独存储，每条分析记录的每个关键词一行（小写、去除首尾空白、去重），用于按关键词统计
+-- sentiment_analyses.keywords 保留原始的逗号分隔列表
+
+CREATE TABLE IF NOT EXISTS analysis_keywords (
+    id          bigserial PRIMARY KEY,
+    analysis_id uuid         NOT NULL,
+    keyword     varchar(100) NOT NULL,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_analysis_keywords_analysis_id ON analysis_keywords (analysis_id);
+CREATE INDEX IF NOT EXISTS idx_analysis_keywords_deleted_at ON analysis_keywords (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_analysis_keywords_keyword_analysis_id
+    ON analysis_keywords (keyword, analysis_id) WHERE deleted_at IS NULL;
+
+-- 回填已有记录，规范化方式与 repositories.NormalizeKeyword 一致
+INSERT INTO analysis_keywords (analysis_id, keyword, created_at, updated_at, deleted_at)
+SELECT a.id, k.keyword, a.created_at, a.updated_at, a.deleted_at
+FROM sentiment_analyses a
+CROSS JOIN LATERAL (
+    SELECT DISTINCT left(lower(btrim(value, E' \t\r\n')), 100) AS keyword
+    FROM unnest(string_to_array(a.keywords, ',')) AS value
+) k
+WHERE a.keywords <> ''
+  AND k.keyword <> ''
+  AND NOT EXISTS (SELECT 1 FROM analysis_keywords ak WHERE ak.analysis_id = a.id);
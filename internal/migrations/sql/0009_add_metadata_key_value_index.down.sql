@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_analysis_metadata_key_value_analysis_id;
@@ -0,0 +1,3 @@
+-- 按元数据键值过滤和按元数据键分组统计时使用
+CREATE INDEX IF NOT EXISTS idx_analysis_metadata_key_value_analysis_id
+    ON analysis_metadata (key, value, analysis_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,56 @@
+-- 告警规则和告警记录
+-- 规则按时间窗口统计符合条件的分析记录，超过阈值或偏离历史基准时产生告警
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name             varchar(100)     NOT NULL,
+    type             varchar(20)      NOT NULL,
+    metric           varchar(20)      NOT NULL,
+    direction        varchar(10)      NOT NULL,
+    threshold        double precision NOT NULL,
+    window_seconds   integer          NOT NULL,
+    baseline_windows integer          NOT NULL DEFAULT 0,
+    min_count        integer          NOT NULL DEFAULT 0,
+    cooldown_seconds integer          NOT NULL DEFAULT 0,
+    user_id          varchar(50),
+    language         varchar(10),
+    metadata         text,
+    webhook_url      text             NOT NULL,
+    enabled          boolean          NOT NULL DEFAULT true,
+    created_at       timestamptz,
+    updated_at       timestamptz,
+    deleted_at       timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_deleted_at ON alert_rules (deleted_at);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id         uuid             NOT NULL,
+    status          varchar(20)      NOT NULL,
+    value           double precision NOT NULL,
+    threshold       double precision NOT NULL,
+    baseline_mean   double precision,
+    baseline_stddev double precision,
+    z_score         double precision,
+    sample_count    integer          NOT NULL,
+    window_start    timestamptz      NOT NULL,
+    window_end      timestamptz      NOT NULL,
+    message         text             NOT NULL,
+    acknowledged_at timestamptz,
+    acknowledged_by varchar(50),
+    resolved_at     timestamptz,
+    resolved_by     varchar(50),
+    note            text,
+    notified_at     timestamptz,
+    notify_error    text,
+    created_at      timestamptz,
+    updated_at      timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_rule_id_created_at ON alerts (rule_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_alerts_status_created_at ON alerts (status, created_at);
+
+-- 每条规则最多一个未解决的告警，多个实例同时评估时只有一个能创建告警
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_rule_id_active
+    ON alerts (rule_id) WHERE status IN ('open', 'acknowledged');
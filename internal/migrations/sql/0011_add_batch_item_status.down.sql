@@ -0,0 +1,9 @@
+DELETE FROM batch_items WHERE analysis_id IS NULL;
+
+ALTER TABLE batch_items
+    DROP COLUMN IF EXISTS source_row,
+    DROP COLUMN IF EXISTS error,
+    DROP COLUMN IF EXISTS status;
+
+ALTER TABLE batch_items
+    ALTER COLUMN analysis_id SET NOT NULL;
@@ -0,0 +1,10 @@
+-- 记录每个批处理项目的处理状态和原始输入，失败和跳过的项目也保留，导出结果时可以逐行对应上传文件
+-- 失败和跳过的项目没有分析记录
+
+ALTER TABLE batch_items
+    ALTER COLUMN analysis_id DROP NOT NULL;
+
+ALTER TABLE batch_items
+    ADD COLUMN status varchar(20) NOT NULL DEFAULT 'succeeded',
+    ADD COLUMN error text,
+    ADD COLUMN source_row text;
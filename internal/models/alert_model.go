@@ -0,0 +1,154 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 告警规则类型
+const (
+	AlertRuleTypeThreshold = "threshold" // 当前窗口的指标超过阈值
+	AlertRuleTypeZScore    = "zscore"    // 当前窗口的指标偏离之前若干窗口的均值超过 threshold 个标准差
+)
+
+// 告警规则的指标
+const (
+	AlertMetricNegativeShare = "negative_share" // 负面记录的占比（0-1）
+	AlertMetricAverageScore  = "average_score"  // 平均分数
+	AlertMetricVolume        = "volume"         // 记录数
+)
+
+// 告警规则的方向
+const (
+	AlertDirectionAbove = "above"
+	AlertDirectionBelow = "below"
+)
+
+// 告警状态，open 和 acknowledged 为未解决
+const (
+	AlertStatusOpen         = "open"
+	AlertStatusAcknowledged = "acknowledged"
+	AlertStatusResolved     = "resolved"
+)
+
+// AlertRule 表示告警规则
+type AlertRule struct {
+	ID              string            `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name            string            `gorm:"type:varchar(100);not null" json:"name"`
+	Type            string            `gorm:"type:varchar(20);not null" json:"type"`               // threshold, zscore
+	Metric          string            `gorm:"type:varchar(20);not null" json:"metric"`             // negative_share, average_score, volume
+	Direction       string            `gorm:"type:varchar(10);not null" json:"direction"`          // above, below
+	Threshold       float64           `gorm:"not null" json:"threshold"`                           // 指标阈值，zscore 规则为标准差倍数
+	WindowSeconds   int               `gorm:"type:int;not null" json:"window_seconds"`             // 统计窗口长度
+	BaselineWindows int               `gorm:"type:int;not null;default:0" json:"baseline_windows"` // zscore 规则用作基准的之前窗口数
+	MinCount        int               `gorm:"type:int;not null;default:0" json:"min_count"`        // 窗口内记录少于该值时不评估
+	CooldownSeconds int               `gorm:"type:int;not null;default:0" json:"cooldown_seconds"` // 告警解决后再次告警的最短间隔
+	UserID          string            `gorm:"type:varchar(50)" json:"user_id"`                     // 只统计该用户的记录
+	Language        string            `gorm:"type:varchar(10)" json:"language"`                    // 只统计该语言的记录
+	Metadata        map[string]string `gorm:"type:text;serializer:json" json:"metadata"`           // 只统计元数据全部匹配的记录
+	WebhookURL      string            `gorm:"type:text;not null" json:"webhook_url"`               // 告警产生和解决时通知的地址
+	Enabled         bool              `gorm:"not null" json:"enabled"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// Alert 表示规则产生的一次告警
+type Alert struct {
+	ID             string     `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	RuleID         string     `gorm:"type:uuid;not null" json:"rule_id"`       // 引用 AlertRule.ID
+	Status         string     `gorm:"type:varchar(20);not null" json:"status"` // open, acknowledged, resolved
+	Value          float64    `gorm:"not null" json:"value"`                   // 触发告警时的指标值
+	Threshold      float64    `gorm:"not null" json:"threshold"`               // 触发告警时规则的阈值
+	BaselineMean   *float64   `json:"baseline_mean"`                           // zscore 规则的基准均值
+	BaselineStddev *float64   `json:"baseline_stddev"`                         // zscore 规则的基准标准差
+	ZScore         *float64   `json:"z_score"`                                 // zscore 规则的标准分数
+	SampleCount    int        `gorm:"type:int;not null" json:"sample_count"`   // 窗口内的记录数
+	WindowStart    time.Time  `gorm:"not null" json:"window_start"`            // 触发告警的统计窗口开始时间
+	WindowEnd      time.Time  `gorm:"not null" json:"window_end"`              // 统计窗口结束时间（不含）
+	Message        string     `gorm:"type:text;not null" json:"message"`       // 告警说明
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy string     `gorm:"type:varchar(50)" json:"acknowledged_by"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	ResolvedBy     string     `gorm:"type:varchar(50)" json:"resolved_by"` // 自动解决时为 system
+	Note           string     `gorm:"type:text" json:"note"`               // 确认或解决时的备注
+	NotifiedAt     *time.Time `json:"notified_at"`                         // 最近一次成功发送 webhook 的时间
+	NotifyError    string     `gorm:"type:text" json:"notify_error"`       // 最近一次发送 webhook 失败的原因
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 覆盖 AlertRule 的表名
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+// TableName 覆盖 Alert 的表名
+func (Alert) TableName() string {
+	return "alerts"
+}
+
+// Window 返回规则的统计窗口长度
+func (r *AlertRule) Window() time.Duration {
+	return time.Duration(r.WindowSeconds) * time.Second
+}
+
+// Cooldown 返回告警解决后再次告警的最短间隔
+func (r *AlertRule) Cooldown() time.Duration {
+	return time.Duration(r.CooldownSeconds) * time.Second
+}
+
+// Active 判断告警是否未解决
+func (a *Alert) Active() bool {
+	return a.Status == AlertStatusOpen || a.Status == AlertStatusAcknowledged
+}
+
+// webhook 通知的事件类型
+const (
+	AlertEventFired        = "alert.fired"
+	AlertEventAcknowledged = "alert.acknowledged"
+	AlertEventResolved     = "alert.resolved"
+)
+
+// AlertNotification webhook 通知的内容
+type AlertNotification struct {
+	Event  string     `json:"event"`
+	SentAt time.Time  `json:"sent_at"`
+	Rule   *AlertRule `json:"rule"`
+	Alert  *Alert     `json:"alert"`
+}
+
+// AlertEvaluation 规则在一个统计窗口上的评估结果
+type AlertEvaluation struct {
+	RuleID      string
+	WindowStart time.Time
+	WindowEnd   time.Time
+	SampleCount int
+	// Evaluable 为 false 时数据不足（记录数少于 min_count 或基准窗口不足），不触发也不自动解决告警
+	Evaluable bool
+	Reason    string // 无法评估的原因
+	Breached  bool
+	Value     *float64 // 当前窗口的指标值，窗口内没有记录时为空（volume 除外）
+	Threshold float64
+
+	// zscore 规则的基准
+	BaselineWindows int // 参与计算的基准窗口数
+	BaselineMean    *float64
+	BaselineStddev  *float64
+	ZScore          *float64
+
+	Message string
+}
+
+// AlertRuleListResult 包含告警规则列表
+type AlertRuleListResult struct {
+	Rules      []*AlertRule
+	TotalCount int
+}
+
+// AlertListResult 包含告警列表
+type AlertListResult struct {
+	Alerts     []*Alert
+	TotalCount int
+}
@@ -33,26 +33,68 @@ type AnalysisMetadata struct {
 	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// AnalysisKeyword 表示分析结果中的一个关键词，每条分析记录的每个关键词一行
+type AnalysisKeyword struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	AnalysisID string         `gorm:"type:uuid;not null;index" json:"analysis_id"` // 引用 SentimentAnalysis.ID
+	Keyword    string         `gorm:"type:varchar(100);not null" json:"keyword"`   // 规范化后的关键词（小写）
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// 批处理状态
+const (
+	BatchStatusPending         = "pending"
+	BatchStatusProcessing      = "processing"
+	BatchStatusCompleted       = "completed"
+	BatchStatusFailed          = "failed"
+	BatchStatusPartiallyFailed = "partially_failed"
+)
+
+// 批处理项目状态
+const (
+	BatchItemStatusSucceeded = "succeeded"
+	BatchItemStatusFailed    = "failed"
+	BatchItemStatusSkipped   = "skipped"
+	// BatchItemStatusAnalyzed 流式响应中已分析但尚未存储的项目，存储完成后计为成功
+	BatchItemStatusAnalyzed = "analyzed"
+)
+
 // BatchAnalysis 表示批处理请求
 type BatchAnalysis struct {
-	ID        string         `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID    string         `gorm:"type:varchar(50);index" json:"user_id"`
-	Count     int            `gorm:"type:int;not null" json:"count"`          // 批处理中的分析数量
-	Status    string         `gorm:"type:varchar(20);not null" json:"status"` // pending, completed, failed
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             string         `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID         string         `gorm:"type:varchar(50);index" json:"user_id"`
+	Count          int            `gorm:"type:int;not null" json:"count"`                     // 批处理中的分析数量
+	Status         string         `gorm:"type:varchar(20);not null" json:"status"`            // pending, processing, completed, failed, partially_failed
+	ProcessedCount int            `gorm:"type:int;not null;default:0" json:"processed_count"` // 已处理的数量（含失败）
+	FailedCount    int            `gorm:"type:int;not null;default:0" json:"failed_count"`    // 处理失败的数量
+	SkippedCount   int            `gorm:"type:int;not null;default:0" json:"skipped_count"`   // 文本为空而跳过的数量
+	Error          string         `gorm:"type:text" json:"error"`                             // 最近一次错误
+	StartedAt      *time.Time     `json:"started_at"`
+	CompletedAt    *time.Time     `json:"completed_at"`
+	EstimatedAt    *time.Time     `json:"estimated_at"`                                    // 预计完成时间
+	SourceFormat   string         `gorm:"type:varchar(10)" json:"source_format"`           // 上传文件格式（csv, jsonl），非上传批处理为空
+	TextColumn     string         `gorm:"type:varchar(50)" json:"text_column"`             // 上传文件中的文本列
+	SourceColumns  []string       `gorm:"type:text;serializer:json" json:"source_columns"` // 上传文件中的列，按原始顺序
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // BatchItem 表示批处理请求中的单个项目
 type BatchItem struct {
-	ID         uint           `gorm:"primaryKey" json:"id"`
-	BatchID    string         `gorm:"type:uuid;not null;index" json:"batch_id"` // 引用 BatchAnalysis.ID
-	AnalysisID string         `gorm:"type:uuid;not null" json:"analysis_id"`    // 引用 SentimentAnalysis.ID
-	Order      int            `gorm:"type:int;not null" json:"order"`           // 批处理中的顺序
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID         uint              `gorm:"primaryKey" json:"id"`
+	BatchID    string            `gorm:"type:uuid;not null;index" json:"batch_id"`    // 引用 BatchAnalysis.ID
+	AnalysisID *string           `gorm:"type:uuid" json:"analysis_id"`                // 引用 SentimentAnalysis.ID，失败和跳过的项目为空
+	Order      int               `gorm:"type:int;not null" json:"order"`              // 批处理中的顺序
+	ItemID     string            `gorm:"type:varchar(100)" json:"item_id"`            // 调用方提供或自动生成的项目ID
+	Status     string            `gorm:"type:varchar(20);not null" json:"status"`     // succeeded, failed, skipped
+	Error      string            `gorm:"type:text" json:"error"`                      // 失败或跳过的原因
+	SourceRow  map[string]string `gorm:"type:text;serializer:json" json:"source_row"` // 原始输入（上传文件中的各列，其他批处理为文本）
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt    `gorm:"index" json:"-"`
 }
 
 // TableName 覆盖 SentimentAnalysis 的表名
@@ -65,6 +107,11 @@ func (AnalysisMetadata) TableName() string {
 	return "analysis_metadata"
 }
 
+// TableName 覆盖 AnalysisKeyword 的表名
+func (AnalysisKeyword) TableName() string {
+	return "analysis_keywords"
+}
+
 // TableName 覆盖 BatchAnalysis 的表名
 func (BatchAnalysis) TableName() string {
 	return "batch_analyses"
@@ -79,33 +126,176 @@ func (BatchItem) TableName() string {
 
 // SentimentResult 包含情感分析的结果
 type SentimentResult struct {
+	ItemID           string // 批处理中的项目ID，单条分析时为空
 	Text             string
 	Sentiment        string
 	Score            float64
 	ConfidenceScores map[string]float64
 	Keywords         []string
 	RequestID        string
+	Metadata         map[string]string // 批处理项目自带的元数据
 	Timestamp        time.Time
 }
 
-// BatchSentimentResult 包含多个情感分析的结果
+// BatchItemResult 批处理中单个项目的处理结果
+type BatchItemResult struct {
+	ItemID   string
+	Text     string
+	Metadata map[string]string
+	Status   string           // succeeded, failed, skipped
+	Error    string           // 失败原因
+	Result   *SentimentResult // 仅在分析成功时有值
+}
+
+// BatchSummary 批处理项目的统计
+type BatchSummary struct {
+	Total     int
+	Succeeded int
+	Failed    int
+	Skipped   int
+}
+
+// BatchSentimentResult 包含多个情感分析的结果，结果按输入顺序排列
 type BatchSentimentResult struct {
-	Results []SentimentResult
+	Results []BatchItemResult
 	BatchID string
+	Status  string
+	Summary BatchSummary
 }
 
 // AnalysisHistoryResult 包含历史情感分析
 type AnalysisHistoryResult struct {
 	Records    []AnalysisRecord
-	TotalCount int
+	TotalCount int    // -1 表示未统计
+	NextCursor string // 下一页的游标，没有更多记录时为空
+	PrevCursor string // 上一页的游标，当前是第一页时为空
+}
+
+// SearchResult 包含全文检索命中的分析记录，按请求的顺序排列
+type SearchResult struct {
+	Hits       []SearchHitRecord
+	TotalCount int // -1 表示未统计
+}
+
+// SearchHitRecord 表示全文检索命中的单条记录
+type SearchHitRecord struct {
+	AnalysisRecord
+	Rank    float64 // 相关度
+	Snippet string  // 带高亮标记的摘要
+}
+
+// TrendResult 按时间段统计的情感趋势，时间段按开始时间升序排列
+type TrendResult struct {
+	Buckets []TrendBucket
+}
+
+// TrendBucket 一个时间段内的统计结果
+type TrendBucket struct {
+	Start        time.Time      // 时间段开始时间（统计使用的时区）
+	Total        int            // 记录总数
+	Counts       map[string]int // 各情感标签的记录数
+	AverageScore *float64       // 平均分数，没有记录时为空
+}
+
+// KeywordStats 一个关键词的情感分布，每条包含该关键词的分析记录计一次
+type KeywordStats struct {
+	Keyword      string
+	Total        int
+	Counts       map[string]int // 各情感标签的记录数
+	AverageScore *float64       // 平均分数，没有记录时为空
+}
+
+// RisingKeyword 一个关键词在当前时间窗口与上一个等长窗口中的出现次数
+type RisingKeyword struct {
+	Keyword       string
+	Count         int
+	PreviousCount int
+	Growth        *float64 // 相对上一个窗口的增长比例，上一个窗口没有出现时为空
+}
+
+// BreakdownResult 按元数据键分组的统计结果，分组按记录数倒序排列
+type BreakdownResult struct {
+	Key       string
+	Groups    []BreakdownGroup
+	Truncated bool // 分组数超过上限，只返回了记录最多的分组
+}
+
+// BreakdownGroup 一个元数据值的统计结果，指定基准时间范围时包含基准统计和变化
+type BreakdownGroup struct {
+	Value    string
+	Stats    GroupStats
+	Baseline *GroupStats
+	Change   *GroupChange
+}
+
+// GroupStats 一组分析记录的统计
+type GroupStats struct {
+	Total        int
+	Counts       map[string]int     // 各情感标签的记录数
+	Shares       map[string]float64 // 各情感标签的占比，没有记录时为空
+	AverageScore *float64
+	Percentiles  map[string]float64 // 分数的分位数（p10, p25, p50, p75, p90），没有记录时为空
+}
+
+// GroupChange 当前时间范围相对基准时间范围的变化
+type GroupChange struct {
+	TotalChange       *float64           // 记录数的变化比例，基准没有记录时为空
+	AverageScoreDelta *float64           // 平均分数之差，任一方没有记录时为空
+	ShareDeltas       map[string]float64 // 各情感标签占比之差，任一方没有记录时为空
 }
 
 // AnalysisRecord 表示存储的情感分析记录
 type AnalysisRecord struct {
 	ID        string
+	RequestID string
+	UserID    string
 	Text      string
 	Sentiment string
 	Score     float64
+	Language  string
+	Keywords  []string
 	Timestamp time.Time
+	UpdatedAt time.Time
 	Metadata  map[string]string
 }
+
+// BatchRecord 表示批处理分析的状态
+type BatchRecord struct {
+	ID             string
+	UserID         string
+	Status         string
+	Count          int
+	ItemCount      int
+	ProcessedCount int
+	FailedCount    int
+	SkippedCount   int
+	Error          string
+	StartedAt      *time.Time
+	CompletedAt    *time.Time
+	EstimatedAt    *time.Time
+	SourceFormat   string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// BatchListResult 包含批处理分析列表
+type BatchListResult struct {
+	Batches    []BatchRecord
+	TotalCount int
+}
+
+// BatchResultRecord 表示批处理中的单个结果
+type BatchResultRecord struct {
+	Order  int
+	ItemID string
+	AnalysisRecord
+}
+
+// BatchResultsPage 包含批处理结果的一页
+type BatchResultsPage struct {
+	BatchID    string
+	Records    []BatchResultRecord
+	TotalCount int    // -1 表示未统计
+	NextCursor string // 下一页的游标，没有更多记录时为空
+	PrevCursor string // 上一页的游标，当前是第一页时为空
+}
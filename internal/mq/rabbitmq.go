@@ -3,16 +3,22 @@ package mq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
 
+	"sentiment-service/internal/logging"
 	"sentiment-service/internal/models"
 )
 
+// ErrDuplicateTask 相同请求ID的任务仍在等待结果
+var ErrDuplicateTask = errors.New("相同请求ID的任务仍在等待结果")
+
 // SentimentMQ 管理RabbitMQ情感分析队列
 type SentimentMQ struct {
 	conn    *amqp.Connection
@@ -24,13 +30,36 @@ type SentimentMQ struct {
 	// 结果队列名称
 	resultQueue string
 
+	// 结果消费者标识，关闭时用于取消消费
+	consumerTag  string
+	consumerDone chan struct{}
+
 	// 结果回调
-	resultCallbacks map[string]ResultCallback
+	resultCallbacks  map[string]pendingCallback
+	runningCallbacks int
+	callbacksMu      sync.Mutex
 }
 
+// drainPollInterval 等待回调完成时的检查间隔
+const drainPollInterval = 100 * time.Millisecond
+
+// 结果队列由所有实例共享，没有本地回调的结果可能属于其他实例
+const (
+	// requeueDelay 退回没有本地回调的结果前的等待时间，避免消息在实例间快速循环
+	requeueDelay = time.Second
+	// orphanResultTTL 结果处理完成超过该时间仍没有实例认领时丢弃
+	orphanResultTTL = 10 * time.Minute
+)
+
 // ResultCallback 结果回调函数类型
 type ResultCallback func(*models.SentimentResult)
 
+// pendingCallback 等待结果的回调及发布任务时的请求级日志记录器
+type pendingCallback struct {
+	callback ResultCallback
+	logger   *logrus.Entry
+}
+
 // NewSentimentMQ 创建新的RabbitMQ客户端
 func NewSentimentMQ(amqpURL, taskQueue, resultQueue string) (*SentimentMQ, error) {
 	// 记录连接信息
@@ -96,29 +125,53 @@ func NewSentimentMQ(amqpURL, taskQueue, resultQueue string) (*SentimentMQ, error
 
 	logrus.Info("成功连接到RabbitMQ并声明队列")
 
+	// 开始消费结果（手动确认，回调完成后才确认）
+	consumerTag := "sentiment-service-" + uuid.New().String()
+	msgs, err := channel.Consume(
+		resultQueue, // 队列
+		consumerTag, // 消费者
+		false,       // 自动应答
+		false,       // 独占
+		false,       // 不等待
+		false,       // 参数
+		nil,         // 参数
+	)
+	if err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, fmt.Errorf("开始消费结果失败: %v", err)
+	}
+
 	mq := &SentimentMQ{
 		conn:            conn,
 		channel:         channel,
 		taskQueue:       taskQueue,
 		resultQueue:     resultQueue,
-		resultCallbacks: make(map[string]ResultCallback),
+		consumerTag:     consumerTag,
+		consumerDone:    make(chan struct{}),
+		resultCallbacks: make(map[string]pendingCallback),
 	}
 
 	// 启动结果消费者
-	go mq.consumeResults()
+	go mq.consumeResults(msgs)
 
 	return mq, nil
 }
 
-// PublishTask 发布情感分析任务
+// PublishTask 发布情感分析任务，requestID 为空时自动生成
+// 相同请求ID的任务仍在等待结果时返回 ErrDuplicateTask
 func (mq *SentimentMQ) PublishTask(
 	ctx context.Context,
+	requestID,
 	text,
 	language string,
 	callback ResultCallback,
 ) (string, error) {
 	// 生成请求ID
-	requestID := uuid.New().String()
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	logger := logging.FromContext(ctx).WithField("task_request_id", requestID)
 
 	// 创建任务
 	task := map[string]interface{}{
@@ -134,6 +187,17 @@ func (mq *SentimentMQ) PublishTask(
 		return "", fmt.Errorf("序列化任务失败: %v", err)
 	}
 
+	// 先注册回调，避免结果在注册前到达
+	if callback != nil {
+		mq.callbacksMu.Lock()
+		if _, exists := mq.resultCallbacks[requestID]; exists {
+			mq.callbacksMu.Unlock()
+			return "", ErrDuplicateTask
+		}
+		mq.resultCallbacks[requestID] = pendingCallback{callback: callback, logger: logger}
+		mq.callbacksMu.Unlock()
+	}
+
 	// 发布消息
 	err = mq.channel.Publish(
 		"",           // 交换机
@@ -147,59 +211,140 @@ func (mq *SentimentMQ) PublishTask(
 		},
 	)
 	if err != nil {
+		mq.callbacksMu.Lock()
+		delete(mq.resultCallbacks, requestID)
+		mq.callbacksMu.Unlock()
 		return "", fmt.Errorf("发布任务失败: %v", err)
 	}
 
-	// 注册回调
-	if callback != nil {
-		mq.resultCallbacks[requestID] = callback
+	logger.Debug("情感分析任务已发布")
+	return requestID, nil
+}
+
+// consumeResults 消费结果队列，直到消费者被取消或连接关闭
+func (mq *SentimentMQ) consumeResults(msgs <-chan amqp.Delivery) {
+	defer close(mq.consumerDone)
+
+	// 处理消息
+	for msg := range msgs {
+		mq.handleResult(msg)
 	}
 
-	return requestID, nil
+	logrus.Info("结果队列消费已停止")
 }
 
-// consumeResults 消费结果队列
-func (mq *SentimentMQ) consumeResults() {
-	// 消费消息
-	msgs, err := mq.channel.Consume(
-		mq.resultQueue, // 队列
-		"",             // 消费者
-		true,           // 自动应答
-		false,          // 独占
-		false,          // 不等待
-		false,          // 参数
-		nil,            // 参数
-	)
-	if err != nil {
-		logrus.Errorf("开始消费结果失败: %v", err)
+// handleResult 处理单条结果消息，回调完成后才确认消息
+// 这样关闭过程中未处理完的消息会被重新投递
+// 没有本地回调的结果退回队列，由发布任务的实例处理
+func (mq *SentimentMQ) handleResult(msg amqp.Delivery) {
+	var result map[string]interface{}
+	if err := json.Unmarshal(msg.Body, &result); err != nil {
+		logrus.Errorf("解析结果消息失败: %v", err)
+		ackResult(msg)
 		return
 	}
 
-	// 处理消息
-	for msg := range msgs {
-		var result map[string]interface{}
-		if err := json.Unmarshal(msg.Body, &result); err != nil {
-			logrus.Errorf("解析结果消息失败: %v", err)
-			continue
+	requestID, ok := result["request_id"].(string)
+	if !ok {
+		logrus.Error("结果消息缺少request_id字段")
+		ackResult(msg)
+		return
+	}
+
+	// 转换为结果模型
+	sentimentResult := convertToSentimentResult(result)
+
+	// 取出回调，执行期间计入运行中的回调
+	mq.callbacksMu.Lock()
+	pending, exists := mq.resultCallbacks[requestID]
+	if exists {
+		delete(mq.resultCallbacks, requestID)
+		mq.runningCallbacks++
+	}
+	mq.callbacksMu.Unlock()
+
+	if !exists {
+		mq.requeueOrphan(msg, requestID, result)
+		return
+	}
+
+	defer ackResult(msg)
+	defer func() {
+		mq.callbacksMu.Lock()
+		mq.runningCallbacks--
+		mq.callbacksMu.Unlock()
+	}()
+
+	pending.logger.Debug("收到异步分析结果")
+	pending.callback(sentimentResult)
+}
+
+// requeueOrphan 将没有本地回调的结果延迟退回队列
+// 结果已被退回过且处理完成超过 orphanResultTTL 时，发布任务的实例可能已经退出，直接丢弃
+func (mq *SentimentMQ) requeueOrphan(msg amqp.Delivery, requestID string, result map[string]interface{}) {
+	logger := logrus.WithField("task_request_id", requestID)
+
+	if msg.Redelivered {
+		processedAt, ok := result["processed_at"].(float64)
+		if !ok || time.Since(time.Unix(0, int64(processedAt*1e9))) > orphanResultTTL {
+			logger.Warn("结果长时间没有实例认领，已丢弃")
+			ackResult(msg)
+			return
 		}
+	}
 
-		requestID, ok := result["request_id"].(string)
-		if !ok {
-			logrus.Error("结果消息缺少request_id字段")
-			continue
+	logger.Debug("结果没有对应的回调，退回队列")
+
+	// 等待期间消息保持未确认，连接关闭时由RabbitMQ重新投递
+	time.AfterFunc(requeueDelay, func() {
+		if err := msg.Nack(false, true); err != nil {
+			logger.WithError(err).Debug("退回结果消息失败")
 		}
+	})
+}
+
+// ackResult 确认结果消息
+func ackResult(msg amqp.Delivery) {
+	if err := msg.Ack(false); err != nil {
+		logrus.WithError(err).Error("确认结果消息失败")
+	}
+}
+
+// Drain 等待所有已发布任务的回调执行完成
+// 上下文结束时返回仍在等待结果的请求ID，由调用方记录为已放弃
+func (mq *SentimentMQ) Drain(ctx context.Context) []string {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		mq.callbacksMu.Lock()
+		remaining := len(mq.resultCallbacks) + mq.runningCallbacks
+		mq.callbacksMu.Unlock()
 
-		// 转换为结果模型
-		sentimentResult := convertToSentimentResult(result)
+		if remaining == 0 {
+			return nil
+		}
 
-		// 调用回调函数
-		if callback, exists := mq.resultCallbacks[requestID]; exists {
-			callback(sentimentResult)
-			delete(mq.resultCallbacks, requestID)
+		select {
+		case <-ctx.Done():
+			return mq.pendingRequestIDs()
+		case <-ticker.C:
 		}
 	}
 }
 
+// pendingRequestIDs 返回仍在等待结果的请求ID
+func (mq *SentimentMQ) pendingRequestIDs() []string {
+	mq.callbacksMu.Lock()
+	defer mq.callbacksMu.Unlock()
+
+	ids := make([]string, 0, len(mq.resultCallbacks))
+	for requestID := range mq.resultCallbacks {
+		ids = append(ids, requestID)
+	}
+	return ids
+}
+
 // 转换为模型
 func convertToSentimentResult(data map[string]interface{}) *models.SentimentResult {
 	result := &models.SentimentResult{
@@ -240,9 +385,19 @@ func convertToSentimentResult(data map[string]interface{}) *models.SentimentResu
 	return result
 }
 
-// Close 关闭连接
-func (mq *SentimentMQ) Close() error {
+// Close 停止消费并关闭连接，正在执行的回调会先完成
+// ctx 结束时不再等待回调，直接关闭连接，未确认的结果由RabbitMQ重新投递
+func (mq *SentimentMQ) Close(ctx context.Context) error {
 	if mq.channel != nil {
+		if err := mq.channel.Cancel(mq.consumerTag, false); err != nil {
+			logrus.WithError(err).Warn("取消结果消费者失败")
+		} else {
+			select {
+			case <-mq.consumerDone:
+			case <-ctx.Done():
+				logrus.Warn("等待结果消费者停止超时，直接关闭连接")
+			}
+		}
 		mq.channel.Close()
 	}
 	if mq.conn != nil {
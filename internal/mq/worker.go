@@ -0,0 +1,196 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/streadway/amqp"
+
+	"sentiment-service/internal/models"
+)
+
+// Task 任务队列中的分析任务
+type Task struct {
+	Text      string `json:"text"`
+	Language  string `json:"language"`
+	RequestID string `json:"request_id"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// TaskHandler 处理单个分析任务并返回结果
+type TaskHandler func(ctx context.Context, task Task) (*models.SentimentResult, error)
+
+// TaskWorker 消费任务队列、处理任务并将结果发布到结果队列
+// 消息格式与 python-service/worker.py 一致，两者可以同时运行
+type TaskWorker struct {
+	conn        *amqp.Connection
+	taskQueue   string
+	resultQueue string
+	concurrency int
+}
+
+// NewTaskWorker 创建任务处理器，concurrency 为同时处理的任务数
+func NewTaskWorker(amqpURL, taskQueue, resultQueue string, concurrency int) (*TaskWorker, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	conn, err := amqp.Dial(amqpURL)
+	if err != nil {
+		return nil, fmt.Errorf("连接到RabbitMQ失败: %v", err)
+	}
+
+	return &TaskWorker{
+		conn:        conn,
+		taskQueue:   taskQueue,
+		resultQueue: resultQueue,
+		concurrency: concurrency,
+	}, nil
+}
+
+// Run 处理任务直到ctx结束，然后停止消费并等待正在处理的任务完成
+// 连接或通道意外关闭时等待处理协程退出后返回错误，由调用方决定退出或重新连接
+func (w *TaskWorker) Run(ctx context.Context, handler TaskHandler) error {
+	channel, err := w.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("创建通道失败: %v", err)
+	}
+	defer channel.Close()
+
+	for _, queue := range []string{w.taskQueue, w.resultQueue} {
+		if _, err := channel.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("声明队列 %s 失败: %v", queue, err)
+		}
+	}
+
+	// 每个处理协程最多持有一条未确认消息
+	if err := channel.Qos(w.concurrency, 0, false); err != nil {
+		return fmt.Errorf("设置预取数量失败: %v", err)
+	}
+
+	consumerTag := "sentiment-worker-" + uuid.New().String()
+	msgs, err := channel.Consume(w.taskQueue, consumerTag, false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("开始消费任务失败: %v", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"task_queue":   w.taskQueue,
+		"result_queue": w.resultQueue,
+		"concurrency":  w.concurrency,
+	}).Info("任务处理器已启动")
+
+	// 发布结果的通道不是并发安全的，用互斥锁保护
+	var publishMu sync.Mutex
+	publish := func(body []byte) error {
+		publishMu.Lock()
+		defer publishMu.Unlock()
+		return channel.Publish("", w.resultQueue, false, false, amqp.Publishing{
+			DeliveryMode: amqp.Persistent,
+			ContentType:  "application/json",
+			Body:         body,
+		})
+	}
+
+	// 连接或通道被关闭时（如RabbitMQ重启）msgs 也会被关闭
+	closed := channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range msgs {
+				w.handle(msg, handler, publish)
+			}
+		}()
+	}
+
+	// 所有处理协程退出说明消费已经停止
+	consumersDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(consumersDone)
+	}()
+
+	select {
+	case <-ctx.Done():
+	case amqpErr := <-closed:
+		<-consumersDone
+		if amqpErr == nil {
+			return errors.New("RabbitMQ通道已关闭")
+		}
+		return fmt.Errorf("RabbitMQ通道已关闭: %v", amqpErr)
+	case <-consumersDone:
+		return errors.New("任务消费已停止")
+	}
+	logrus.Info("正在停止任务处理器，等待进行中的任务完成...")
+
+	// 取消消费后 msgs 会被关闭，处理协程处理完手上的消息后退出
+	if err := channel.Cancel(consumerTag, false); err != nil {
+		logrus.WithError(err).Warn("取消任务消费者失败")
+	}
+	<-consumersDone
+
+	logrus.Info("任务处理器已停止")
+	return nil
+}
+
+// handle 处理单条任务消息
+// 解析失败的消息直接丢弃；处理失败的消息重新入队一次，再次失败则丢弃
+func (w *TaskWorker) handle(msg amqp.Delivery, handler TaskHandler, publish func([]byte) error) {
+	var task Task
+	if err := json.Unmarshal(msg.Body, &task); err != nil || task.Text == "" || task.RequestID == "" {
+		logrus.WithError(err).Error("任务消息格式无效，已丢弃")
+		msg.Nack(false, false)
+		return
+	}
+
+	logger := logrus.WithField("task_request_id", task.RequestID)
+	start := time.Now()
+
+	// 使用独立上下文，停止时让进行中的任务处理完成
+	result, err := handler(context.Background(), task)
+	if err != nil {
+		logger.WithError(err).Error("处理任务失败")
+		msg.Nack(false, !msg.Redelivered)
+		return
+	}
+
+	duration := time.Since(start)
+	body, err := json.Marshal(map[string]interface{}{
+		"request_id":        task.RequestID,
+		"text":              task.Text,
+		"sentiment":         result.Sentiment,
+		"score":             result.Score,
+		"confidence_scores": result.ConfidenceScores,
+		"keywords":          result.Keywords,
+		"duration":          duration.Seconds(),
+		"processed_at":      float64(time.Now().UnixNano()) / 1e9,
+	})
+	if err != nil {
+		logger.WithError(err).Error("序列化结果失败")
+		msg.Nack(false, false)
+		return
+	}
+
+	if err := publish(body); err != nil {
+		logger.WithError(err).Error("发布结果失败")
+		msg.Nack(false, true)
+		return
+	}
+
+	msg.Ack(false)
+	logger.WithField("duration_ms", duration.Milliseconds()).Info("任务处理完成")
+}
+
+// Close 关闭连接
+func (w *TaskWorker) Close() error {
+	return w.conn.Close()
+}
@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+)
+
+// ErrActiveAlertExists 规则已有未解决的告警（可能由其他实例同时创建）
+var ErrActiveAlertExists = errors.New("规则已有未解决的告警")
+
+// uniqueViolation PostgreSQL 唯一约束冲突的错误码
+const uniqueViolation = "23505"
+
+// AlertRepository 定义告警规则和告警记录的数据访问接口
+type AlertRepository interface {
+	// CreateRule 创建告警规则
+	CreateRule(ctx context.Context, rule *models.AlertRule) error
+
+	// GetRule 根据ID获取告警规则，不存在时返回 nil
+	GetRule(ctx context.Context, id string) (*models.AlertRule, error)
+
+	// FindRules 查询告警规则，按创建时间倒序
+	FindRules(ctx context.Context, params FindRulesParams) ([]*models.AlertRule, int64, error)
+
+	// UpdateRule 保存告警规则的所有字段
+	UpdateRule(ctx context.Context, rule *models.AlertRule) error
+
+	// DeleteRule 删除告警规则，规则的告警记录保留
+	DeleteRule(ctx context.Context, id string) error
+
+	// CreateAlert 创建告警；规则已有未解决的告警时返回 ErrActiveAlertExists
+	CreateAlert(ctx context.Context, alert *models.Alert) error
+
+	// GetAlert 根据ID获取告警，不存在时返回 nil
+	GetAlert(ctx context.Context, id string) (*models.Alert, error)
+
+	// FindAlerts 查询告警，按创建时间倒序
+	FindAlerts(ctx context.Context, params FindAlertsParams) ([]*models.Alert, int64, error)
+
+	// GetActiveAlert 获取规则未解决的告警，没有时返回 nil
+	GetActiveAlert(ctx context.Context, ruleID string) (*models.Alert, error)
+
+	// GetLatestAlert 获取规则最近的一次告警，没有时返回 nil
+	GetLatestAlert(ctx context.Context, ruleID string) (*models.Alert, error)
+
+	// TransitionAlert 仅当告警处于 from 中的某个状态时更新告警，返回是否更新
+	// 多个请求或实例同时修改同一告警时只有一个成功
+	TransitionAlert(ctx context.Context, id string, from []string, updates map[string]interface{}) (bool, error)
+
+	// UpdateAlertNotification 记录发送 webhook 的结果
+	UpdateAlertNotification(ctx context.Context, id string, notifiedAt *time.Time, notifyError string) error
+}
+
+// FindRulesParams 告警规则的查询参数
+type FindRulesParams struct {
+	UserID  string
+	Enabled *bool
+	Limit   int
+	Offset  int
+}
+
+// FindAlertsParams 告警的查询参数
+type FindAlertsParams struct {
+	RuleID string
+	Status string
+	Limit  int
+	Offset int
+}
+
+// alertRepository 实现告警规则和告警记录的数据访问
+type alertRepository struct {
+	db *gorm.DB
+}
+
+// NewAlertRepository 创建告警规则和告警记录的存储库
+func NewAlertRepository(db *gorm.DB) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+// CreateRule 创建告警规则
+func (r *alertRepository) CreateRule(ctx context.Context, rule *models.AlertRule) error {
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"name":   rule.Name,
+		"type":   rule.Type,
+		"metric": rule.Metric,
+	}).Debug("创建告警规则")
+
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// GetRule 根据ID获取告警规则
+func (r *alertRepository) GetRule(ctx context.Context, id string) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	err := r.db.WithContext(ctx).First(&rule, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// FindRules 查询告警规则
+func (r *alertRepository) FindRules(ctx context.Context, params FindRulesParams) ([]*models.AlertRule, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.AlertRule{})
+	if params.UserID != "" {
+		query = query.Where("user_id = ?", params.UserID)
+	}
+	if params.Enabled != nil {
+		query = query.Where("enabled = ?", *params.Enabled)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+	if params.Offset > 0 {
+		query = query.Offset(params.Offset)
+	}
+
+	var rules []*models.AlertRule
+	if err := query.Order("created_at DESC, id DESC").Find(&rules).Error; err != nil {
+		return nil, 0, err
+	}
+	return rules, count, nil
+}
+
+// UpdateRule 保存告警规则
+func (r *alertRepository) UpdateRule(ctx context.Context, rule *models.AlertRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+// DeleteRule 软删除告警规则
+func (r *alertRepository) DeleteRule(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.AlertRule{}).Error
+}
+
+// CreateAlert 创建告警
+func (r *alertRepository) CreateAlert(ctx context.Context, alert *models.Alert) error {
+	err := r.db.WithContext(ctx).Create(alert).Error
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrActiveAlertExists
+	}
+	return err
+}
+
+// GetAlert 根据ID获取告警
+func (r *alertRepository) GetAlert(ctx context.Context, id string) (*models.Alert, error) {
+	return r.firstAlert(r.db.WithContext(ctx).Where("id = ?", id))
+}
+
+// FindAlerts 查询告警
+func (r *alertRepository) FindAlerts(ctx context.Context, params FindAlertsParams) ([]*models.Alert, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Alert{})
+	if params.RuleID != "" {
+		query = query.Where("rule_id = ?", params.RuleID)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+	if params.Offset > 0 {
+		query = query.Offset(params.Offset)
+	}
+
+	var alerts []*models.Alert
+	if err := query.Order("created_at DESC, id DESC").Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+	return alerts, count, nil
+}
+
+// GetActiveAlert 获取规则未解决的告警
+func (r *alertRepository) GetActiveAlert(ctx context.Context, ruleID string) (*models.Alert, error) {
+	return r.firstAlert(r.db.WithContext(ctx).
+		Where("rule_id = ? AND status IN ?", ruleID, []string{models.AlertStatusOpen, models.AlertStatusAcknowledged}))
+}
+
+// GetLatestAlert 获取规则最近的一次告警
+func (r *alertRepository) GetLatestAlert(ctx context.Context, ruleID string) (*models.Alert, error) {
+	return r.firstAlert(r.db.WithContext(ctx).Where("rule_id = ?", ruleID).Order("created_at DESC, id DESC"))
+}
+
+// TransitionAlert 按条件更新告警状态
+func (r *alertRepository) TransitionAlert(ctx context.Context, id string, from []string, updates map[string]interface{}) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Alert{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"id":      id,
+		"status":  updates["status"],
+		"updated": result.RowsAffected > 0,
+	}).Debug("更新告警状态")
+
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateAlertNotification 记录发送 webhook 的结果
+func (r *alertRepository) UpdateAlertNotification(ctx context.Context, id string, notifiedAt *time.Time, notifyError string) error {
+	updates := map[string]interface{}{"notify_error": notifyError}
+	if notifiedAt != nil {
+		updates["notified_at"] = notifiedAt
+	}
+	return r.db.WithContext(ctx).Model(&models.Alert{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// firstAlert 返回查询的第一条告警，没有时返回 nil
+func (r *alertRepository) firstAlert(query *gorm.DB) (*models.Alert, error) {
+	var alert models.Alert
+	err := query.First(&alert).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
+)
+
+// BreakdownRow 一个元数据值对应的分析记录统计
+type BreakdownRow struct {
+	Value    string
+	Total    int64
+	Positive int64
+	Negative int64
+	Neutral  int64
+	AvgScore *float64
+	P10      *float64
+	P25      *float64
+	P50      *float64
+	P75      *float64
+	P90      *float64
+}
+
+// MetadataBreakdown 按元数据键 key 的值分组统计分析记录，按记录数倒序返回最多 limit 组
+// values 不为空时只统计这些值（用于与基准时间范围比较）
+func (r *sentimentRepository) MetadataBreakdown(ctx context.Context, filter StatsFilter, key string, values []string, limit int) ([]BreakdownRow, error) {
+	analyses := r.statsQuery(ctx, filter).Select("id, sentiment, score")
+	query := r.db.WithContext(ctx).
+		Table("analysis_metadata AS am").
+		Joins("JOIN (?) AS a ON a.id = am.analysis_id", analyses).
+		Where("am.key = ? AND am.deleted_at IS NULL", key)
+	if len(values) > 0 {
+		query = query.Where("am.value IN ?", values)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"key":    key,
+		"start":  filter.StartTime,
+		"end":    filter.EndTime,
+		"values": len(values),
+	}).Debug("按元数据分组统计")
+
+	var rows []BreakdownRow
+	err := query.
+		Select(`am.value,
+			count(*) AS total,
+			count(*) FILTER (WHERE a.sentiment = 'positive') AS positive,
+			count(*) FILTER (WHERE a.sentiment = 'negative') AS negative,
+			count(*) FILTER (WHERE a.sentiment = 'neutral') AS neutral,
+			avg(a.score::float8) AS avg_score,
+			percentile_cont(0.1) WITHIN GROUP (ORDER BY a.score::float8) AS p10,
+			percentile_cont(0.25) WITHIN GROUP (ORDER BY a.score::float8) AS p25,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY a.score::float8) AS p50,
+			percentile_cont(0.75) WITHIN GROUP (ORDER BY a.score::float8) AS p75,
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY a.score::float8) AS p90`).
+		Group("am.value").
+		Order("total DESC, am.value").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+)
+
+// maxKeywordLength 关键词的最大字符数，与 analysis_keywords.keyword 列一致
+const maxKeywordLength = 100
+
+// KeywordStatsRow 一个关键词的情感分布，每条分析记录计一次
+type KeywordStatsRow struct {
+	Keyword  string
+	Total    int64
+	Positive int64
+	Negative int64
+	Neutral  int64
+	AvgScore *float64
+}
+
+// RisingKeywordRow 一个关键词在当前和上一个时间窗口中出现的记录数
+type RisingKeywordRow struct {
+	Keyword       string
+	CurrentCount  int64
+	PreviousCount int64
+}
+
+// keywordStatsColumns 按关键词分组统计的列，a 是分析记录子查询
+const keywordStatsColumns = `ak.keyword,
+	count(*) AS total,
+	count(*) FILTER (WHERE a.sentiment = 'positive') AS positive,
+	count(*) FILTER (WHERE a.sentiment = 'negative') AS negative,
+	count(*) FILTER (WHERE a.sentiment = 'neutral') AS neutral,
+	avg(a.score) AS avg_score`
+
+// NormalizeKeyword 规范化关键词：去除首尾空白、转为小写并截断到最大长度
+// 与迁移中回填关键词的规则一致
+func NormalizeKeyword(keyword string) string {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	if runes := []rune(keyword); len(runes) > maxKeywordLength {
+		keyword = string(runes[:maxKeywordLength])
+	}
+	return keyword
+}
+
+// analysisKeywords 从分析记录逗号分隔的关键词生成关键词记录，忽略空关键词和重复的关键词
+func analysisKeywords(analysis *models.SentimentAnalysis) []models.AnalysisKeyword {
+	if analysis.Keywords == "" {
+		return nil
+	}
+
+	var rows []models.AnalysisKeyword
+	seen := make(map[string]bool)
+	for _, keyword := range strings.Split(analysis.Keywords, ",") {
+		keyword = NormalizeKeyword(keyword)
+		if keyword == "" || seen[keyword] {
+			continue
+		}
+		seen[keyword] = true
+		rows = append(rows, models.AnalysisKeyword{AnalysisID: analysis.ID, Keyword: keyword})
+	}
+	return rows
+}
+
+// TopKeywords 返回时间范围内出现在最多分析记录中的关键词及其情感分布
+func (r *sentimentRepository) TopKeywords(ctx context.Context, filter StatsFilter, limit int) ([]KeywordStatsRow, error) {
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"start": filter.StartTime,
+		"end":   filter.EndTime,
+		"limit": limit,
+	}).Debug("统计热门关键词")
+
+	var rows []KeywordStatsRow
+	err := r.keywordQuery(ctx, filter).
+		Select(keywordStatsColumns).
+		Group("ak.keyword").
+		Order("total DESC, ak.keyword").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// KeywordStats 返回指定关键词（已规范化）在时间范围内的情感分布，没有出现的关键词不返回
+func (r *sentimentRepository) KeywordStats(ctx context.Context, filter StatsFilter, keywords []string) ([]KeywordStatsRow, error) {
+	if len(keywords) == 0 {
+		return []KeywordStatsRow{}, nil
+	}
+
+	var rows []KeywordStatsRow
+	err := r.keywordQuery(ctx, filter).
+		Where("ak.keyword IN ?", keywords).
+		Select(keywordStatsColumns).
+		Group("ak.keyword").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// RisingKeywords 比较 filter 的时间范围与紧邻的上一个等长时间范围，返回出现次数增长最快的关键词
+// 增长按 (当前次数+1)/(之前次数+1) 排序，当前次数少于 minCount 的关键词不参与排序
+func (r *sentimentRepository) RisingKeywords(ctx context.Context, filter StatsFilter, minCount, limit int) ([]RisingKeywordRow, error) {
+	window := filter
+	window.StartTime = filter.StartTime.Add(-filter.EndTime.Sub(filter.StartTime))
+
+	counts := r.keywordQuery(ctx, window).
+		Select(`ak.keyword,
+			count(*) FILTER (WHERE a.created_at >= ?) AS current_count,
+			count(*) FILTER (WHERE a.created_at < ?) AS previous_count`, filter.StartTime, filter.StartTime).
+		Group("ak.keyword")
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"start":     filter.StartTime,
+		"end":       filter.EndTime,
+		"min_count": minCount,
+		"limit":     limit,
+	}).Debug("统计增长最快的关键词")
+
+	var rows []RisingKeywordRow
+	err := r.db.WithContext(ctx).
+		Table("(?) AS k", counts).
+		Where("current_count >= ?", minCount).
+		Order("(current_count + 1.0) / (previous_count + 1) DESC, current_count DESC, keyword").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// keywordQuery 连接关键词与符合统计条件的分析记录（别名 ak 和 a），每条分析记录的每个关键词一行
+func (r *sentimentRepository) keywordQuery(ctx context.Context, filter StatsFilter) *gorm.DB {
+	analyses := r.statsQuery(ctx, filter).Select("id, sentiment, score, created_at")
+	return r.db.WithContext(ctx).
+		Table("analysis_keywords AS ak").
+		Joins("JOIN (?) AS a ON a.id = ak.analysis_id", analyses).
+		Where("ak.deleted_at IS NULL")
+}
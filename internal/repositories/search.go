@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+)
+
+// SortByRelevance 按全文检索的相关度排序，只能用于 SearchAnalyses
+const SortByRelevance = "relevance"
+
+// 摘要中高亮命中词的标记，摘要中的其余文字已做HTML转义
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// headlineOptions ts_headline 的参数：最多两个片段，每个片段5到20个词
+const headlineOptions = "StartSel=" + HighlightStart + ", StopSel=" + HighlightStop + ", MaxFragments=2, MinWords=5, MaxWords=20, FragmentDelimiter= ... "
+
+// escapedText 按 html.EscapeString 的方式转义的原文，ts_headline 只在转义后的文字中插入高亮标记
+// 转义后的字符是XML实体，解析器不把它们当作词，命中位置不变
+const escapedText = `replace(replace(replace(replace(replace(text, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`
+
+// SearchHit 全文检索命中的分析记录
+type SearchHit struct {
+	Analysis *models.SentimentAnalysis
+	Rank     float64
+	// Headline 带高亮标记的摘要；中文记录由调用方生成，此处为空
+	Headline string
+}
+
+// SearchAnalyses 按 params.Query 全文检索分析记录，其他过滤条件与 FindAnalyses 相同
+// 默认按相关度倒序，也可以按 SortBy 排序；不支持游标，只按 Limit 和 Offset 分页
+func (r *sentimentRepository) SearchAnalyses(ctx context.Context, params FindAnalysesParams) ([]SearchHit, int64, error) {
+	count := int64(-1)
+	query := applyFilters(r.db.WithContext(ctx).Model(&models.SentimentAnalysis{}), params)
+
+	if !params.SkipCount {
+		if err := query.Count(&count).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"query":    params.Query,
+		"language": params.Language,
+		"count":    count,
+	}).Debug("全文检索情感分析记录")
+
+	tsquery, args := searchQuery(params.Language, params.Query)
+	query = query.Select("id, ts_rank_cd(search_vector, "+tsquery+") AS rank", args...)
+
+	if params.SortBy == SortByRelevance || params.SortBy == "" {
+		query = query.Order("rank DESC, id DESC")
+	} else {
+		query = query.Order(analysesOrder(params))
+	}
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+	if params.Offset > 0 {
+		query = query.Offset(params.Offset)
+	}
+
+	var ranked []struct {
+		ID   string
+		Rank float64
+	}
+	if err := query.Scan(&ranked).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(ranked) == 0 {
+		return []SearchHit{}, count, nil
+	}
+
+	ids := make([]string, len(ranked))
+	for i, row := range ranked {
+		ids[i] = row.ID
+	}
+
+	// 一次查询本页所有分析记录
+	var analyses []*models.SentimentAnalysis
+	if err := r.db.WithContext(ctx).Preload("Metadata").Where("id IN ?", ids).Find(&analyses).Error; err != nil {
+		return nil, 0, err
+	}
+	byId := make(map[string]*models.SentimentAnalysis, len(analyses))
+	for _, analysis := range analyses {
+		byId[analysis.ID] = analysis
+	}
+
+	headlines, err := r.searchHeadlines(ctx, ids, params.Query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]SearchHit, 0, len(ranked))
+	for _, row := range ranked {
+		if analysis, ok := byId[row.ID]; ok {
+			hits = append(hits, SearchHit{Analysis: analysis, Rank: row.Rank, Headline: headlines[row.ID]})
+		}
+	}
+
+	return hits, count, nil
+}
+
+// searchHeadlines 只为本页的非中文记录生成摘要（ts_headline 需要重新解析全文，代价较高）
+// 中文按字索引，ts_headline 无法在原文上高亮
+// 原文先做HTML转义，摘要中只有高亮标记是HTML标签
+func (r *sentimentRepository) searchHeadlines(ctx context.Context, ids []string, q string) (map[string]string, error) {
+	var rows []struct {
+		ID       string
+		Headline string
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.SentimentAnalysis{}).
+		Select("id, ts_headline('english', "+escapedText+", websearch_to_tsquery('english', ?), ?) AS headline", q, headlineOptions).
+		Where("id IN ?", ids).
+		Where("language IS NULL OR language NOT LIKE 'zh%'").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	headlines := make(map[string]string, len(rows))
+	for _, row := range rows {
+		headlines[row.ID] = row.Headline
+	}
+	return headlines, nil
+}
+
+// applySearch 只保留匹配全文检索查询的记录
+func applySearch(query *gorm.DB, language, q string) *gorm.DB {
+	tsquery, args := searchQuery(language, q)
+	return query.Where("search_vector @@ "+tsquery, args...)
+}
+
+// searchQuery 返回全文检索查询的SQL表达式和参数，与迁移中 sentiment_search_document 的索引方式对应
+// 指定了英文或中文时只按该语言的方式查询，否则两种方式任一匹配即可
+func searchQuery(language, q string) (string, []interface{}) {
+	switch {
+	case strings.HasPrefix(language, "en"):
+		return "websearch_to_tsquery('english', ?)", []interface{}{q}
+	case strings.HasPrefix(language, "zh"):
+		return "websearch_to_tsquery('simple', ?)", []interface{}{cjkSearchQuery(q)}
+	default:
+		return "(websearch_to_tsquery('english', ?) || websearch_to_tsquery('simple', ?))", []interface{}{q, cjkSearchQuery(q)}
+	}
+}
+
+// cjkSearchQuery 将查询中连续的汉字改写为逐字短语（情感 -> "情 感"），与按字索引的中文文本匹配
+// 已在引号中的汉字只加空格，引号内整体仍是一个短语
+func cjkSearchQuery(q string) string {
+	var b strings.Builder
+	runes := []rune(q)
+	quoted := false
+
+	for i := 0; i < len(runes); {
+		if runes[i] == '"' {
+			quoted = !quoted
+		}
+		if !IsCJK(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && IsCJK(runes[j]) {
+			j++
+		}
+		chars := make([]string, 0, j-i)
+		for _, r := range runes[i:j] {
+			chars = append(chars, string(r))
+		}
+
+		phrase := strings.Join(chars, " ")
+		if !quoted {
+			phrase = `"` + phrase + `"`
+		}
+		// 紧跟在 - 之后时不能加空格，否则排除不生效
+		if i == 0 || runes[i-1] != '-' {
+			b.WriteString(" ")
+		}
+		b.WriteString(phrase + " ")
+		i = j
+	}
+
+	return b.String()
+}
+
+// IsCJK 判断字符是否按字索引，范围与迁移中 sentiment_cjk_spaced 一致
+// （CJK统一汉字、扩展A区和兼容汉字）
+func IsCJK(r rune) bool {
+	return (r >= 0x3400 && r <= 0x4dbf) || (r >= 0x4e00 && r <= 0x9fff) || (r >= 0xf900 && r <= 0xfaff)
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCJKSearchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"没有汉字", "battery life", "battery life"},
+		{"连续汉字改写为短语", "情感", `"情 感"`},
+		{"多个词", "好 服务", `"好" "服 务"`},
+		{"汉字与英文相连", "iPhone好用", `iPhone "好 用"`},
+		{"引号中的汉字不再加引号", `"服务 很好"`, `" 服 务 很 好 "`},
+		{"排除汉字", "-差评", `-"差 评"`},
+		{"排除与其他词组合", "价格 -贵", `"价 格" -"贵"`},
+		{"or 运算符", "好 or 坏", `"好" or "坏"`},
+		{"扩展A区和兼容汉字", "㐀豈", `"㐀 豈"`},
+		{"假名不按字改写", "ありがとう", "ありがとう"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 多余的空白不影响 websearch_to_tsquery 的解析
+			got := strings.Join(strings.Fields(cjkSearchQuery(tt.query)), " ")
+			if got != tt.want {
+				t.Errorf("cjkSearchQuery(%q) = %q，期望 %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,18 +3,29 @@ package repositories
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"sentiment-service/internal/logging"
 	"sentiment-service/internal/models"
 )
 
+// ErrDuplicateRequestID 请求ID已被其他分析记录使用
+var ErrDuplicateRequestID = errors.New("请求ID已被其他分析记录使用")
+
+// requestIDIndex 分析记录请求ID的唯一索引
+const requestIDIndex = "idx_sentiment_analyses_request_id"
+
 // SentimentRepository 定义了情感分析数据存储操作的接口
 type SentimentRepository interface {
-	// CreateAnalysis 创建一个新的情感分析记录
+	// CreateAnalysis 创建一个新的情感分析记录，请求ID已被使用时返回 ErrDuplicateRequestID
 	CreateAnalysis(ctx context.Context, analysis *models.SentimentAnalysis) error
 
 	// GetAnalysisById 根据ID获取情感分析记录
@@ -23,9 +34,30 @@ type SentimentRepository interface {
 	// GetAnalysisByRequestId 根据请求ID获取情感分析记录
 	GetAnalysisByRequestId(ctx context.Context, requestId string) (*models.SentimentAnalysis, error)
 
-	// FindAnalyses 获取情感分析记录，可选过滤条件
+	// FindAnalyses 获取情感分析记录，可选过滤条件；SkipCount 时返回的总数为 -1
 	FindAnalyses(ctx context.Context, params FindAnalysesParams) ([]*models.SentimentAnalysis, int64, error)
 
+	// SearchAnalyses 全文检索分析记录，返回相关度和摘要；SkipCount 时返回的总数为 -1
+	SearchAnalyses(ctx context.Context, params FindAnalysesParams) ([]SearchHit, int64, error)
+
+	// SentimentTrend 按时间段统计各情感标签的数量和平均分数，包含没有记录的时间段
+	SentimentTrend(ctx context.Context, params TrendParams) ([]TrendRow, error)
+
+	// TopKeywords 返回时间范围内出现在最多分析记录中的关键词及其情感分布
+	TopKeywords(ctx context.Context, filter StatsFilter, limit int) ([]KeywordStatsRow, error)
+
+	// KeywordStats 返回指定关键词（已规范化）的情感分布，没有出现的关键词不返回
+	KeywordStats(ctx context.Context, filter StatsFilter, keywords []string) ([]KeywordStatsRow, error)
+
+	// RisingKeywords 返回与上一个等长时间范围相比出现次数增长最快的关键词
+	RisingKeywords(ctx context.Context, filter StatsFilter, minCount, limit int) ([]RisingKeywordRow, error)
+
+	// MetadataBreakdown 按元数据键的值分组统计分析记录；values 不为空时只统计这些值
+	MetadataBreakdown(ctx context.Context, filter StatsFilter, key string, values []string, limit int) ([]BreakdownRow, error)
+
+	// WindowStats 从 filter.EndTime 向前按 window 长度划分窗口，统计每个窗口的记录数和平均分数
+	WindowStats(ctx context.Context, filter StatsFilter, window time.Duration) ([]WindowStatsRow, error)
+
 	// CreateBatchAnalysis 创建一个新的批处理分析记录
 	CreateBatchAnalysis(ctx context.Context, batch *models.BatchAnalysis) error
 
@@ -34,6 +66,50 @@ type SentimentRepository interface {
 
 	// UpdateBatchStatus 更新批处理分析的状态
 	UpdateBatchStatus(ctx context.Context, batchId string, status string) error
+
+	// GetBatchById 根据ID获取批处理分析记录
+	GetBatchById(ctx context.Context, batchId string) (*models.BatchAnalysis, error)
+
+	// FindBatches 获取批处理分析记录，按创建时间倒序
+	FindBatches(ctx context.Context, params FindBatchesParams) ([]*models.BatchAnalysis, int64, error)
+
+	// CreateBatchResults 在一个事务中批量写入分析记录（含元数据）和批处理项目
+	CreateBatchResults(ctx context.Context, analyses []*models.SentimentAnalysis, items []models.BatchItem) error
+
+	// InsertAnalyses 在一个事务中批量写入分析记录（含元数据），已存在的记录（按ID）被跳过
+	// 请求ID已被其他记录使用的记录改用新生成的请求ID写入
+	InsertAnalyses(ctx context.Context, analyses []*models.SentimentAnalysis) error
+
+	// UpdateBatchProgress 更新批处理的状态、进度和时间戳
+	UpdateBatchProgress(ctx context.Context, batch *models.BatchAnalysis) error
+
+	// TouchBatches 刷新未结束批处理的更新时间，表示处理它们的实例仍在运行
+	TouchBatches(ctx context.Context, batchIds []string) error
+
+	// FindStaleBatches 获取更新时间早于 before 的未结束（pending、processing）批处理
+	FindStaleBatches(ctx context.Context, before time.Time) ([]*models.BatchAnalysis, error)
+
+	// MarkBatchInterrupted 记录被中断批处理的最终状态和进度，
+	// 仅在记录仍未结束且更新时间早于 before 时更新，返回是否已更新
+	MarkBatchInterrupted(ctx context.Context, batch *models.BatchAnalysis, before time.Time) (bool, error)
+
+	// CountBatchItems 统计批处理中已存储分析记录的项目数量
+	CountBatchItems(ctx context.Context, batchId string) (int64, error)
+
+	// FindBatchResults 按批处理中的顺序获取批处理项目及其分析记录（分析记录不存在时为nil）
+	FindBatchResults(ctx context.Context, batchId string, params FindBatchResultsParams) ([]BatchResultItem, int64, error)
+
+	// IterateAnalyses 按创建时间顺序分批遍历符合条件的分析记录（忽略分页参数）
+	IterateAnalyses(ctx context.Context, params FindAnalysesParams, batchSize int, fn func([]*models.SentimentAnalysis) error) error
+
+	// UpdateAnalysisResult 更新分析记录的分析结果
+	UpdateAnalysisResult(ctx context.Context, id string, sentiment string, score float64, keywords string) error
+
+	// CountAnalyses 统计符合条件的分析记录数量
+	CountAnalyses(ctx context.Context, params FindAnalysesParams) (int64, error)
+
+	// DeleteAnalyses 删除符合条件的分析记录及其元数据和批处理项目，hard 为 true 时物理删除
+	DeleteAnalyses(ctx context.Context, params FindAnalysesParams, hard bool) (int64, error)
 }
 
 // FindAnalysesParams 定义了搜索分析记录的参数
@@ -42,10 +118,72 @@ type FindAnalysesParams struct {
 	StartTime *time.Time
 	EndTime   *time.Time
 	Sentiment string
+	Language  string
+	MinScore  *float64
+	MaxScore  *float64
+	Keyword   string            // 包含该关键词（规范化后完整匹配）
+	BatchID   string            // 属于该批处理
+	Metadata  map[string]string // 每个键值对都必须匹配
+	Query     string            // 全文检索查询（websearch 语法），按 Language 选择分词方式
+	SortBy    string            // SortByCreatedAt（默认）或 SortByScore，IterateAnalyses 忽略排序
+	SortOrder string            // SortDesc（默认）或 SortAsc
+	Cursor    *Cursor           // 设置后从游标位置继续，忽略 Offset
+	SkipCount bool              // 不统计总数，返回的总数为 -1
+	Limit     int
+	Offset    int
+}
+
+// FindBatchResultsParams 定义了获取批处理结果的参数
+type FindBatchResultsParams struct {
+	Status    string  // 只返回该状态的项目，为空时返回所有项目
+	Cursor    *Cursor // 设置后从游标位置继续，忽略 Offset
+	SkipCount bool    // 不统计总数，返回的总数为 -1
 	Limit     int
 	Offset    int
 }
 
+// Cursor 键集分页的位置，即上一页边界记录的排序值和ID
+// 分析记录按 (CreatedAt 或 Score, ID) 定位，批处理结果按 Order 定位
+type Cursor struct {
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	Score     float64   `json:"score,omitempty"`
+	Order     int       `json:"order,omitempty"`
+	ID        string    `json:"id,omitempty"`
+	// SortBy 和 SortOrder 是创建游标时的排序方式，只能用于相同排序的查询
+	SortBy    string `json:"sort_by,omitempty"`
+	SortOrder string `json:"sort_order,omitempty"`
+	// Backward 为 true 时获取游标之前的记录（上一页），返回结果仍按原排序排列
+	Backward bool `json:"backward,omitempty"`
+}
+
+// 分析记录的排序字段和方向
+const (
+	SortByCreatedAt = "created_at"
+	SortByScore     = "score"
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// FindBatchesParams 定义了搜索批处理记录的参数
+type FindBatchesParams struct {
+	UserID string
+	Status string
+	Limit  int
+	Offset int
+}
+
+// BatchResultItem 批处理项目及其分析记录
+type BatchResultItem struct {
+	Order     int
+	ItemID    string
+	Status    string
+	Error     string
+	SourceRow map[string]string
+	// Analysis 项目的分析记录，失败、跳过或分析记录已被删除时为nil
+	Analysis *models.SentimentAnalysis
+}
+
 // sentimentRepository 实现了SentimentRepository接口
 type sentimentRepository struct {
 	db *gorm.DB
@@ -66,13 +204,21 @@ func (r *sentimentRepository) CreateAnalysis(ctx context.Context, analysis *mode
 		analysis.RequestID = uuid.New().String()
 	}
 
-	logrus.WithFields(logrus.Fields{
+	logging.FromContext(ctx).WithFields(logrus.Fields{
 		"id":         analysis.ID,
 		"request_id": analysis.RequestID,
 		"sentiment":  analysis.Sentiment,
 	}).Debug("创建情感分析记录")
 
-	return r.db.WithContext(ctx).Create(analysis).Error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createAnalyses(tx, []*models.SentimentAnalysis{analysis})
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == requestIDIndex {
+		return ErrDuplicateRequestID
+	}
+	return err
 }
 
 // GetAnalysisById 根据ID获取情感分析记录
@@ -85,7 +231,7 @@ func (r *sentimentRepository) GetAnalysisById(ctx context.Context, id string) (*
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logrus.WithField("id", id).Debug("未找到情感分析记录")
+			logging.FromContext(ctx).WithField("id", id).Debug("未找到情感分析记录")
 			return nil, nil
 		}
 		return nil, err
@@ -104,7 +250,7 @@ func (r *sentimentRepository) GetAnalysisByRequestId(ctx context.Context, reques
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logrus.WithField("request_id", requestId).Debug("未找到情感分析记录")
+			logging.FromContext(ctx).WithField("request_id", requestId).Debug("未找到情感分析记录")
 			return nil, nil
 		}
 		return nil, err
@@ -116,12 +262,58 @@ func (r *sentimentRepository) GetAnalysisByRequestId(ctx context.Context, reques
 // FindAnalyses 获取情感分析记录，可选过滤条件
 func (r *sentimentRepository) FindAnalyses(ctx context.Context, params FindAnalysesParams) ([]*models.SentimentAnalysis, int64, error) {
 	var analyses []*models.SentimentAnalysis
-	var count int64
+	count := int64(-1)
 
 	// 构建查询
-	query := r.db.WithContext(ctx).Model(&models.SentimentAnalysis{})
+	query := applyFilters(r.db.WithContext(ctx).Model(&models.SentimentAnalysis{}), params)
+
+	// 获取总数
+	if !params.SkipCount {
+		if err := query.Count(&count).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"user_id":   params.UserID,
+		"sentiment": params.Sentiment,
+		"count":     count,
+		"cursor":    params.Cursor != nil,
+	}).Debug("查询情感分析记录")
+
+	// 应用分页
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+
+	if params.Cursor != nil {
+		query = applyAnalysesCursor(query, params)
+	} else if params.Offset > 0 {
+		query = query.Offset(params.Offset)
+	}
+
+	// 执行查询并预加载
+	err := query.
+		Preload("Metadata").
+		Order(analysesOrder(params)).
+		Find(&analyses).Error
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 向前翻页时按相反方向查询，恢复原排序
+	if params.Cursor != nil && params.Cursor.Backward {
+		for i, j := 0, len(analyses)-1; i < j; i, j = i+1, j-1 {
+			analyses[i], analyses[j] = analyses[j], analyses[i]
+		}
+	}
 
-	// 应用过滤器
+	return analyses, count, nil
+}
+
+// applyFilters 应用分析记录的过滤条件
+func applyFilters(query *gorm.DB, params FindAnalysesParams) *gorm.DB {
 	if params.UserID != "" {
 		query = query.Where("user_id = ?", params.UserID)
 	}
@@ -138,38 +330,223 @@ func (r *sentimentRepository) FindAnalyses(ctx context.Context, params FindAnaly
 		query = query.Where("sentiment = ?", params.Sentiment)
 	}
 
-	// 获取总数
-	err := query.Count(&count).Error
-	if err != nil {
-		return nil, 0, err
+	if params.Language != "" {
+		query = query.Where("language = ?", params.Language)
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"user_id":   params.UserID,
-		"sentiment": params.Sentiment,
-		"count":     count,
-	}).Debug("查询情感分析记录")
+	if params.MinScore != nil {
+		query = query.Where("score >= ?", *params.MinScore)
+	}
 
-	// 应用分页
-	if params.Limit > 0 {
-		query = query.Limit(params.Limit)
+	if params.MaxScore != nil {
+		query = query.Where("score <= ?", *params.MaxScore)
 	}
 
-	if params.Offset > 0 {
-		query = query.Offset(params.Offset)
+	if params.Keyword != "" {
+		// 按规范化后的完整关键词匹配
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM analysis_keywords ak WHERE ak.analysis_id = sentiment_analyses.id AND ak.keyword = ? AND ak.deleted_at IS NULL)",
+			NormalizeKeyword(params.Keyword),
+		)
 	}
 
-	// 执行查询并预加载
-	err = query.
-		Preload("Metadata").
-		Order("created_at DESC").
-		Find(&analyses).Error
+	if params.BatchID != "" {
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM batch_items bi WHERE bi.analysis_id = sentiment_analyses.id AND bi.batch_id = ? AND bi.deleted_at IS NULL)",
+			params.BatchID,
+		)
+	}
 
-	if err != nil {
-		return nil, 0, err
+	if params.Query != "" {
+		query = applySearch(query, params.Language, params.Query)
 	}
 
-	return analyses, count, nil
+	// 按键排序，保证相同条件生成相同的SQL
+	keys := make([]string, 0, len(params.Metadata))
+	for key := range params.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM analysis_metadata am WHERE am.analysis_id = sentiment_analyses.id AND am.key = ? AND am.value = ? AND am.deleted_at IS NULL)",
+			key, params.Metadata[key],
+		)
+	}
+
+	return query
+}
+
+// analysesOrder 返回分析记录的排序子句，排序字段相同时按ID排序保证分页稳定
+// 使用向前翻页的游标时方向相反
+func analysesOrder(params FindAnalysesParams) string {
+	direction := "DESC"
+	if !descending(params) {
+		direction = "ASC"
+	}
+	column := sortColumn(params.SortBy)
+	return fmt.Sprintf("%s %s, id %s", column, direction, direction)
+}
+
+// applyAnalysesCursor 只保留排在游标之后的记录（向前翻页时为之前的记录）
+func applyAnalysesCursor(query *gorm.DB, params FindAnalysesParams) *gorm.DB {
+	cursor := params.Cursor
+	operator := ">"
+	if descending(params) {
+		operator = "<"
+	}
+
+	var value interface{} = cursor.CreatedAt
+	if sortColumn(params.SortBy) == SortByScore {
+		value = cursor.Score
+	}
+	return query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sortColumn(params.SortBy), operator), value, cursor.ID)
+}
+
+// sortColumn 返回排序字段对应的列，未知字段按创建时间排序
+func sortColumn(sortBy string) string {
+	if sortBy == SortByScore {
+		return SortByScore
+	}
+	return SortByCreatedAt
+}
+
+// descending 判断查询是否按倒序执行（向前翻页时与请求的方向相反）
+func descending(params FindAnalysesParams) bool {
+	desc := params.SortOrder != SortAsc
+	if params.Cursor != nil && params.Cursor.Backward {
+		desc = !desc
+	}
+	return desc
+}
+
+// IterateAnalyses 按创建时间顺序分批遍历符合条件的分析记录
+// 使用 (created_at, id) 键集分页，遍历过程中更新记录不会导致跳过或重复
+func (r *sentimentRepository) IterateAnalyses(
+	ctx context.Context,
+	params FindAnalysesParams,
+	batchSize int,
+	fn func([]*models.SentimentAnalysis) error,
+) error {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	var lastCreatedAt time.Time
+	var lastID string
+
+	for {
+		query := applyFilters(r.db.WithContext(ctx).Model(&models.SentimentAnalysis{}), params)
+		if lastID != "" {
+			query = query.Where("(created_at, id) > (?, ?)", lastCreatedAt, lastID)
+		}
+
+		var analyses []*models.SentimentAnalysis
+		err := query.
+			Preload("Metadata").
+			Order("created_at ASC, id ASC").
+			Limit(batchSize).
+			Find(&analyses).Error
+		if err != nil {
+			return err
+		}
+
+		if len(analyses) == 0 {
+			return nil
+		}
+
+		if err := fn(analyses); err != nil {
+			return err
+		}
+
+		last := analyses[len(analyses)-1]
+		lastCreatedAt, lastID = last.CreatedAt, last.ID
+
+		if len(analyses) < batchSize {
+			return nil
+		}
+	}
+}
+
+// UpdateAnalysisResult 更新分析记录的分析结果，同时替换记录的关键词
+func (r *sentimentRepository) UpdateAnalysisResult(
+	ctx context.Context,
+	id string,
+	sentiment string,
+	score float64,
+	keywords string,
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.SentimentAnalysis{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"sentiment": sentiment,
+				"score":     score,
+				"keywords":  keywords,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("analysis_id = ?", id).Delete(&models.AnalysisKeyword{}).Error; err != nil {
+			return err
+		}
+		rows := analysisKeywords(&models.SentimentAnalysis{ID: id, Keywords: keywords})
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// CountAnalyses 统计符合条件的分析记录数量
+func (r *sentimentRepository) CountAnalyses(ctx context.Context, params FindAnalysesParams) (int64, error) {
+	var count int64
+	err := applyFilters(r.db.WithContext(ctx).Model(&models.SentimentAnalysis{}), params).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteAnalyses 删除符合条件的分析记录及其元数据和批处理项目
+// 软删除只标记 deleted_at；物理删除同时清理已被软删除的记录
+func (r *sentimentRepository) DeleteAnalyses(ctx context.Context, params FindAnalysesParams, hard bool) (int64, error) {
+	var deleted int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		scoped := tx
+		if hard {
+			scoped = tx.Unscoped()
+		}
+
+		ids := applyFilters(scoped.Model(&models.SentimentAnalysis{}), params).Select("id")
+
+		if err := scoped.Where("analysis_id IN (?)", ids).Delete(&models.AnalysisMetadata{}).Error; err != nil {
+			return err
+		}
+
+		if err := scoped.Where("analysis_id IN (?)", ids).Delete(&models.AnalysisKeyword{}).Error; err != nil {
+			return err
+		}
+
+		if err := scoped.Where("analysis_id IN (?)", ids).Delete(&models.BatchItem{}).Error; err != nil {
+			return err
+		}
+
+		result := applyFilters(scoped, params).Delete(&models.SentimentAnalysis{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return nil
+	})
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"user_id": params.UserID,
+		"hard":    hard,
+		"deleted": deleted,
+	}).Debug("删除情感分析记录")
+
+	return deleted, err
 }
 
 // CreateBatchAnalysis 创建一个新的批处理分析记录
@@ -182,7 +559,7 @@ func (r *sentimentRepository) CreateBatchAnalysis(ctx context.Context, batch *mo
 		batch.Status = "pending"
 	}
 
-	logrus.WithFields(logrus.Fields{
+	logging.FromContext(ctx).WithFields(logrus.Fields{
 		"id":     batch.ID,
 		"count":  batch.Count,
 		"status": batch.Status,
@@ -193,16 +570,20 @@ func (r *sentimentRepository) CreateBatchAnalysis(ctx context.Context, batch *mo
 
 // AddBatchItems 向批处理分析添加项目
 func (r *sentimentRepository) AddBatchItems(ctx context.Context, batchId string, analysisIds []string) error {
+	items := make([]models.BatchItem, len(analysisIds))
+	for i := range analysisIds {
+		items[i] = models.BatchItem{
+			BatchID:    batchId,
+			AnalysisID: &analysisIds[i],
+			Order:      i,
+			Status:     models.BatchItemStatusSucceeded,
+		}
+	}
+
 	// 使用事务确保所有项目都被原子性添加
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		for i, analysisId := range analysisIds {
-			item := models.BatchItem{
-				BatchID:    batchId,
-				AnalysisID: analysisId,
-				Order:      i,
-			}
-
-			if err := tx.Create(&item).Error; err != nil {
+		if len(items) > 0 {
+			if err := tx.CreateInBatches(items, bulkInsertBatchSize).Error; err != nil {
 				return err
 			}
 		}
@@ -215,9 +596,380 @@ func (r *sentimentRepository) AddBatchItems(ctx context.Context, batchId string,
 	})
 }
 
+// GetBatchById 根据ID获取批处理分析记录
+func (r *sentimentRepository) GetBatchById(ctx context.Context, batchId string) (*models.BatchAnalysis, error) {
+	var batch models.BatchAnalysis
+
+	err := r.db.WithContext(ctx).First(&batch, "id = ?", batchId).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logging.FromContext(ctx).WithField("batch_id", batchId).Debug("未找到批处理分析记录")
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+// FindBatches 获取批处理分析记录，按创建时间倒序
+func (r *sentimentRepository) FindBatches(ctx context.Context, params FindBatchesParams) ([]*models.BatchAnalysis, int64, error) {
+	var batches []*models.BatchAnalysis
+	var count int64
+
+	query := r.db.WithContext(ctx).Model(&models.BatchAnalysis{})
+
+	if params.UserID != "" {
+		query = query.Where("user_id = ?", params.UserID)
+	}
+
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+
+	if params.Offset > 0 {
+		query = query.Offset(params.Offset)
+	}
+
+	if err := query.Order("created_at DESC").Find(&batches).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return batches, count, nil
+}
+
+// CountBatchItems 统计批处理中已存储分析记录的项目数量
+func (r *sentimentRepository) CountBatchItems(ctx context.Context, batchId string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.BatchItem{}).
+		Where("batch_id = ? AND status = ?", batchId, models.BatchItemStatusSucceeded).
+		Count(&count).Error
+	return count, err
+}
+
+// FindBatchResults 按批处理中的顺序获取批处理项目及其分析记录
+func (r *sentimentRepository) FindBatchResults(ctx context.Context, batchId string, params FindBatchResultsParams) ([]BatchResultItem, int64, error) {
+	var items []models.BatchItem
+	count := int64(-1)
+
+	query := r.db.WithContext(ctx).Model(&models.BatchItem{}).Where("batch_id = ?", batchId)
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+
+	if !params.SkipCount {
+		if err := query.Count(&count).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+
+	order := `"order" ASC`
+	if params.Cursor != nil {
+		if params.Cursor.Backward {
+			query = query.Where(`"order" < ?`, params.Cursor.Order)
+			order = `"order" DESC`
+		} else {
+			query = query.Where(`"order" > ?`, params.Cursor.Order)
+		}
+	} else if params.Offset > 0 {
+		query = query.Offset(params.Offset)
+	}
+
+	if err := query.Order(order).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 向前翻页时按相反方向查询，恢复输入顺序
+	if params.Cursor != nil && params.Cursor.Backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if len(items) == 0 {
+		return []BatchResultItem{}, count, nil
+	}
+
+	// 一次查询本页所有分析记录
+	analysisIds := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.AnalysisID != nil {
+			analysisIds = append(analysisIds, *item.AnalysisID)
+		}
+	}
+
+	byId := make(map[string]*models.SentimentAnalysis, len(analysisIds))
+	if len(analysisIds) > 0 {
+		var analyses []*models.SentimentAnalysis
+		err := r.db.WithContext(ctx).
+			Preload("Metadata").
+			Where("id IN ?", analysisIds).
+			Find(&analyses).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, analysis := range analyses {
+			byId[analysis.ID] = analysis
+		}
+	}
+
+	// 分析记录可能已被删除，项目仍然返回，由调用方决定如何处理
+	// 返回的项目数与本页的项目数一致，调用方可以据此判断是否还有更多项目
+	results := make([]BatchResultItem, len(items))
+	for i, item := range items {
+		results[i] = BatchResultItem{
+			Order:     item.Order,
+			ItemID:    item.ItemID,
+			Status:    item.Status,
+			Error:     item.Error,
+			SourceRow: item.SourceRow,
+		}
+		if item.AnalysisID != nil {
+			results[i].Analysis = byId[*item.AnalysisID]
+		}
+	}
+
+	return results, count, nil
+}
+
+// bulkInsertBatchSize 批量写入时每条 INSERT 语句包含的行数
+// 每行的参数不超过十几个，保持在 PostgreSQL 单条语句 65535 个参数的限制以内
+const bulkInsertBatchSize = 1000
+
+// CreateBatchResults 在一个事务中批量写入分析记录（含元数据）和批处理项目
+// 使用多行 INSERT，每种记录每 bulkInsertBatchSize 行一条语句；未设置ID的分析记录会生成ID，
+// 批处理项目的 AnalysisID 必须为空或引用 analyses 中的记录
+func (r *sentimentRepository) CreateBatchResults(ctx context.Context, analyses []*models.SentimentAnalysis, items []models.BatchItem) error {
+	if len(analyses) == 0 && len(items) == 0 {
+		return nil
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"analyses": len(analyses),
+		"items":    len(items),
+	}).Debug("批量写入批处理结果")
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := createAnalyses(tx, analyses); err != nil {
+			return err
+		}
+		if len(items) > 0 {
+			if err := tx.CreateInBatches(items, bulkInsertBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// InsertAnalyses 在一个事务中批量写入分析记录（含元数据）
+// 已存在的记录（按ID，包括已软删除的）被跳过，同一批记录可以安全地重复写入
+func (r *sentimentRepository) InsertAnalyses(ctx context.Context, analyses []*models.SentimentAnalysis) error {
+	if len(analyses) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		for _, analysis := range analyses {
+			if analysis.ID != "" {
+				ids = append(ids, analysis.ID)
+			}
+		}
+
+		var existing []string
+		if len(ids) > 0 {
+			if err := tx.Unscoped().
+				Model(&models.SentimentAnalysis{}).
+				Where("id IN ?", ids).
+				Pluck("id", &existing).Error; err != nil {
+				return err
+			}
+		}
+
+		exists := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			exists[id] = true
+		}
+		pending := make([]*models.SentimentAnalysis, 0, len(analyses))
+		for _, analysis := range analyses {
+			if !exists[analysis.ID] {
+				pending = append(pending, analysis)
+			}
+		}
+
+		// 异步写入时已无法向客户端报告冲突，改用新的请求ID，避免整条记录被拒绝
+		if err := reassignDuplicateRequestIDs(ctx, tx, pending); err != nil {
+			return err
+		}
+
+		logging.FromContext(ctx).WithFields(logrus.Fields{
+			"analyses": len(pending),
+			"skipped":  len(analyses) - len(pending),
+		}).Debug("批量写入分析记录")
+
+		return createAnalyses(tx, pending)
+	})
+}
+
+// reassignDuplicateRequestIDs 为请求ID已被其他记录（或同一批中之前的记录）使用的记录生成新的请求ID
+func reassignDuplicateRequestIDs(ctx context.Context, tx *gorm.DB, analyses []*models.SentimentAnalysis) error {
+	var requestIDs []string
+	for _, analysis := range analyses {
+		if analysis.RequestID != "" {
+			requestIDs = append(requestIDs, analysis.RequestID)
+		}
+	}
+	if len(requestIDs) == 0 {
+		return nil
+	}
+
+	var existing []string
+	if err := tx.Unscoped().
+		Model(&models.SentimentAnalysis{}).
+		Where("request_id IN ?", requestIDs).
+		Pluck("request_id", &existing).Error; err != nil {
+		return err
+	}
+
+	used := make(map[string]bool, len(existing)+len(analyses))
+	for _, requestID := range existing {
+		used[requestID] = true
+	}
+	for _, analysis := range analyses {
+		if analysis.RequestID == "" {
+			continue
+		}
+		if used[analysis.RequestID] {
+			requestID := uuid.New().String()
+			logging.FromContext(ctx).WithFields(logrus.Fields{
+				"analysis_id":    analysis.ID,
+				"request_id":     analysis.RequestID,
+				"new_request_id": requestID,
+			}).Warn("请求ID已被其他分析记录使用，改用新的请求ID")
+			analysis.RequestID = requestID
+		}
+		used[analysis.RequestID] = true
+	}
+	return nil
+}
+
+// createAnalyses 在事务中批量写入分析记录，元数据和关键词单独批量写入，避免按记录逐条保存关联
+func createAnalyses(tx *gorm.DB, analyses []*models.SentimentAnalysis) error {
+	if len(analyses) == 0 {
+		return nil
+	}
+
+	var metadata []models.AnalysisMetadata
+	var keywords []models.AnalysisKeyword
+	for _, analysis := range analyses {
+		if analysis.ID == "" {
+			analysis.ID = uuid.New().String()
+		}
+		if analysis.RequestID == "" {
+			analysis.RequestID = uuid.New().String()
+		}
+		for i := range analysis.Metadata {
+			analysis.Metadata[i].AnalysisID = analysis.ID
+			metadata = append(metadata, analysis.Metadata[i])
+		}
+		keywords = append(keywords, analysisKeywords(analysis)...)
+	}
+
+	if err := tx.Omit(clause.Associations).CreateInBatches(analyses, bulkInsertBatchSize).Error; err != nil {
+		return err
+	}
+	if len(metadata) > 0 {
+		if err := tx.CreateInBatches(metadata, bulkInsertBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(keywords) > 0 {
+		if err := tx.CreateInBatches(keywords, bulkInsertBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateBatchProgress 更新批处理的状态、进度和时间戳
+func (r *sentimentRepository) UpdateBatchProgress(ctx context.Context, batch *models.BatchAnalysis) error {
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"batch_id":  batch.ID,
+		"status":    batch.Status,
+		"processed": batch.ProcessedCount,
+		"failed":    batch.FailedCount,
+		"skipped":   batch.SkippedCount,
+	}).Debug("更新批处理进度")
+
+	// 显式选择列，保证零值（例如清空错误）也会被写入
+	return r.db.WithContext(ctx).
+		Model(batch).
+		Select("count", "status", "processed_count", "failed_count", "skipped_count", "error", "started_at", "completed_at", "estimated_at", "updated_at").
+		Updates(batch).
+		Error
+}
+
+// unfinishedBatchStatuses 未结束的批处理状态
+var unfinishedBatchStatuses = []string{models.BatchStatusPending, models.BatchStatusProcessing}
+
+// TouchBatches 刷新未结束批处理的更新时间，表示处理它们的实例仍在运行
+func (r *sentimentRepository) TouchBatches(ctx context.Context, batchIds []string) error {
+	if len(batchIds) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).
+		Model(&models.BatchAnalysis{}).
+		Where("id IN ? AND status IN ?", batchIds, unfinishedBatchStatuses).
+		Update("updated_at", time.Now()).
+		Error
+}
+
+// FindStaleBatches 获取更新时间早于 before 的未结束（pending、processing）批处理
+func (r *sentimentRepository) FindStaleBatches(ctx context.Context, before time.Time) ([]*models.BatchAnalysis, error) {
+	var batches []*models.BatchAnalysis
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND updated_at < ?", unfinishedBatchStatuses, before).
+		Order("updated_at").
+		Find(&batches).Error
+	return batches, err
+}
+
+// MarkBatchInterrupted 记录被中断批处理的最终状态和进度，
+// 仅在记录仍未结束且更新时间早于 before 时更新，返回是否已更新
+func (r *sentimentRepository) MarkBatchInterrupted(ctx context.Context, batch *models.BatchAnalysis, before time.Time) (bool, error) {
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"batch_id": batch.ID,
+		"status":   batch.Status,
+		"failed":   batch.FailedCount,
+	}).Debug("记录被中断的批处理")
+
+	// 查询之后处理该批处理的实例可能恢复了心跳，条件更新避免覆盖其进度
+	result := r.db.WithContext(ctx).
+		Model(batch).
+		Where("status IN ? AND updated_at < ?", unfinishedBatchStatuses, before).
+		Select("status", "failed_count", "error", "completed_at", "estimated_at", "updated_at").
+		Updates(batch)
+	return result.RowsAffected > 0, result.Error
+}
+
 // UpdateBatchStatus 更新批处理分析的状态
 func (r *sentimentRepository) UpdateBatchStatus(ctx context.Context, batchId string, status string) error {
-	logrus.WithFields(logrus.Fields{
+	logging.FromContext(ctx).WithFields(logrus.Fields{
 		"batch_id": batchId,
 		"status":   status,
 	}).Debug("更新批处理状态")
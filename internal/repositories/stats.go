@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+)
+
+// 趋势统计的时间粒度，与 PostgreSQL date_trunc 的单位一致
+const (
+	IntervalMinute = "minute"
+	IntervalHour   = "hour"
+	IntervalDay    = "day"
+	IntervalWeek   = "week"
+)
+
+// StatsFilter 统计接口共用的过滤条件，时间范围为 [StartTime, EndTime)
+type StatsFilter struct {
+	StartTime time.Time
+	EndTime   time.Time
+	UserID    string
+	Language  string
+	Metadata  map[string]string
+}
+
+// TrendParams 情感趋势统计的条件
+type TrendParams struct {
+	StatsFilter
+	Interval string
+	Timezone string // IANA 时区名，按该时区的本地时间划分时间段
+}
+
+// TrendRow 一个时间段的统计结果，没有记录的时间段计数为0、平均分数为空
+type TrendRow struct {
+	Bucket   time.Time
+	Total    int64
+	Positive int64
+	Negative int64
+	Neutral  int64
+	AvgScore *float64
+}
+
+// trendSQL 用 generate_series 生成范围内的所有时间段，再与按时间段分组的统计结果连接，补齐没有记录的时间段
+// 时间段在指定时区的本地时间上截断，返回时再转换回带时区的时间
+const trendSQL = `SELECT (b.bucket AT TIME ZONE ?) AS bucket,
+	COALESCE(c.total, 0) AS total,
+	COALESCE(c.positive, 0) AS positive,
+	COALESCE(c.negative, 0) AS negative,
+	COALESCE(c.neutral, 0) AS neutral,
+	c.avg_score
+FROM generate_series(
+	date_trunc(?, ?::timestamptz AT TIME ZONE ?),
+	(?::timestamptz AT TIME ZONE ?) - interval '1 microsecond',
+	?::interval
+) AS b(bucket)
+LEFT JOIN (?) AS c ON c.bucket = b.bucket
+ORDER BY b.bucket`
+
+// SentimentTrend 按时间段统计各情感标签的数量和平均分数
+func (r *sentimentRepository) SentimentTrend(ctx context.Context, params TrendParams) ([]TrendRow, error) {
+	counts := r.statsQuery(ctx, params.StatsFilter).
+		Select(`date_trunc(?, created_at AT TIME ZONE ?) AS bucket,
+			count(*) AS total,
+			count(*) FILTER (WHERE sentiment = 'positive') AS positive,
+			count(*) FILTER (WHERE sentiment = 'negative') AS negative,
+			count(*) FILTER (WHERE sentiment = 'neutral') AS neutral,
+			avg(score) AS avg_score`, params.Interval, params.Timezone).
+		Group("bucket")
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"interval": params.Interval,
+		"timezone": params.Timezone,
+		"start":    params.StartTime,
+		"end":      params.EndTime,
+	}).Debug("统计情感趋势")
+
+	var rows []TrendRow
+	err := r.db.WithContext(ctx).Raw(trendSQL,
+		params.Timezone,
+		params.Interval, params.StartTime, params.Timezone,
+		params.EndTime, params.Timezone,
+		"1 "+params.Interval,
+		counts,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// statsQuery 返回符合统计条件的分析记录查询
+// 与其他表连接时，应将其作为子查询使用（条件中的列名没有加表名）
+func (r *sentimentRepository) statsQuery(ctx context.Context, filter StatsFilter) *gorm.DB {
+	params := FindAnalysesParams{
+		UserID:    filter.UserID,
+		Language:  filter.Language,
+		Metadata:  filter.Metadata,
+		StartTime: &filter.StartTime,
+	}
+	return applyFilters(r.db.WithContext(ctx).Model(&models.SentimentAnalysis{}), params).
+		Where("created_at < ?", filter.EndTime)
+}
+
+// WindowStatsRow 一个统计窗口内的记录数和平均分数
+type WindowStatsRow struct {
+	WindowIndex int // 0 是最近的窗口 [EndTime-window, EndTime)，1 是之前的一个，依此类推
+	Total       int64
+	Negative    int64
+	AvgScore    *float64
+}
+
+// WindowStats 从 filter.EndTime 向前按 window 长度划分窗口，统计 [StartTime, EndTime) 内每个窗口的记录
+// 没有记录的窗口不返回
+func (r *sentimentRepository) WindowStats(ctx context.Context, filter StatsFilter, window time.Duration) ([]WindowStatsRow, error) {
+	var rows []WindowStatsRow
+	err := r.statsQuery(ctx, filter).
+		Select(`floor(extract(epoch FROM (?::timestamptz - created_at)) / ?)::int AS window_index,
+			count(*) AS total,
+			count(*) FILTER (WHERE sentiment = 'negative') AS negative,
+			avg(score) AS avg_score`, filter.EndTime, window.Seconds()).
+		Group("window_index").
+		Order("window_index").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
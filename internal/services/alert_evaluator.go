@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// MinBaselineWindows zscore 规则至少需要的有效基准窗口数
+const MinBaselineWindows = 3
+
+// alertMetricNames 告警说明中的指标名称
+var alertMetricNames = map[string]string{
+	models.AlertMetricNegativeShare: "负面占比",
+	models.AlertMetricAverageScore:  "平均分数",
+	models.AlertMetricVolume:        "记录数",
+}
+
+// Start 在后台每隔 interval 评估一次所有启用的规则
+func (s *AlertService) Start(interval time.Duration) {
+	s.stop = make(chan struct{})
+	s.stopped = make(chan struct{})
+
+	go func() {
+		defer close(s.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.EvaluateRules(s.ctx)
+			}
+		}
+	}()
+
+	logrus.WithField("interval", interval).Info("告警规则评估已启动")
+}
+
+// Stop 停止定期评估并等待进行中的评估和通知完成
+// ctx 结束时取消进行中的请求，返回是否已正常结束
+func (s *AlertService) Stop(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		if s.stop != nil {
+			s.once.Do(func() { close(s.stop) })
+			<-s.stopped
+		}
+		s.notifications.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		s.cancel()
+		return false
+	}
+}
+
+// EvaluateRules 评估所有启用的规则，产生或自动解决告警
+// 单个规则评估失败只记录日志，不影响其他规则
+func (s *AlertService) EvaluateRules(ctx context.Context) {
+	enabled := true
+	rules, _, err := s.alerts.FindRules(ctx, repositories.FindRulesParams{Enabled: &enabled})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("获取告警规则失败")
+		return
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		if ctx.Err() != nil {
+			return
+		}
+		ruleCtx := logging.WithLogger(ctx, logging.FromContext(ctx).WithField("rule_id", rule.ID))
+		if err := s.evaluateRule(ruleCtx, rule, now); err != nil {
+			logging.FromContext(ruleCtx).WithError(err).Error("评估告警规则失败")
+		}
+	}
+}
+
+// PreviewRule 按当前数据评估规则，不产生或解决告警
+func (s *AlertService) PreviewRule(ctx context.Context, id string) (*models.AlertEvaluation, error) {
+	rule, err := s.GetRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(ctx, rule, time.Now())
+}
+
+// evaluateRule 评估规则并更新告警状态
+// 规则没有未解决的告警且超过冷却时间时产生新告警；指标恢复正常时自动解决未解决的告警
+// 数据不足时保持现状
+func (s *AlertService) evaluateRule(ctx context.Context, rule *models.AlertRule, now time.Time) error {
+	eval, err := s.evaluate(ctx, rule, now)
+	if err != nil {
+		return err
+	}
+
+	active, err := s.alerts.GetActiveAlert(ctx, rule.ID)
+	if err != nil {
+		return err
+	}
+	if active != nil {
+		if eval.Evaluable && !eval.Breached {
+			return s.autoResolve(ctx, rule, active, "指标已恢复："+eval.Message)
+		}
+		return nil
+	}
+	if !eval.Breached {
+		return nil
+	}
+
+	latest, err := s.alerts.GetLatestAlert(ctx, rule.ID)
+	if err != nil {
+		return err
+	}
+	if latest != nil && latest.ResolvedAt != nil && now.Sub(*latest.ResolvedAt) < rule.Cooldown() {
+		logging.FromContext(ctx).WithField("resolved_at", latest.ResolvedAt).Debug("告警规则在冷却时间内，不产生新告警")
+		return nil
+	}
+
+	alert := &models.Alert{
+		RuleID:         rule.ID,
+		Status:         models.AlertStatusOpen,
+		Value:          *eval.Value,
+		Threshold:      eval.Threshold,
+		BaselineMean:   eval.BaselineMean,
+		BaselineStddev: eval.BaselineStddev,
+		ZScore:         eval.ZScore,
+		SampleCount:    eval.SampleCount,
+		WindowStart:    eval.WindowStart,
+		WindowEnd:      eval.WindowEnd,
+		Message:        eval.Message,
+	}
+	err = s.alerts.CreateAlert(ctx, alert)
+	if errors.Is(err, repositories.ErrActiveAlertExists) {
+		// 其他实例已经产生了告警
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"alert_id": alert.ID,
+		"value":    alert.Value,
+	}).Warn("触发告警：" + alert.Message)
+
+	s.notify(ctx, models.AlertEventFired, rule, alert)
+	return nil
+}
+
+// evaluate 计算规则在截止到 now 的最近一个窗口上的指标，判断是否触发
+func (s *AlertService) evaluate(ctx context.Context, rule *models.AlertRule, now time.Time) (*models.AlertEvaluation, error) {
+	window := rule.Window()
+	windows := 1
+	if rule.Type == models.AlertRuleTypeZScore {
+		windows += rule.BaselineWindows
+	}
+
+	rows, err := s.analyses.WindowStats(ctx, repositories.StatsFilter{
+		StartTime: now.Add(-time.Duration(windows) * window),
+		EndTime:   now,
+		UserID:    rule.UserID,
+		Language:  rule.Language,
+		Metadata:  rule.Metadata,
+	}, window)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[int]repositories.WindowStatsRow, len(rows))
+	for _, row := range rows {
+		stats[row.WindowIndex] = row
+	}
+
+	current := stats[0]
+	eval := &models.AlertEvaluation{
+		RuleID:      rule.ID,
+		WindowStart: now.Add(-window),
+		WindowEnd:   now,
+		SampleCount: int(current.Total),
+		Threshold:   rule.Threshold,
+	}
+
+	value, ok := metricValue(rule, current)
+	if !ok {
+		eval.Reason = fmt.Sprintf("当前窗口的记录数 %d 少于最小记录数 %d", current.Total, max(rule.MinCount, 1))
+		return eval, nil
+	}
+	eval.Value = &value
+
+	switch rule.Type {
+	case models.AlertRuleTypeThreshold:
+		eval.Evaluable = true
+		eval.Breached = exceeds(rule.Direction, value, rule.Threshold)
+	case models.AlertRuleTypeZScore:
+		var baseline []float64
+		for i := 1; i < windows; i++ {
+			if v, ok := metricValue(rule, stats[i]); ok {
+				baseline = append(baseline, v)
+			}
+		}
+		eval.BaselineWindows = len(baseline)
+		if len(baseline) < MinBaselineWindows {
+			eval.Reason = fmt.Sprintf("有效的基准窗口数 %d 少于 %d", len(baseline), MinBaselineWindows)
+			return eval, nil
+		}
+
+		mean, stddev := meanStddev(baseline)
+		eval.BaselineMean = &mean
+		eval.BaselineStddev = &stddev
+		if stddev == 0 {
+			eval.Reason = "基准窗口的指标没有波动，无法计算标准分数"
+			return eval, nil
+		}
+
+		z := (value - mean) / stddev
+		eval.ZScore = &z
+		eval.Evaluable = true
+		if rule.Direction == models.AlertDirectionBelow {
+			eval.Breached = exceeds(rule.Direction, z, -rule.Threshold)
+		} else {
+			eval.Breached = exceeds(rule.Direction, z, rule.Threshold)
+		}
+	default:
+		return nil, fmt.Errorf("未知的告警规则类型: %s", rule.Type)
+	}
+
+	eval.Message = alertMessage(rule, eval)
+	return eval, nil
+}
+
+// metricValue 计算窗口的指标值，记录数少于规则的最小记录数时返回 false
+// volume 指标总是有值（没有记录的窗口为0）
+func metricValue(rule *models.AlertRule, row repositories.WindowStatsRow) (float64, bool) {
+	if rule.Metric == models.AlertMetricVolume {
+		return float64(row.Total), true
+	}
+	if row.Total == 0 || row.Total < int64(rule.MinCount) {
+		return 0, false
+	}
+
+	switch rule.Metric {
+	case models.AlertMetricNegativeShare:
+		return float64(row.Negative) / float64(row.Total), true
+	case models.AlertMetricAverageScore:
+		if row.AvgScore == nil {
+			return 0, false
+		}
+		return *row.AvgScore, true
+	}
+	return 0, false
+}
+
+// exceeds 判断指标值是否按方向越过阈值（等于阈值不算越过）
+func exceeds(direction string, value, threshold float64) bool {
+	if direction == models.AlertDirectionBelow {
+		return value < threshold
+	}
+	return value > threshold
+}
+
+// meanStddev 计算均值和样本标准差
+func meanStddev(values []float64) (mean, stddev float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sum / float64(len(values)-1))
+}
+
+// alertMessage 生成评估结果的说明
+func alertMessage(rule *models.AlertRule, eval *models.AlertEvaluation) string {
+	name := alertMetricNames[rule.Metric]
+	relation := "高于"
+	if rule.Direction == models.AlertDirectionBelow {
+		relation = "低于"
+	}
+	if !eval.Breached {
+		relation = "未" + relation
+	}
+
+	if rule.Type == models.AlertRuleTypeZScore {
+		// 方向为 below 时标准分数与负的阈值比较
+		limit := rule.Threshold
+		if rule.Direction == models.AlertDirectionBelow {
+			limit = -limit
+		}
+		return fmt.Sprintf("%s %.4g 偏离基准均值 %.4g 达 %.2f 个标准差，%s阈值 %g（最近 %s，%d 条记录，%d 个基准窗口）",
+			name, *eval.Value, *eval.BaselineMean, *eval.ZScore, relation, limit,
+			rule.Window(), eval.SampleCount, eval.BaselineWindows)
+	}
+	return fmt.Sprintf("%s %.4g %s阈值 %g（最近 %s，%d 条记录）",
+		name, *eval.Value, relation, rule.Threshold, rule.Window(), eval.SampleCount)
+}
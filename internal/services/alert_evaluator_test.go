@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+func TestMetricValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric string
+		min    int
+		row    repositories.WindowStatsRow
+		want   float64
+		wantOK bool
+	}{
+		{"记录数", models.AlertMetricVolume, 0, repositories.WindowStatsRow{Total: 12}, 12, true},
+		{"没有记录的窗口记录数为0", models.AlertMetricVolume, 5, repositories.WindowStatsRow{}, 0, true},
+		{"负面占比", models.AlertMetricNegativeShare, 0, repositories.WindowStatsRow{Total: 8, Negative: 2}, 0.25, true},
+		{"没有记录时没有负面占比", models.AlertMetricNegativeShare, 0, repositories.WindowStatsRow{}, 0, false},
+		{"少于最小记录数", models.AlertMetricNegativeShare, 10, repositories.WindowStatsRow{Total: 9, Negative: 9}, 0, false},
+		{"等于最小记录数", models.AlertMetricNegativeShare, 10, repositories.WindowStatsRow{Total: 10, Negative: 5}, 0.5, true},
+		{"平均分数", models.AlertMetricAverageScore, 0, repositories.WindowStatsRow{Total: 3, AvgScore: floatPtr(-0.4)}, -0.4, true},
+		{"缺少平均分数", models.AlertMetricAverageScore, 0, repositories.WindowStatsRow{Total: 3}, 0, false},
+		{"未知指标", "latency", 0, repositories.WindowStatsRow{Total: 3}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &models.AlertRule{Metric: tt.metric, MinCount: tt.min}
+			got, ok := metricValue(rule, tt.row)
+			if ok != tt.wantOK || !approxEqual(got, tt.want) {
+				t.Errorf("metricValue() = %v, %v，期望 %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestExceeds(t *testing.T) {
+	tests := []struct {
+		direction string
+		value     float64
+		threshold float64
+		want      bool
+	}{
+		{models.AlertDirectionAbove, 0.6, 0.5, true},
+		{models.AlertDirectionAbove, 0.5, 0.5, false},
+		{models.AlertDirectionAbove, 0.4, 0.5, false},
+		{models.AlertDirectionBelow, 0.4, 0.5, true},
+		{models.AlertDirectionBelow, 0.5, 0.5, false},
+		{models.AlertDirectionBelow, 0.6, 0.5, false},
+		{models.AlertDirectionBelow, -3, -2, true},
+		{models.AlertDirectionBelow, 3, -2, false},
+	}
+
+	for _, tt := range tests {
+		if got := exceeds(tt.direction, tt.value, tt.threshold); got != tt.want {
+			t.Errorf("exceeds(%s, %v, %v) = %v，期望 %v", tt.direction, tt.value, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestMeanStddev(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		mean   float64
+		stddev float64
+	}{
+		{"单个值没有标准差", []float64{4}, 4, 0},
+		{"相同的值", []float64{2, 2, 2}, 2, 0},
+		{"样本标准差", []float64{90, 100, 110}, 100, 10},
+		{"负数", []float64{-1, -3}, -2, 1.4142135623730951},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mean, stddev := meanStddev(tt.values)
+			if !approxEqual(mean, tt.mean) || !approxEqual(stddev, tt.stddev) {
+				t.Errorf("meanStddev(%v) = %v, %v，期望 %v, %v", tt.values, mean, stddev, tt.mean, tt.stddev)
+			}
+		})
+	}
+}
+
+// windowStatsRepository 返回固定窗口统计的分析记录仓库
+type windowStatsRepository struct {
+	repositories.SentimentRepository
+	rows []repositories.WindowStatsRow
+}
+
+func (r *windowStatsRepository) WindowStats(context.Context, repositories.StatsFilter, time.Duration) ([]repositories.WindowStatsRow, error) {
+	return r.rows, nil
+}
+
+// volumeRows 按窗口顺序（0 为当前窗口）生成记录数统计
+func volumeRows(totals ...int64) []repositories.WindowStatsRow {
+	rows := make([]repositories.WindowStatsRow, 0, len(totals))
+	for i, total := range totals {
+		rows = append(rows, repositories.WindowStatsRow{WindowIndex: i, Total: total})
+	}
+	return rows
+}
+
+func TestEvaluate(t *testing.T) {
+	zscore := func(direction string) *models.AlertRule {
+		return &models.AlertRule{
+			Type:            models.AlertRuleTypeZScore,
+			Metric:          models.AlertMetricVolume,
+			Direction:       direction,
+			Threshold:       2,
+			WindowSeconds:   300,
+			BaselineWindows: 3,
+		}
+	}
+
+	// 基准窗口的记录数为 90、100、110：均值 100，样本标准差 10
+	tests := []struct {
+		name      string
+		rule      *models.AlertRule
+		rows      []repositories.WindowStatsRow
+		evaluable bool
+		breached  bool
+		zscore    *float64
+		reason    string
+	}{
+		{
+			name:      "低于基准超过阈值",
+			rule:      zscore(models.AlertDirectionBelow),
+			rows:      volumeRows(70, 90, 100, 110),
+			evaluable: true,
+			breached:  true,
+			zscore:    floatPtr(-3),
+		},
+		{
+			name:      "低于基准未超过阈值",
+			rule:      zscore(models.AlertDirectionBelow),
+			rows:      volumeRows(85, 90, 100, 110),
+			evaluable: true,
+			zscore:    floatPtr(-1.5),
+		},
+		{
+			name:      "低于基准恰好等于阈值",
+			rule:      zscore(models.AlertDirectionBelow),
+			rows:      volumeRows(80, 90, 100, 110),
+			evaluable: true,
+			zscore:    floatPtr(-2),
+		},
+		{
+			name:      "方向为 below 时高于基准不触发",
+			rule:      zscore(models.AlertDirectionBelow),
+			rows:      volumeRows(130, 90, 100, 110),
+			evaluable: true,
+			zscore:    floatPtr(3),
+		},
+		{
+			name:      "高于基准超过阈值",
+			rule:      zscore(models.AlertDirectionAbove),
+			rows:      volumeRows(125, 90, 100, 110),
+			evaluable: true,
+			breached:  true,
+			zscore:    floatPtr(2.5),
+		},
+		{
+			name:      "方向为 above 时低于基准不触发",
+			rule:      zscore(models.AlertDirectionAbove),
+			rows:      volumeRows(70, 90, 100, 110),
+			evaluable: true,
+			zscore:    floatPtr(-3),
+		},
+		{
+			name:   "基准窗口不足",
+			rule:   &models.AlertRule{Type: models.AlertRuleTypeZScore, Metric: models.AlertMetricNegativeShare, Direction: models.AlertDirectionAbove, Threshold: 2, WindowSeconds: 300, BaselineWindows: 3},
+			rows:   []repositories.WindowStatsRow{{WindowIndex: 0, Total: 10, Negative: 9}, {WindowIndex: 1, Total: 10, Negative: 1}},
+			reason: "有效的基准窗口数 1 少于 3",
+		},
+		{
+			name:   "基准没有波动",
+			rule:   zscore(models.AlertDirectionAbove),
+			rows:   volumeRows(500, 100, 100, 100),
+			reason: "基准窗口的指标没有波动",
+		},
+		{
+			name:      "阈值规则",
+			rule:      &models.AlertRule{Type: models.AlertRuleTypeThreshold, Metric: models.AlertMetricNegativeShare, Direction: models.AlertDirectionAbove, Threshold: 0.5, WindowSeconds: 300, MinCount: 5},
+			rows:      []repositories.WindowStatsRow{{WindowIndex: 0, Total: 10, Negative: 6}},
+			evaluable: true,
+			breached:  true,
+		},
+		{
+			name:   "阈值规则记录数不足",
+			rule:   &models.AlertRule{Type: models.AlertRuleTypeThreshold, Metric: models.AlertMetricNegativeShare, Direction: models.AlertDirectionAbove, Threshold: 0.5, WindowSeconds: 300, MinCount: 5},
+			rows:   []repositories.WindowStatsRow{{WindowIndex: 0, Total: 4, Negative: 4}},
+			reason: "当前窗口的记录数 4 少于最小记录数 5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAlertService(nil, &windowStatsRepository{rows: tt.rows}, nil)
+
+			eval, err := s.evaluate(context.Background(), tt.rule, time.Now())
+			if err != nil {
+				t.Fatalf("evaluate() 返回错误: %v", err)
+			}
+			if eval.Evaluable != tt.evaluable || eval.Breached != tt.breached {
+				t.Errorf("Evaluable, Breached = %v, %v，期望 %v, %v（%s）", eval.Evaluable, eval.Breached, tt.evaluable, tt.breached, eval.Message)
+			}
+			if tt.zscore != nil && !approxPtrEqual(eval.ZScore, tt.zscore) {
+				t.Errorf("ZScore = %v，期望 %v", eval.ZScore, *tt.zscore)
+			}
+			if !strings.Contains(eval.Reason, tt.reason) {
+				t.Errorf("Reason = %q，期望包含 %q", eval.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestAlertMessageBelowThreshold(t *testing.T) {
+	rule := &models.AlertRule{
+		Type:            models.AlertRuleTypeZScore,
+		Metric:          models.AlertMetricVolume,
+		Direction:       models.AlertDirectionBelow,
+		Threshold:       2,
+		WindowSeconds:   300,
+		BaselineWindows: 3,
+	}
+	s := NewAlertService(nil, &windowStatsRepository{rows: volumeRows(70, 90, 100, 110)}, nil)
+
+	eval, err := s.evaluate(context.Background(), rule, time.Now())
+	if err != nil {
+		t.Fatalf("evaluate() 返回错误: %v", err)
+	}
+	// 方向为 below 时说明中的阈值为负数
+	if !strings.Contains(eval.Message, "低于阈值 -2") {
+		t.Errorf("Message = %q，期望包含 %q", eval.Message, "低于阈值 -2")
+	}
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// ErrAlertRuleNotFound 告警规则不存在
+var ErrAlertRuleNotFound = errors.New("告警规则不存在")
+
+// ErrAlertNotFound 告警不存在
+var ErrAlertNotFound = errors.New("告警不存在")
+
+// ErrInvalidAlertTransition 告警当前的状态不允许该操作（例如确认已解决的告警）
+var ErrInvalidAlertTransition = errors.New("告警当前的状态不允许该操作")
+
+// ErrInvalidWebhookURL 规则的 webhook 地址不可用（例如指向内网地址）
+var ErrInvalidWebhookURL = errors.New("webhook_url 不可用")
+
+// resolvedBySystem 规则自动解决告警时记录的操作人
+const resolvedBySystem = "system"
+
+// AlertNotifier 发送告警通知（默认实现为 webhook.Sender）
+type AlertNotifier interface {
+	// Send 向 url 发送事件，接收方未成功接收时返回错误
+	Send(ctx context.Context, url, event string, payload interface{}) error
+
+	// CheckURL 校验 url 是否可以作为通知地址，保存规则前调用
+	CheckURL(ctx context.Context, url string) error
+}
+
+// AlertService 管理告警规则，定期评估规则并记录和通知告警
+type AlertService struct {
+	alerts   repositories.AlertRepository
+	analyses repositories.SentimentRepository
+	notifier AlertNotifier
+
+	// 后台评估和通知使用的上下文，Stop 超时后取消
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 定期评估协程
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	// 进行中的通知
+	notifications sync.WaitGroup
+}
+
+// NewAlertService 创建告警服务，notifier 为nil时不发送通知
+func NewAlertService(
+	alerts repositories.AlertRepository,
+	analyses repositories.SentimentRepository,
+	notifier AlertNotifier,
+) *AlertService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AlertService{
+		alerts:   alerts,
+		analyses: analyses,
+		notifier: notifier,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// AlertRuleQuery 告警规则的查询条件
+type AlertRuleQuery struct {
+	UserID  string
+	Enabled *bool
+	Limit   int
+	Offset  int
+}
+
+// AlertQuery 告警的查询条件
+type AlertQuery struct {
+	RuleID string
+	Status string
+	Limit  int
+	Offset int
+}
+
+// CreateRule 创建告警规则，规则在下一次评估时生效
+// webhook 地址不可用时返回包装了 ErrInvalidWebhookURL 的错误
+func (s *AlertService) CreateRule(ctx context.Context, rule *models.AlertRule) error {
+	if err := s.checkWebhookURL(ctx, rule.WebhookURL); err != nil {
+		return err
+	}
+
+	if err := s.alerts.CreateRule(ctx, rule); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"rule_id": rule.ID,
+		"name":    rule.Name,
+	}).Info("告警规则已创建")
+	return nil
+}
+
+// GetRule 获取告警规则
+func (s *AlertService) GetRule(ctx context.Context, id string) (*models.AlertRule, error) {
+	rule, err := s.alerts.GetRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, ErrAlertRuleNotFound
+	}
+	return rule, nil
+}
+
+// ListRules 获取告警规则列表，按创建时间倒序
+func (s *AlertService) ListRules(ctx context.Context, query AlertRuleQuery) (*models.AlertRuleListResult, error) {
+	rules, count, err := s.alerts.FindRules(ctx, repositories.FindRulesParams{
+		UserID:  query.UserID,
+		Enabled: query.Enabled,
+		Limit:   query.Limit,
+		Offset:  query.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.AlertRuleListResult{Rules: rules, TotalCount: int(count)}, nil
+}
+
+// UpdateRule 用 rule 替换已有规则的全部可修改字段
+// 规则被停用时，其未解决的告警自动解决；webhook 地址不可用时返回包装了 ErrInvalidWebhookURL 的错误
+func (s *AlertService) UpdateRule(ctx context.Context, id string, rule *models.AlertRule) error {
+	existing, err := s.GetRule(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkWebhookURL(ctx, rule.WebhookURL); err != nil {
+		return err
+	}
+
+	rule.ID = existing.ID
+	rule.CreatedAt = existing.CreatedAt
+	if err := s.alerts.UpdateRule(ctx, rule); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).WithField("rule_id", id).Info("告警规则已更新")
+
+	if !rule.Enabled {
+		return s.resolveActive(ctx, rule, "规则已停用")
+	}
+	return nil
+}
+
+// DeleteRule 删除告警规则并自动解决其未解决的告警，告警记录保留
+func (s *AlertService) DeleteRule(ctx context.Context, id string) error {
+	rule, err := s.GetRule(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.alerts.DeleteRule(ctx, id); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).WithField("rule_id", id).Info("告警规则已删除")
+
+	return s.resolveActive(ctx, rule, "规则已删除")
+}
+
+// checkWebhookURL 校验规则的 webhook 地址，没有通知器时不校验
+func (s *AlertService) checkWebhookURL(ctx context.Context, url string) error {
+	if s.notifier == nil || url == "" {
+		return nil
+	}
+	if err := s.notifier.CheckURL(ctx, url); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhookURL, err)
+	}
+	return nil
+}
+
+// ListAlerts 获取告警列表，按创建时间倒序
+func (s *AlertService) ListAlerts(ctx context.Context, query AlertQuery) (*models.AlertListResult, error) {
+	alerts, count, err := s.alerts.FindAlerts(ctx, repositories.FindAlertsParams{
+		RuleID: query.RuleID,
+		Status: query.Status,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.AlertListResult{Alerts: alerts, TotalCount: int(count)}, nil
+}
+
+// GetAlert 获取告警
+func (s *AlertService) GetAlert(ctx context.Context, id string) (*models.Alert, error) {
+	alert, err := s.alerts.GetAlert(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if alert == nil {
+		return nil, ErrAlertNotFound
+	}
+	return alert, nil
+}
+
+// AcknowledgeAlert 确认告警，只能确认 open 状态的告警
+// 确认后规则不会重复通知，指标恢复时告警仍会自动解决
+func (s *AlertService) AcknowledgeAlert(ctx context.Context, id, by, note string) (*models.Alert, error) {
+	now := time.Now()
+	return s.transition(ctx, id, []string{models.AlertStatusOpen}, models.AlertEventAcknowledged, map[string]interface{}{
+		"status":          models.AlertStatusAcknowledged,
+		"acknowledged_at": now,
+		"acknowledged_by": by,
+		"note":            note,
+	})
+}
+
+// ResolveAlert 手动解决告警，只能解决未解决的告警
+// 解决后规则的冷却时间开始计算，之后指标仍然异常时会产生新的告警
+func (s *AlertService) ResolveAlert(ctx context.Context, id, by, note string) (*models.Alert, error) {
+	now := time.Now()
+	return s.transition(ctx, id, []string{models.AlertStatusOpen, models.AlertStatusAcknowledged}, models.AlertEventResolved, map[string]interface{}{
+		"status":      models.AlertStatusResolved,
+		"resolved_at": now,
+		"resolved_by": by,
+		"note":        note,
+	})
+}
+
+// transition 将处于 from 状态的告警更新为 updates，成功后发送 event 通知
+func (s *AlertService) transition(ctx context.Context, id string, from []string, event string, updates map[string]interface{}) (*models.Alert, error) {
+	if _, err := s.GetAlert(ctx, id); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.alerts.TransitionAlert(ctx, id, from, updates)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrInvalidAlertTransition
+	}
+
+	alert, err := s.GetAlert(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"alert_id": id,
+		"status":   alert.Status,
+	}).Info("告警状态已更新")
+
+	// 规则已删除时不再通知
+	rule, err := s.alerts.GetRule(ctx, alert.RuleID)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("获取告警规则失败，未发送通知")
+	} else if rule != nil {
+		s.notify(ctx, event, rule, alert)
+	}
+
+	return alert, nil
+}
+
+// resolveActive 自动解决规则未解决的告警（如果有）
+func (s *AlertService) resolveActive(ctx context.Context, rule *models.AlertRule, note string) error {
+	active, err := s.alerts.GetActiveAlert(ctx, rule.ID)
+	if err != nil || active == nil {
+		return err
+	}
+	return s.autoResolve(ctx, rule, active, note)
+}
+
+// autoResolve 以 system 身份解决告警并发送通知，告警已被其他请求或实例解决时不做处理
+func (s *AlertService) autoResolve(ctx context.Context, rule *models.AlertRule, active *models.Alert, note string) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      models.AlertStatusResolved,
+		"resolved_at": now,
+		"resolved_by": resolvedBySystem,
+	}
+	// 保留确认时填写的备注
+	if active.Note == "" {
+		updates["note"] = note
+	}
+
+	updated, err := s.alerts.TransitionAlert(ctx, active.ID, []string{models.AlertStatusOpen, models.AlertStatusAcknowledged}, updates)
+	if err != nil || !updated {
+		return err
+	}
+
+	active.Status = models.AlertStatusResolved
+	active.ResolvedAt = &now
+	active.ResolvedBy = resolvedBySystem
+	if active.Note == "" {
+		active.Note = note
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"rule_id":  rule.ID,
+		"alert_id": active.ID,
+		"note":     note,
+	}).Info("告警已自动解决")
+
+	s.notify(ctx, models.AlertEventResolved, rule, active)
+	return nil
+}
+
+// notify 在后台发送告警通知并记录结果，不阻塞调用方
+func (s *AlertService) notify(ctx context.Context, event string, rule *models.AlertRule, alert *models.Alert) {
+	if s.notifier == nil || rule.WebhookURL == "" {
+		return
+	}
+
+	// 通知在请求结束后继续发送，保留请求级日志记录器
+	logger := logging.FromContext(ctx).WithFields(logrus.Fields{
+		"rule_id":  rule.ID,
+		"alert_id": alert.ID,
+		"event":    event,
+	})
+	notifyCtx := logging.WithLogger(s.ctx, logger)
+
+	// 通知发送前告警可能继续变化，发送调用时的快照
+	ruleSnapshot, alertSnapshot := *rule, *alert
+	payload := models.AlertNotification{
+		Event:  event,
+		SentAt: time.Now(),
+		Rule:   &ruleSnapshot,
+		Alert:  &alertSnapshot,
+	}
+
+	s.notifications.Add(1)
+	go func() {
+		defer s.notifications.Done()
+
+		var notifiedAt *time.Time
+		notifyError := ""
+		if err := s.notifier.Send(notifyCtx, rule.WebhookURL, event, payload); err != nil {
+			logger.WithError(err).Error("发送告警通知失败")
+			notifyError = err.Error()
+		} else {
+			now := time.Now()
+			notifiedAt = &now
+		}
+
+		// 关闭超时后 notifyCtx 已取消，结果仍然需要记录
+		if err := s.alerts.UpdateAlertNotification(logging.Detach(notifyCtx), alert.ID, notifiedAt, notifyError); err != nil {
+			logger.WithError(err).Error("记录告警通知结果失败")
+		}
+	}()
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// batchExportPageSize 导出批处理结果时每次读取的记录数
+const batchExportPageSize = 500
+
+// resultColumns 导出时追加在原始列之后的处理状态和分析结果列
+var resultColumns = []string{"status", "error", "sentiment", "score", "keywords"}
+
+// ExportBatchResults 按输入顺序将批处理的每个项目写入 w，格式为 csv 或 jsonl
+// 上传文件产生的批处理按原始列原样输出，并在末尾追加状态、错误、情感、分数和关键词；
+// 其他批处理只输出文本列。失败和跳过的项目也会输出，分析结果列为空。
+// 与原始列同名的追加列加上 result_ 前缀，原始值不会被覆盖。批处理不存在时返回 ErrBatchNotFound
+func (s *SentimentService) ExportBatchResults(ctx context.Context, batchID, format string, w io.Writer) error {
+	if format != UploadFormatCSV && format != UploadFormatJSONL {
+		return fmt.Errorf("不支持的导出格式: %s（可选 csv, jsonl）", format)
+	}
+
+	batch, err := s.repository.GetBatchById(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	if batch == nil {
+		return ErrBatchNotFound
+	}
+
+	textColumn := batch.TextColumn
+	if textColumn == "" {
+		textColumn = "text"
+	}
+	columns := batch.SourceColumns
+	if len(columns) == 0 {
+		columns = []string{textColumn}
+	}
+	extra := exportResultColumns(columns)
+
+	var write func(row map[string]string, result []interface{}) error
+	var flush func() error
+
+	if format == UploadFormatCSV {
+		writer := csv.NewWriter(w)
+		if err := writer.Write(append(append([]string{}, columns...), extra...)); err != nil {
+			return err
+		}
+		write = func(row map[string]string, result []interface{}) error {
+			fields := make([]string, 0, len(columns)+len(extra))
+			for _, column := range columns {
+				fields = append(fields, row[column])
+			}
+			for _, value := range result {
+				fields = append(fields, csvValue(value))
+			}
+			return writer.Write(fields)
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	} else {
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		write = func(row map[string]string, result []interface{}) error {
+			object := make(map[string]interface{}, len(columns)+len(extra))
+			for _, column := range columns {
+				if value, ok := row[column]; ok {
+					object[column] = value
+				}
+			}
+			for i, value := range result {
+				object[extra[i]] = value
+			}
+			return encoder.Encode(object)
+		}
+		flush = func() error { return nil }
+	}
+
+	// 按顺序键集分页，不统计总数；每页的项目数与分析记录是否存在无关，取到空页时结束
+	params := repositories.FindBatchResultsParams{SkipCount: true, Limit: batchExportPageSize}
+	for {
+		items, _, err := s.repository.FindBatchResults(ctx, batchID, params)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		for _, item := range items {
+			if err := write(exportSourceRow(item, textColumn), exportResult(item)); err != nil {
+				return fmt.Errorf("写入导出数据失败: %v", err)
+			}
+		}
+
+		params.Cursor = &repositories.Cursor{Order: items[len(items)-1].Order}
+	}
+
+	return flush()
+}
+
+// exportResultColumns 返回追加列的列名，与原始列同名时加上 result_ 前缀
+func exportResultColumns(columns []string) []string {
+	taken := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		taken[column] = true
+	}
+
+	names := make([]string, len(resultColumns))
+	for i, name := range resultColumns {
+		for taken[name] {
+			name = "result_" + name
+		}
+		taken[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// exportSourceRow 返回项目的原始输入
+// 记录原始输入之前存储的项目没有原始值，用分析记录的文本和元数据代替
+func exportSourceRow(item repositories.BatchResultItem, textColumn string) map[string]string {
+	if item.SourceRow != nil {
+		return item.SourceRow
+	}
+	if item.Analysis == nil {
+		return nil
+	}
+
+	record := toAnalysisRecord(item.Analysis)
+	row := make(map[string]string, len(record.Metadata)+1)
+	for key, value := range record.Metadata {
+		row[key] = value
+	}
+	row[textColumn] = record.Text
+	return row
+}
+
+// exportResult 返回项目的追加列的值，顺序与 resultColumns 一致
+func exportResult(item repositories.BatchResultItem) []interface{} {
+	status, message := item.Status, item.Error
+	if item.Analysis == nil {
+		if status == models.BatchItemStatusSucceeded {
+			message = "分析记录已删除"
+		}
+		return []interface{}{status, message, nil, nil, nil}
+	}
+
+	record := toAnalysisRecord(item.Analysis)
+	return []interface{}{status, message, record.Sentiment, record.Score, record.Keywords}
+}
+
+// csvValue 将追加列的值格式化为CSV单元格
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', 4, 64)
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
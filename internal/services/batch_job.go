@@ -0,0 +1,486 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+)
+
+// ErrBatchTooLarge 异步批处理的文本数超过上限
+var ErrBatchTooLarge = errors.New("批处理文本数超过上限")
+
+// defaultChunkSize 未配置分块大小时使用的默认值
+const defaultChunkSize = 500
+
+// BatchInput 批处理中的单个输入
+type BatchInput struct {
+	// ID 调用方提供的项目ID，为空时不记录
+	ID       string
+	Text     string
+	Metadata map[string]string
+	// Row 上传文件中该行的原始值（含文本列，未去除空白），导出结果时原样输出；其他输入为nil
+	Row map[string]string
+}
+
+// BatchSource 按顺序提供批处理输入，读完后返回 io.EOF
+type BatchSource interface {
+	Next() (BatchInput, error)
+	Close() error
+}
+
+// sliceSource 基于内存切片的批处理输入
+type sliceSource struct {
+	inputs []BatchInput
+	next   int
+}
+
+// NewInputSource 创建基于输入列表的批处理输入
+func NewInputSource(inputs []BatchInput) BatchSource {
+	return &sliceSource{inputs: inputs}
+}
+
+// Next 返回下一个输入
+func (s *sliceSource) Next() (BatchInput, error) {
+	if s.next >= len(s.inputs) {
+		return BatchInput{}, io.EOF
+	}
+	input := s.inputs[s.next]
+	s.next++
+	return input, nil
+}
+
+// Close 无需释放资源
+func (s *sliceSource) Close() error {
+	return nil
+}
+
+// BatchJob 异步批处理任务
+type BatchJob struct {
+	// Source 输入来源，任务结束后关闭
+	Source BatchSource
+	// Count 输入总数，未知时为0（用于进度和预计完成时间）
+	Count int
+	// Language 文本语言
+	Language string
+	// Metadata 批处理级别的元数据，附加到每个结果上
+	Metadata map[string]string
+	// Upload 来自上传文件时的文件信息，用于按原始列导出结果
+	Upload *Upload
+}
+
+// batchJobs 跟踪后台运行的批处理任务
+type batchJobs struct {
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 本实例正在处理的批处理（含同步批处理），清理协程定期刷新其更新时间
+	mu     sync.Mutex
+	active map[string]struct{}
+
+	// 定期清理协程
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// newBatchJobs 创建批处理任务跟踪器
+func newBatchJobs() *batchJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &batchJobs{ctx: ctx, cancel: cancel, active: make(map[string]struct{})}
+}
+
+// track 记录本实例开始处理批处理
+func (j *batchJobs) track(batchID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.active[batchID] = struct{}{}
+}
+
+// untrack 记录本实例不再处理批处理
+func (j *batchJobs) untrack(batchID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.active, batchID)
+}
+
+// activeIDs 返回本实例正在处理的批处理ID
+func (j *batchJobs) activeIDs() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	ids := make([]string, 0, len(j.active))
+	for id := range j.active {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// isActive 返回本实例是否正在处理批处理
+func (j *batchJobs) isActive(batchID string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, ok := j.active[batchID]
+	return ok
+}
+
+// SubmitBatch 创建批处理记录并在后台分块处理，立即返回批处理状态
+// 结果总是被存储，可通过批处理查询接口获取进度和结果
+func (s *SentimentService) SubmitBatch(ctx context.Context, job BatchJob) (*models.BatchRecord, error) {
+	if s.shuttingDown.Load() {
+		job.Source.Close()
+		return nil, ErrShuttingDown
+	}
+
+	opts := s.currentOptions()
+	if opts.MaxAsyncTexts > 0 && job.Count > opts.MaxAsyncTexts {
+		job.Source.Close()
+		return nil, fmt.Errorf("%w（最多 %d 个）", ErrBatchTooLarge, opts.MaxAsyncTexts)
+	}
+
+	batch := &models.BatchAnalysis{
+		ID:     uuid.New().String(),
+		Count:  job.Count,
+		Status: models.BatchStatusPending,
+	}
+	if userID, ok := job.Metadata["user_id"]; ok {
+		batch.UserID = userID
+	}
+	if job.Upload != nil {
+		batch.SourceFormat = job.Upload.Format
+		batch.TextColumn = job.Upload.TextColumn
+		batch.SourceColumns = job.Upload.Columns
+	}
+
+	if err := s.repository.CreateBatchAnalysis(ctx, batch); err != nil {
+		job.Source.Close()
+		return nil, fmt.Errorf("创建批处理记录失败: %v", err)
+	}
+
+	// 任务在请求结束后继续运行，保留请求级日志记录器
+	logger := logging.FromContext(ctx).WithField("batch_id", batch.ID)
+	jobCtx := logging.WithLogger(s.jobs.ctx, logger)
+
+	// 任务启动后会修改批处理记录，先取快照
+	record := toBatchRecord(batch)
+
+	s.jobs.track(batch.ID)
+	s.jobs.wg.Add(1)
+	go s.runBatchJob(jobCtx, batch, job)
+
+	logger.WithField("count", job.Count).Info("异步批处理已提交")
+
+	return &record, nil
+}
+
+// runBatchJob 分块处理批处理输入并持续更新进度
+func (s *SentimentService) runBatchJob(ctx context.Context, batch *models.BatchAnalysis, job BatchJob) {
+	defer s.jobs.wg.Done()
+	defer s.jobs.untrack(batch.ID)
+	defer job.Source.Close()
+
+	logger := logging.FromContext(ctx)
+	// 进度写入使用不会被取消的上下文，保证中断时也能记录最终状态
+	storeCtx := logging.Detach(ctx)
+
+	startedAt := time.Now()
+	batch.Status = models.BatchStatusProcessing
+	batch.StartedAt = &startedAt
+	s.saveBatchProgress(storeCtx, batch)
+
+	// 每轮读取的输入足够所有流并发处理，内存占用与总数无关
+	opts := s.currentOptions()
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	if opts.Streams > 1 {
+		chunkSize *= opts.Streams
+	}
+
+	interrupted := false
+	for {
+		// 关闭过程中不再开始新的分块
+		if s.shuttingDown.Load() || ctx.Err() != nil {
+			interrupted = true
+			break
+		}
+
+		chunk, readErr := readChunk(job.Source, chunkSize)
+		if len(chunk) > 0 {
+			succeeded, skipped, err := s.processChunk(ctx, storeCtx, batch.ID, chunk, batch.ProcessedCount, job)
+			if err != nil {
+				logger.WithError(err).Error("处理批处理分块失败")
+				batch.Error = err.Error()
+			}
+
+			batch.ProcessedCount += len(chunk)
+			batch.SkippedCount += skipped
+			batch.FailedCount += len(chunk) - succeeded - skipped
+			if batch.Count < batch.ProcessedCount {
+				batch.Count = batch.ProcessedCount
+			}
+			batch.EstimatedAt = estimateCompletion(startedAt, batch.ProcessedCount, batch.Count)
+			s.saveBatchProgress(storeCtx, batch)
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			logger.WithError(readErr).Error("读取批处理输入失败")
+			batch.Error = fmt.Sprintf("读取输入失败: %v", readErr)
+			break
+		}
+	}
+
+	if interrupted {
+		batch.Error = "服务关闭，批处理被中断"
+	}
+
+	completedAt := time.Now()
+	finishBatch(batch, completedAt)
+	s.saveBatchProgress(storeCtx, batch)
+
+	logger.WithFields(logrus.Fields{
+		"status":     batch.Status,
+		"count":      batch.Count,
+		"processed":  batch.ProcessedCount,
+		"failed":     batch.FailedCount,
+		"skipped":    batch.SkippedCount,
+		"elapsed_ms": completedAt.Sub(startedAt).Milliseconds(),
+	}).Info("异步批处理已结束")
+}
+
+// readChunk 从输入中读取最多 size 个输入
+func readChunk(source BatchSource, size int) ([]BatchInput, error) {
+	chunk := make([]BatchInput, 0, size)
+	for len(chunk) < size {
+		input, err := source.Next()
+		if err != nil {
+			return chunk, err
+		}
+		chunk = append(chunk, input)
+	}
+	return chunk, nil
+}
+
+// processChunk 分析一个分块并在一个事务中存储结果，返回成功存储和跳过的数量
+// 部分项目失败时同时返回第一个失败原因
+func (s *SentimentService) processChunk(
+	ctx context.Context,
+	storeCtx context.Context,
+	batchID string,
+	chunk []BatchInput,
+	startOrder int,
+	job BatchJob,
+) (int, int, error) {
+	results := s.analyzeItems(ctx, chunk, job.Language, nil)
+	s.storeBatchResults(storeCtx, batchID, chunk, results, startOrder, job.Language, job.Metadata)
+
+	summary := summarizeItems(results)
+	if message := firstItemError(results); message != "" {
+		return summary.Succeeded, summary.Skipped, errors.New(message)
+	}
+	return summary.Succeeded, summary.Skipped, nil
+}
+
+// mergeMetadata 合并批处理级别和输入级别的元数据，输入级别优先
+func mergeMetadata(batch, item map[string]string) map[string]string {
+	if len(item) == 0 {
+		return batch
+	}
+
+	merged := make(map[string]string, len(batch)+len(item))
+	for k, v := range batch {
+		merged[k] = v
+	}
+	for k, v := range item {
+		merged[k] = v
+	}
+	return merged
+}
+
+// estimateCompletion 根据已用时间和进度估算完成时间，总数未知时返回nil
+func estimateCompletion(startedAt time.Time, processed, total int) *time.Time {
+	if processed <= 0 || total <= processed {
+		return nil
+	}
+
+	elapsed := time.Since(startedAt)
+	remaining := time.Duration(float64(elapsed) / float64(processed) * float64(total-processed))
+	eta := time.Now().Add(remaining)
+	return &eta
+}
+
+// finalBatchStatus 根据失败数量确定批处理的最终状态，跳过的项目不计入失败
+func finalBatchStatus(count, failed, skipped int) string {
+	switch {
+	case failed == 0:
+		return models.BatchStatusCompleted
+	case failed >= count-skipped:
+		return models.BatchStatusFailed
+	default:
+		return models.BatchStatusPartiallyFailed
+	}
+}
+
+// finishBatch 设置批处理的最终状态，未处理的输入计为失败
+func finishBatch(batch *models.BatchAnalysis, completedAt time.Time) {
+	if remaining := batch.Count - batch.ProcessedCount; remaining > 0 {
+		batch.FailedCount += remaining
+	}
+	batch.CompletedAt = &completedAt
+	batch.EstimatedAt = nil
+	batch.Status = finalBatchStatus(batch.Count, batch.FailedCount, batch.SkippedCount)
+}
+
+// saveBatchProgress 持久化批处理进度，失败只记录日志
+func (s *SentimentService) saveBatchProgress(ctx context.Context, batch *models.BatchAnalysis) {
+	if err := s.repository.UpdateBatchProgress(ctx, batch); err != nil {
+		logging.FromContext(ctx).WithError(err).Error("更新批处理进度失败")
+	}
+}
+
+// batchJobCancelGrace 取消批处理任务后等待其记录最终状态的最长时间
+const batchJobCancelGrace = 5 * time.Second
+
+// DrainBatchJobs 等待后台批处理任务结束（关闭过程中任务不会开始新的分块）
+// 在 ctx 的截止时间之前取消进行中的分块，并在截止时间内等待任务记录最终状态，返回是否所有任务都已正常结束
+func (s *SentimentService) DrainBatchJobs(ctx context.Context) bool {
+	// 任务结束前继续刷新其更新时间；超时放弃的任务由其他实例或下次启动时清理
+	defer s.stopBatchJanitor()
+
+	done := make(chan struct{})
+	go func() {
+		s.jobs.wg.Wait()
+		close(done)
+	}()
+
+	// 从截止时间中预留取消后的等待时间（最多剩余时间的一半），保证总时长不超过截止时间
+	grace := batchJobCancelGrace
+	waitCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		if half := time.Until(deadline) / 2; half < grace {
+			grace = half
+		}
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithDeadline(ctx, deadline.Add(-grace))
+		defer cancel()
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-waitCtx.Done():
+	}
+
+	// 取消进行中的gRPC流，给任务一点时间记录最终状态
+	s.jobs.cancel()
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+	return false
+}
+
+// BatchHeartbeatInterval 刷新本实例未结束批处理的更新时间并清理中断批处理的默认间隔
+const BatchHeartbeatInterval = 30 * time.Second
+
+// batchStaleHeartbeats 未结束的批处理超过这么多个间隔未更新时，视为处理它的实例已退出
+const batchStaleHeartbeats = 10
+
+// errBatchInterrupted 被清理的批处理记录的错误
+const errBatchInterrupted = "处理批处理的服务实例已退出，批处理被中断"
+
+// StartBatchJanitor 立即并在之后每隔 interval 刷新本实例未结束批处理的更新时间，
+// 并将长时间未更新、不属于任何运行中实例的批处理（进程崩溃或关闭超时遗留）记录为中断
+// 多个实例共享数据库时各自刷新自己的批处理，不会清理其他实例正在处理的批处理
+func (s *SentimentService) StartBatchJanitor(interval time.Duration) {
+	s.jobs.stop = make(chan struct{})
+	s.jobs.stopped = make(chan struct{})
+	staleAfter := interval * batchStaleHeartbeats
+
+	go func() {
+		defer close(s.jobs.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.sweepBatches(s.jobs.ctx, time.Now().Add(-staleAfter))
+
+			select {
+			case <-s.jobs.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	logrus.WithFields(logrus.Fields{
+		"interval":    interval,
+		"stale_after": staleAfter,
+	}).Info("批处理清理已启动")
+}
+
+// stopBatchJanitor 停止定期清理并等待进行中的清理结束
+func (s *SentimentService) stopBatchJanitor() {
+	if s.jobs.stop == nil {
+		return
+	}
+	s.jobs.once.Do(func() { close(s.jobs.stop) })
+	<-s.jobs.stopped
+}
+
+// sweepBatches 刷新本实例未结束批处理的更新时间，并将更新时间早于 staleBefore 的其他未结束批处理记录为中断
+func (s *SentimentService) sweepBatches(ctx context.Context, staleBefore time.Time) {
+	logger := logging.FromContext(ctx)
+
+	if err := s.repository.TouchBatches(ctx, s.jobs.activeIDs()); err != nil {
+		logger.WithError(err).Error("刷新批处理更新时间失败")
+	}
+
+	stale, err := s.repository.FindStaleBatches(ctx, staleBefore)
+	if err != nil {
+		logger.WithError(err).Error("获取中断的批处理失败")
+		return
+	}
+
+	for _, batch := range stale {
+		// 刚开始处理的批处理可能在刷新之后才被记录
+		if s.jobs.isActive(batch.ID) {
+			continue
+		}
+
+		batch.Error = errBatchInterrupted
+		finishBatch(batch, time.Now())
+
+		batchLogger := logger.WithFields(logrus.Fields{
+			"batch_id":   batch.ID,
+			"status":     batch.Status,
+			"count":      batch.Count,
+			"processed":  batch.ProcessedCount,
+			"failed":     batch.FailedCount,
+			"updated_at": batch.UpdatedAt,
+		})
+		updated, err := s.repository.MarkBatchInterrupted(ctx, batch, staleBefore)
+		if err != nil {
+			batchLogger.WithError(err).Error("记录中断的批处理失败")
+			continue
+		}
+		if updated {
+			batchLogger.Warn("批处理长时间未更新，已记录为中断")
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+func TestFinalBatchStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		failed  int
+		skipped int
+		want    string
+	}{
+		{"全部成功", 10, 0, 0, models.BatchStatusCompleted},
+		{"只有跳过的项目", 10, 0, 3, models.BatchStatusCompleted},
+		{"部分失败", 10, 4, 0, models.BatchStatusPartiallyFailed},
+		{"全部失败", 10, 10, 0, models.BatchStatusFailed},
+		{"未跳过的项目全部失败", 10, 7, 3, models.BatchStatusFailed},
+		{"未跳过的项目部分失败", 10, 6, 3, models.BatchStatusPartiallyFailed},
+		{"空批处理", 0, 0, 0, models.BatchStatusCompleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := finalBatchStatus(tt.count, tt.failed, tt.skipped); got != tt.want {
+				t.Errorf("finalBatchStatus(%d, %d, %d) = %q，期望 %q", tt.count, tt.failed, tt.skipped, got, tt.want)
+			}
+		})
+	}
+}
+
+// staleBatchRepository 返回固定的长时间未更新批处理，并记录刷新和标记中断的调用
+type staleBatchRepository struct {
+	repositories.SentimentRepository
+	stale   []*models.BatchAnalysis
+	touched []string
+	marked  map[string]*models.BatchAnalysis
+	before  time.Time
+}
+
+func (r *staleBatchRepository) TouchBatches(_ context.Context, ids []string) error {
+	r.touched = append(r.touched, ids...)
+	return nil
+}
+
+func (r *staleBatchRepository) FindStaleBatches(_ context.Context, before time.Time) ([]*models.BatchAnalysis, error) {
+	r.before = before
+	return r.stale, nil
+}
+
+func (r *staleBatchRepository) MarkBatchInterrupted(_ context.Context, batch *models.BatchAnalysis, before time.Time) (bool, error) {
+	if !before.Equal(r.before) {
+		return false, nil
+	}
+	r.marked[batch.ID] = batch
+	return true, nil
+}
+
+func TestSweepBatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		batch  models.BatchAnalysis
+		active bool
+		// 期望的最终状态和失败数，为空表示不应被标记
+		status string
+		failed int
+	}{
+		{
+			name:   "处理中途中断",
+			batch:  models.BatchAnalysis{ID: "partial", Count: 10, ProcessedCount: 4, FailedCount: 1, Status: models.BatchStatusProcessing},
+			status: models.BatchStatusPartiallyFailed,
+			failed: 7,
+		},
+		{
+			name:   "未处理任何输入",
+			batch:  models.BatchAnalysis{ID: "none", Count: 5, Status: models.BatchStatusProcessing},
+			status: models.BatchStatusFailed,
+			failed: 5,
+		},
+		{
+			name:   "创建后未开始",
+			batch:  models.BatchAnalysis{ID: "pending", Count: 3, Status: models.BatchStatusPending},
+			status: models.BatchStatusFailed,
+			failed: 3,
+		},
+		{
+			name:   "跳过的项目不计入失败",
+			batch:  models.BatchAnalysis{ID: "skipped", Count: 6, ProcessedCount: 2, SkippedCount: 2, Status: models.BatchStatusProcessing},
+			status: models.BatchStatusFailed,
+			failed: 4,
+		},
+		{
+			name:   "全部处理后未记录最终状态",
+			batch:  models.BatchAnalysis{ID: "done", Count: 4, ProcessedCount: 4, Status: models.BatchStatusProcessing},
+			status: models.BatchStatusCompleted,
+		},
+		{
+			name:   "本实例正在处理",
+			batch:  models.BatchAnalysis{ID: "local", Count: 8, ProcessedCount: 2, Status: models.BatchStatusProcessing},
+			active: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := tt.batch
+			repo := &staleBatchRepository{
+				stale:  []*models.BatchAnalysis{&batch},
+				marked: make(map[string]*models.BatchAnalysis),
+			}
+			s := NewSentimentService(repo, nil, nil)
+			s.jobs.track("running")
+			if tt.active {
+				s.jobs.track(batch.ID)
+			}
+
+			s.sweepBatches(context.Background(), time.Now().Add(-time.Minute))
+
+			// 本实例的批处理总是被刷新
+			if !tt.active && !reflect.DeepEqual(repo.touched, []string{"running"}) {
+				t.Errorf("刷新的批处理 = %v，期望 [running]", repo.touched)
+			}
+
+			marked, ok := repo.marked[batch.ID]
+			if tt.status == "" {
+				if ok {
+					t.Fatalf("本实例正在处理的批处理被标记为 %q", marked.Status)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("批处理没有被标记为中断")
+			}
+			if marked.Status != tt.status || marked.FailedCount != tt.failed {
+				t.Errorf("状态, 失败数 = %q, %d，期望 %q, %d", marked.Status, marked.FailedCount, tt.status, tt.failed)
+			}
+			if marked.Error != errBatchInterrupted || marked.CompletedAt == nil {
+				t.Errorf("Error = %q, CompletedAt = %v", marked.Error, marked.CompletedAt)
+			}
+		})
+	}
+}
+
+func TestBatchJanitorStopsWithDrain(t *testing.T) {
+	repo := &staleBatchRepository{marked: make(map[string]*models.BatchAnalysis)}
+	s := NewSentimentService(repo, nil, nil)
+
+	s.StartBatchJanitor(time.Hour)
+	if !s.DrainBatchJobs(context.Background()) {
+		t.Fatal("没有进行中的任务时 DrainBatchJobs() 返回 false")
+	}
+
+	// 清理协程已退出，重复停止不会阻塞
+	select {
+	case <-s.jobs.stopped:
+	default:
+		t.Error("DrainBatchJobs() 返回后清理协程仍在运行")
+	}
+	s.stopBatchJanitor()
+}
+
+func TestDrainBatchJobsWithinDeadline(t *testing.T) {
+	s := NewSentimentService(nil, nil, nil)
+
+	// 模拟取消后仍不结束的任务，记录被取消的时间
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	cancelled := make(chan time.Time, 1)
+	s.jobs.wg.Add(1)
+	go func() {
+		defer s.jobs.wg.Done()
+		<-s.jobs.ctx.Done()
+		cancelled <- time.Now()
+		<-release
+	}()
+
+	timeout := 200 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	if s.DrainBatchJobs(ctx) {
+		t.Fatal("任务未结束时 DrainBatchJobs() 返回 true")
+	}
+	// 取消后的等待时间从截止时间中预留，不会超过截止时间
+	if late := time.Since(deadline); late > 50*time.Millisecond {
+		t.Errorf("DrainBatchJobs() 在截止时间之后 %v 才返回", late)
+	}
+	select {
+	case at := <-cancelled:
+		if !at.Before(deadline) {
+			t.Errorf("任务在截止时间之后才被取消（晚 %v）", at.Sub(deadline))
+		}
+	default:
+		t.Error("超时后任务没有被取消")
+	}
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	sentimentv1 "sentiment-service/internal/gen/sentiment/v1"
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+)
+
+// batchRetryAttempts 流中断后对缺少结果的项目重试的次数
+const batchRetryAttempts = 1
+
+// analyzeItems 分析一组输入并返回每个项目的处理结果，顺序与输入一致
+// 文本为空的项目被跳过；流中断导致部分项目缺少结果时，为这些项目重新建立流重试一次。
+// onItem 不为nil时，每个项目得到最终状态后立即调用一次（可能并发调用），可以修改项目的状态
+func (s *SentimentService) analyzeItems(
+	ctx context.Context,
+	inputs []BatchInput,
+	language string,
+	onItem func(index int, item *models.BatchItemResult),
+) []models.BatchItemResult {
+	logger := logging.FromContext(ctx)
+
+	items := make([]models.BatchItemResult, len(inputs))
+	var pending []int
+	for i, input := range inputs {
+		items[i] = models.BatchItemResult{
+			ItemID:   input.ID,
+			Text:     input.Text,
+			Metadata: input.Metadata,
+		}
+		if strings.TrimSpace(input.Text) == "" {
+			items[i].Status = models.BatchItemStatusSkipped
+			items[i].Error = "文本为空"
+			if onItem != nil {
+				onItem(i, &items[i])
+			}
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		attemptInputs := make([]BatchInput, len(pending))
+		for n, i := range pending {
+			attemptInputs[n] = inputs[i]
+		}
+
+		attemptPending := pending
+		results, err := s.analyzeInputs(ctx, attemptInputs, language, func(n int, result *models.SentimentResult) {
+			i := attemptPending[n]
+			items[i].Status = models.BatchItemStatusSucceeded
+			items[i].Error = ""
+			items[i].Result = result
+			if onItem != nil {
+				onItem(i, &items[i])
+			}
+		})
+		if err == nil {
+			err = errors.New("未收到分析结果")
+		}
+
+		var failed []int
+		for n, i := range pending {
+			if results[n] == nil {
+				items[i].Status = models.BatchItemStatusFailed
+				items[i].Error = err.Error()
+				failed = append(failed, i)
+			}
+		}
+
+		if len(failed) == 0 || attempt >= batchRetryAttempts || ctx.Err() != nil {
+			// 重试后仍然失败的项目
+			if onItem != nil {
+				for _, i := range failed {
+					onItem(i, &items[i])
+				}
+			}
+			break
+		}
+
+		logger.WithError(err).WithFields(logrus.Fields{
+			"failed": len(failed),
+			"total":  len(inputs),
+		}).Warn("部分批处理项目分析失败，重试剩余项目")
+		pending = failed
+	}
+
+	return items
+}
+
+// summarizeItems 统计各状态的项目数
+func summarizeItems(items []models.BatchItemResult) models.BatchSummary {
+	summary := models.BatchSummary{Total: len(items)}
+	for _, item := range items {
+		switch item.Status {
+		case models.BatchItemStatusSucceeded:
+			summary.Succeeded++
+		case models.BatchItemStatusSkipped:
+			summary.Skipped++
+		default:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// firstItemError 返回第一个失败项目的错误，没有失败时返回空字符串
+func firstItemError(items []models.BatchItemResult) string {
+	for _, item := range items {
+		if item.Status == models.BatchItemStatusFailed {
+			return item.Error
+		}
+	}
+	return ""
+}
+
+// 未配置时使用的流参数
+const (
+	defaultStreams     = 1
+	defaultMaxInFlight = 100
+)
+
+// analyzeInputs 分析一组输入，返回与输入一一对应的结果
+// 相同的文本只发送一次，结果复制给所有相同文本的输入，每个输入仍有自己的请求ID；
+// 唯一文本按 ChunkSize 分块，最多 Streams 个gRPC流并发处理。
+// 某个流中断时其他流照常完成，未收到结果的位置为nil，同时返回第一个错误。
+// onResult 不为nil时，每个位置收到结果后立即调用一次（可能并发调用）
+func (s *SentimentService) analyzeInputs(
+	ctx context.Context,
+	inputs []BatchInput,
+	language string,
+	onResult func(index int, result *models.SentimentResult),
+) ([]*models.SentimentResult, error) {
+	opts := s.currentOptions()
+	results := make([]*models.SentimentResult, len(inputs))
+
+	// 按文本去重，记录每个唯一文本对应的输入位置
+	positions := make(map[string][]int, len(inputs))
+	var uniqueTexts []string
+	for i, input := range inputs {
+		if _, ok := positions[input.Text]; !ok {
+			uniqueTexts = append(uniqueTexts, input.Text)
+		}
+		positions[input.Text] = append(positions[input.Text], i)
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	streams := opts.Streams
+	if streams <= 0 {
+		streams = defaultStreams
+	}
+	maxInFlight := opts.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlight
+	}
+
+	// 超时覆盖整个批量分析，而不是单个流
+	streamCtx, cancel := withTimeout(ctx, opts.BatchTimeout)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	slots := make(chan struct{}, streams)
+
+	// fill 将一个唯一文本的结果复制给所有相同文本的输入
+	fill := func(text string, resp *sentimentv1.SentimentResponse) {
+		mu.Lock()
+		now := time.Now()
+		for n, i := range positions[text] {
+			// 第一个输入使用流请求ID，重复文本的输入各自生成请求ID（存储时请求ID必须唯一）
+			requestID := resp.RequestId
+			if n > 0 {
+				requestID = uuid.New().String()
+			}
+
+			results[i] = &models.SentimentResult{
+				ItemID:           inputs[i].ID,
+				Text:             text,
+				Sentiment:        resp.Sentiment,
+				Score:            resp.Score,
+				ConfidenceScores: resp.ConfidenceScores,
+				Keywords:         resp.Keywords,
+				RequestID:        requestID,
+				Metadata:         inputs[i].Metadata,
+				Timestamp:        now,
+			}
+		}
+		mu.Unlock()
+
+		// 回调可能较慢（例如写入数据库），不持有锁
+		if onResult != nil {
+			for _, i := range positions[text] {
+				onResult(i, results[i])
+			}
+		}
+	}
+
+	for start := 0; start < len(uniqueTexts); start += chunkSize {
+		end := start + chunkSize
+		if end > len(uniqueTexts) {
+			end = len(uniqueTexts)
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(texts []string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := s.analyzeStream(streamCtx, texts, language, maxInFlight, fill); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(uniqueTexts[start:end])
+	}
+	wg.Wait()
+
+	return results, firstErr
+}
+
+// analyzeStream 通过一个gRPC流分析一组互不相同的文本
+// 发送和接收同时进行，已发送但未收到结果的请求不超过 maxInFlight 个；
+// 每收到一个结果调用一次 onResult，流中断时返回错误并注明缺少的结果数
+func (s *SentimentService) analyzeStream(
+	ctx context.Context,
+	texts []string,
+	language string,
+	maxInFlight int,
+	onResult func(text string, resp *sentimentv1.SentimentResponse),
+) error {
+	logger := logging.FromContext(ctx)
+
+	// 接收失败时取消流，使阻塞的发送立即返回
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := s.grpcClient.BatchAnalyzeStream(ctx)
+	if err != nil {
+		return fmt.Errorf("创建gRPC流失败: %v", err)
+	}
+
+	// 流请求ID到文本的映射，发送和接收在不同的协程中访问
+	var mu sync.Mutex
+	pending := make(map[string]string, len(texts))
+
+	inFlight := make(chan struct{}, maxInFlight)
+	stop := make(chan struct{})
+	sendDone := make(chan error, 1)
+
+	go func() {
+		for _, text := range texts {
+			select {
+			case inFlight <- struct{}{}:
+			case <-stop:
+				sendDone <- nil
+				return
+			}
+
+			requestID := uuid.New().String()
+			mu.Lock()
+			pending[requestID] = text
+			mu.Unlock()
+
+			err := stream.Send(&sentimentv1.SentimentRequest{
+				Text:      text,
+				Language:  language,
+				RequestId: requestID,
+			})
+			if err != nil {
+				sendDone <- fmt.Errorf("发送流请求失败: %v", err)
+				return
+			}
+		}
+
+		if err := stream.CloseSend(); err != nil {
+			sendDone <- fmt.Errorf("关闭发送流失败: %v", err)
+			return
+		}
+		sendDone <- nil
+	}()
+
+	var recvErr error
+	for received := 0; received < len(texts); {
+		resp, err := stream.Recv()
+		if err != nil {
+			recvErr = fmt.Errorf("接收流响应失败（缺少 %d 个结果）: %v", len(texts)-received, err)
+			break
+		}
+
+		mu.Lock()
+		text, ok := pending[resp.RequestId]
+		delete(pending, resp.RequestId)
+		mu.Unlock()
+
+		if !ok {
+			logger.WithField("request_id", resp.RequestId).Warn("收到未知请求ID的响应，已忽略")
+			continue
+		}
+
+		received++
+		<-inFlight
+		onResult(text, resp)
+	}
+
+	close(stop)
+	if recvErr != nil {
+		cancel()
+	}
+	sendErr := <-sendDone
+
+	if recvErr != nil {
+		return recvErr
+	}
+	return sendErr
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidUpload 上传的文件格式无效
+var ErrInvalidUpload = errors.New("上传文件无效")
+
+// 上传文件格式
+const (
+	UploadFormatCSV   = "csv"
+	UploadFormatJSONL = "jsonl"
+)
+
+// maxColumnNameLength 列名会作为元数据键存储，长度受 analysis_metadata.key 限制
+const maxColumnNameLength = 50
+
+// maxJSONLLineSize JSONL 单行的最大长度
+const maxJSONLLineSize = 1 << 20
+
+// UploadFormatFromFilename 根据文件扩展名推断上传格式，无法识别时返回空字符串
+func UploadFormatFromFilename(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return UploadFormatCSV
+	case ".jsonl", ".ndjson":
+		return UploadFormatJSONL
+	default:
+		return ""
+	}
+}
+
+// Upload 已暂存并校验过的上传文件
+type Upload struct {
+	// Format 文件格式
+	Format string
+	// TextColumn 文本所在的列（CSV）或字段（JSONL）
+	TextColumn string
+	// Columns 文件中的列，CSV按原始顺序，JSONL按首次出现的顺序
+	Columns []string
+	// Count 数据行数，文本为空的行也计入（处理时标记为跳过）
+	Count int
+
+	path string
+}
+
+// SpoolUpload 将上传内容写入临时文件并完整校验一遍，返回可作为批处理输入的上传文件
+// 校验失败时返回包装了 ErrInvalidUpload 的错误，并指出出错的行号
+// 调用方必须通过 Source().Close() 或 Discard() 删除临时文件
+func SpoolUpload(r io.Reader, format, textColumn string) (*Upload, error) {
+	if format != UploadFormatCSV && format != UploadFormatJSONL {
+		return nil, fmt.Errorf("%w: 不支持的格式 %q（可选 csv, jsonl）", ErrInvalidUpload, format)
+	}
+	if textColumn == "" {
+		textColumn = "text"
+	}
+
+	file, err := os.CreateTemp("", "sentiment-upload-*."+format)
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+
+	upload := &Upload{Format: format, TextColumn: textColumn, path: file.Name()}
+
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		upload.Discard()
+		return nil, fmt.Errorf("保存上传文件失败: %v", err)
+	}
+
+	// 完整读取一遍：校验格式、统计行数并收集列名
+	source, err := upload.open()
+	if err != nil {
+		upload.Discard()
+		return nil, err
+	}
+	defer source.Close()
+
+	texts := 0
+	for {
+		input, err := source.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			upload.Discard()
+			return nil, err
+		}
+		upload.Count++
+		if input.Text != "" {
+			texts++
+		}
+	}
+	if texts == 0 {
+		upload.Discard()
+		return nil, fmt.Errorf("%w: 上传文件中没有可分析的文本", ErrInvalidUpload)
+	}
+	upload.Columns = source.columns()
+
+	return upload, nil
+}
+
+// Source 返回按行读取上传文件的批处理输入，关闭时删除临时文件
+func (u *Upload) Source() (BatchSource, error) {
+	source, err := u.open()
+	if err != nil {
+		return nil, err
+	}
+	return &removeOnClose{uploadSource: source, path: u.path}, nil
+}
+
+// Discard 删除临时文件
+func (u *Upload) Discard() {
+	os.Remove(u.path)
+}
+
+// open 打开临时文件并创建对应格式的读取器
+func (u *Upload) open() (uploadSource, error) {
+	file, err := os.Open(u.path)
+	if err != nil {
+		return nil, fmt.Errorf("打开上传文件失败: %v", err)
+	}
+
+	var source uploadSource
+	if u.Format == UploadFormatCSV {
+		source, err = newCSVSource(file, u.TextColumn)
+	} else {
+		source = newJSONLSource(file, u.TextColumn)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return source, nil
+}
+
+// uploadSource 上传文件的批处理输入，额外提供列名
+type uploadSource interface {
+	BatchSource
+	columns() []string
+}
+
+// removeOnClose 关闭时删除临时文件
+type removeOnClose struct {
+	uploadSource
+	path string
+}
+
+// Close 关闭文件并删除临时文件
+func (s *removeOnClose) Close() error {
+	err := s.uploadSource.Close()
+	os.Remove(s.path)
+	return err
+}
+
+// csvSource 逐行读取CSV，文本列作为文本，其余列作为元数据
+type csvSource struct {
+	file      *os.File
+	reader    *csv.Reader
+	header    []string
+	textIndex int
+}
+
+// newCSVSource 读取表头并定位文本列
+func newCSVSource(file *os.File, textColumn string) (*csvSource, error) {
+	reader := csv.NewReader(bufio.NewReader(file))
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: CSV文件为空", ErrInvalidUpload)
+		}
+		return nil, fmt.Errorf("%w: 读取CSV表头失败: %v", ErrInvalidUpload, err)
+	}
+
+	// 去除Excel导出的UTF-8 BOM
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	textIndex := -1
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		if name == "" || len(name) > maxColumnNameLength {
+			return nil, fmt.Errorf("%w: 第 %d 列的列名为空或超过 %d 个字符", ErrInvalidUpload, i+1, maxColumnNameLength)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: 列名重复: %s", ErrInvalidUpload, name)
+		}
+		seen[name] = true
+
+		if name == textColumn {
+			textIndex = i
+		}
+	}
+	if textIndex < 0 {
+		return nil, fmt.Errorf("%w: CSV中没有文本列 %q", ErrInvalidUpload, textColumn)
+	}
+
+	return &csvSource{file: file, reader: reader, header: header, textIndex: textIndex}, nil
+}
+
+// Next 返回下一行，文本为空的行也返回（处理时跳过），以便结果与上传文件逐行对应
+func (s *csvSource) Next() (BatchInput, error) {
+	record, err := s.reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return BatchInput{}, io.EOF
+		}
+		return BatchInput{}, fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+	}
+
+	metadata := make(map[string]string, len(record)-1)
+	row := make(map[string]string, len(record))
+	for i, value := range record {
+		row[s.header[i]] = value
+		if i != s.textIndex {
+			metadata[s.header[i]] = value
+		}
+	}
+
+	return BatchInput{Text: strings.TrimSpace(record[s.textIndex]), Metadata: metadata, Row: row}, nil
+}
+
+// columns 返回CSV表头
+func (s *csvSource) columns() []string {
+	return s.header
+}
+
+// Close 关闭文件
+func (s *csvSource) Close() error {
+	return s.file.Close()
+}
+
+// jsonlSource 逐行读取JSONL，文本字段作为文本，其余字段作为元数据
+type jsonlSource struct {
+	file       *os.File
+	scanner    *bufio.Scanner
+	textField  string
+	line       int
+	fields     []string
+	seenFields map[string]bool
+}
+
+// newJSONLSource 创建JSONL读取器
+func newJSONLSource(file *os.File, textField string) *jsonlSource {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLLineSize)
+
+	return &jsonlSource{
+		file:       file,
+		scanner:    scanner,
+		textField:  textField,
+		seenFields: make(map[string]bool),
+	}
+}
+
+// Next 返回下一行，跳过空行；文本为空的行也返回（处理时跳过），以便结果与上传文件逐行对应
+func (s *jsonlSource) Next() (BatchInput, error) {
+	for s.scanner.Scan() {
+		s.line++
+
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(line, &object); err != nil {
+			return BatchInput{}, fmt.Errorf("%w: 第 %d 行不是有效的JSON对象: %v", ErrInvalidUpload, s.line, err)
+		}
+
+		var text string
+		if raw, ok := object[s.textField]; ok {
+			if err := json.Unmarshal(raw, &text); err != nil {
+				return BatchInput{}, fmt.Errorf("%w: 第 %d 行的字段 %q 不是字符串", ErrInvalidUpload, s.line, s.textField)
+			}
+		}
+		metadata := make(map[string]string, len(object)-1)
+		row := make(map[string]string, len(object))
+		for key, raw := range object {
+			if len(key) > maxColumnNameLength {
+				return BatchInput{}, fmt.Errorf("%w: 第 %d 行的字段名超过 %d 个字符", ErrInvalidUpload, s.line, maxColumnNameLength)
+			}
+			row[key] = jsonValueString(raw)
+			if key != s.textField {
+				metadata[key] = row[key]
+			}
+		}
+
+		s.recordFields(line)
+		return BatchInput{Text: strings.TrimSpace(text), Metadata: metadata, Row: row}, nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return BatchInput{}, fmt.Errorf("%w: 第 %d 行读取失败: %v", ErrInvalidUpload, s.line+1, err)
+	}
+	return BatchInput{}, io.EOF
+}
+
+// recordFields 按首次出现的顺序记录字段名
+func (s *jsonlSource) recordFields(line []byte) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.Token() // 跳过 {
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return
+		}
+		if key, ok := token.(string); ok && !s.seenFields[key] {
+			s.seenFields[key] = true
+			s.fields = append(s.fields, key)
+		}
+
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return
+		}
+	}
+}
+
+// columns 返回出现过的字段名
+func (s *jsonlSource) columns() []string {
+	return s.fields
+}
+
+// Close 关闭文件
+func (s *jsonlSource) Close() error {
+	return s.file.Close()
+}
+
+// jsonValueString 字符串值保持原样，其他值保留JSON编码
+func jsonValueString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
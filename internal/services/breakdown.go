@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// BreakdownQuery 按元数据分组统计的条件
+type BreakdownQuery struct {
+	StatsFilter
+	Key   string
+	Limit int
+	// Baseline 用于比较的基准时间范围，其他条件与当前时间范围相同；为空时不比较
+	Baseline *TimeRange
+}
+
+// TimeRange 时间范围 [Start, End)
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// MetadataBreakdown 按元数据键 query.Key 的值分组统计分析记录，返回记录最多的 query.Limit 组
+// 指定基准时间范围时，对返回的每组同时统计基准时间范围并计算变化
+func (s *SentimentService) MetadataBreakdown(ctx context.Context, query BreakdownQuery) (*models.BreakdownResult, error) {
+	// 多查询一组以判断是否还有更多分组
+	rows, err := s.repository.MetadataBreakdown(ctx, query.params(), query.Key, nil, query.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.BreakdownResult{Key: query.Key}
+	if len(rows) > query.Limit {
+		rows = rows[:query.Limit]
+		result.Truncated = true
+	}
+	result.Groups = make([]models.BreakdownGroup, len(rows))
+	for i, row := range rows {
+		result.Groups[i] = models.BreakdownGroup{Value: row.Value, Stats: toGroupStats(row)}
+	}
+
+	if query.Baseline == nil || len(rows) == 0 {
+		return result, nil
+	}
+
+	baseline := query.params()
+	baseline.StartTime = query.Baseline.Start
+	baseline.EndTime = query.Baseline.End
+
+	values := make([]string, len(rows))
+	for i, row := range rows {
+		values[i] = row.Value
+	}
+	baselineRows, err := s.repository.MetadataBreakdown(ctx, baseline, query.Key, values, 0)
+	if err != nil {
+		return nil, err
+	}
+	byValue := make(map[string]repositories.BreakdownRow, len(baselineRows))
+	for _, row := range baselineRows {
+		byValue[row.Value] = row
+	}
+
+	for i := range result.Groups {
+		group := &result.Groups[i]
+		row, ok := byValue[group.Value]
+		if !ok {
+			row = repositories.BreakdownRow{Value: group.Value}
+		}
+		stats := toGroupStats(row)
+		group.Baseline = &stats
+		group.Change = groupChange(group.Stats, stats)
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"key":    query.Key,
+		"groups": len(result.Groups),
+	}).Debug("已与基准时间范围比较")
+
+	return result, nil
+}
+
+// toGroupStats 转换一组分析记录的统计结果
+func toGroupStats(row repositories.BreakdownRow) models.GroupStats {
+	stats := models.GroupStats{
+		Total:        int(row.Total),
+		Counts:       labelCounts(row.Positive, row.Negative, row.Neutral),
+		AverageScore: row.AvgScore,
+	}
+	if row.Total == 0 {
+		return stats
+	}
+
+	stats.Shares = make(map[string]float64, len(stats.Counts))
+	for label, count := range stats.Counts {
+		stats.Shares[label] = float64(count) / float64(row.Total)
+	}
+
+	stats.Percentiles = make(map[string]float64, 5)
+	for name, value := range map[string]*float64{
+		"p10": row.P10, "p25": row.P25, "p50": row.P50, "p75": row.P75, "p90": row.P90,
+	} {
+		if value != nil {
+			stats.Percentiles[name] = *value
+		}
+	}
+	return stats
+}
+
+// groupChange 计算当前统计相对基准统计的变化
+func groupChange(current, baseline models.GroupStats) *models.GroupChange {
+	change := &models.GroupChange{}
+	if baseline.Total > 0 {
+		total := float64(current.Total-baseline.Total) / float64(baseline.Total)
+		change.TotalChange = &total
+	}
+	if current.AverageScore != nil && baseline.AverageScore != nil {
+		delta := *current.AverageScore - *baseline.AverageScore
+		change.AverageScoreDelta = &delta
+	}
+	if current.Shares != nil && baseline.Shares != nil {
+		change.ShareDeltas = make(map[string]float64, len(current.Shares))
+		for label, share := range current.Shares {
+			change.ShareDeltas[label] = share - baseline.Shares[label]
+		}
+	}
+	return change
+}
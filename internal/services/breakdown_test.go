@@ -0,0 +1,149 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+// approxEqual 比较浮点数，允许舍入误差
+func approxEqual(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+// approxPtrEqual 比较可能为空的浮点数
+func approxPtrEqual(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return approxEqual(*a, *b)
+}
+
+func approxMapEqual(a, b map[string]float64) bool {
+	if (a == nil) != (b == nil) || len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		w, ok := b[k]
+		if !ok || !approxEqual(v, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestToGroupStats(t *testing.T) {
+	tests := []struct {
+		name string
+		row  repositories.BreakdownRow
+		want models.GroupStats
+	}{
+		{
+			name: "没有记录",
+			row:  repositories.BreakdownRow{Value: "web"},
+			want: models.GroupStats{
+				Counts: map[string]int{"positive": 0, "negative": 0, "neutral": 0},
+			},
+		},
+		{
+			name: "完整的统计",
+			row: repositories.BreakdownRow{
+				Value: "app", Total: 8, Positive: 4, Negative: 2, Neutral: 2,
+				AvgScore: floatPtr(0.25),
+				P10:      floatPtr(-0.8), P25: floatPtr(-0.2), P50: floatPtr(0.3), P75: floatPtr(0.6), P90: floatPtr(0.9),
+			},
+			want: models.GroupStats{
+				Total:        8,
+				Counts:       map[string]int{"positive": 4, "negative": 2, "neutral": 2},
+				Shares:       map[string]float64{"positive": 0.5, "negative": 0.25, "neutral": 0.25},
+				AverageScore: floatPtr(0.25),
+				Percentiles:  map[string]float64{"p10": -0.8, "p25": -0.2, "p50": 0.3, "p75": 0.6, "p90": 0.9},
+			},
+		},
+		{
+			name: "缺少的分位数不输出",
+			row: repositories.BreakdownRow{
+				Value: "api", Total: 1, Neutral: 1, AvgScore: floatPtr(0), P50: floatPtr(0),
+			},
+			want: models.GroupStats{
+				Total:        1,
+				Counts:       map[string]int{"positive": 0, "negative": 0, "neutral": 1},
+				Shares:       map[string]float64{"positive": 0, "negative": 0, "neutral": 1},
+				AverageScore: floatPtr(0),
+				Percentiles:  map[string]float64{"p50": 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toGroupStats(tt.row)
+			if got.Total != tt.want.Total || !reflect.DeepEqual(got.Counts, tt.want.Counts) {
+				t.Errorf("Total, Counts = %d, %v，期望 %d, %v", got.Total, got.Counts, tt.want.Total, tt.want.Counts)
+			}
+			if !approxPtrEqual(got.AverageScore, tt.want.AverageScore) {
+				t.Errorf("AverageScore = %v，期望 %v", got.AverageScore, tt.want.AverageScore)
+			}
+			if !approxMapEqual(got.Shares, tt.want.Shares) {
+				t.Errorf("Shares = %v，期望 %v", got.Shares, tt.want.Shares)
+			}
+			if !approxMapEqual(got.Percentiles, tt.want.Percentiles) {
+				t.Errorf("Percentiles = %v，期望 %v", got.Percentiles, tt.want.Percentiles)
+			}
+		})
+	}
+}
+
+func TestGroupChange(t *testing.T) {
+	stats := func(total int, avg *float64, shares map[string]float64) models.GroupStats {
+		return models.GroupStats{Total: total, AverageScore: avg, Shares: shares}
+	}
+
+	tests := []struct {
+		name     string
+		current  models.GroupStats
+		baseline models.GroupStats
+		want     models.GroupChange
+	}{
+		{
+			name:     "两个时间范围都有记录",
+			current:  stats(150, floatPtr(0.1), map[string]float64{"positive": 0.4, "negative": 0.4, "neutral": 0.2}),
+			baseline: stats(100, floatPtr(0.3), map[string]float64{"positive": 0.5, "negative": 0.2, "neutral": 0.3}),
+			want: models.GroupChange{
+				TotalChange:       floatPtr(0.5),
+				AverageScoreDelta: floatPtr(-0.2),
+				ShareDeltas:       map[string]float64{"positive": -0.1, "negative": 0.2, "neutral": -0.1},
+			},
+		},
+		{
+			name:     "当前时间范围没有记录",
+			current:  stats(0, nil, nil),
+			baseline: stats(40, floatPtr(0.3), map[string]float64{"positive": 1}),
+			want:     models.GroupChange{TotalChange: floatPtr(-1)},
+		},
+		{
+			name:     "基准时间范围没有记录",
+			current:  stats(10, floatPtr(0.2), map[string]float64{"positive": 1}),
+			baseline: stats(0, nil, nil),
+			want:     models.GroupChange{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := groupChange(tt.current, tt.baseline)
+			if !approxPtrEqual(got.TotalChange, tt.want.TotalChange) {
+				t.Errorf("TotalChange = %v，期望 %v", got.TotalChange, tt.want.TotalChange)
+			}
+			if !approxPtrEqual(got.AverageScoreDelta, tt.want.AverageScoreDelta) {
+				t.Errorf("AverageScoreDelta = %v，期望 %v", got.AverageScoreDelta, tt.want.AverageScoreDelta)
+			}
+			if !approxMapEqual(got.ShareDeltas, tt.want.ShareDeltas) {
+				t.Errorf("ShareDeltas = %v，期望 %v", got.ShareDeltas, tt.want.ShareDeltas)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// TopKeywords 返回时间范围内出现在最多分析记录中的关键词及其情感分布，按记录数倒序
+func (s *SentimentService) TopKeywords(ctx context.Context, filter StatsFilter, limit int) ([]models.KeywordStats, error) {
+	rows, err := s.repository.TopKeywords(ctx, filter.params(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.KeywordStats, len(rows))
+	for i, row := range rows {
+		stats[i] = toKeywordStats(row)
+	}
+	return stats, nil
+}
+
+// KeywordSentiment 返回每个指定关键词的情感分布，顺序与参数一致
+// 关键词按存储时的规则规范化（不区分大小写），没有出现的关键词计数为0
+func (s *SentimentService) KeywordSentiment(ctx context.Context, filter StatsFilter, keywords []string) ([]models.KeywordStats, error) {
+	normalized := make([]string, 0, len(keywords))
+	seen := make(map[string]bool, len(keywords))
+	for _, keyword := range keywords {
+		keyword = repositories.NormalizeKeyword(keyword)
+		if keyword != "" && !seen[keyword] {
+			seen[keyword] = true
+			normalized = append(normalized, keyword)
+		}
+	}
+
+	rows, err := s.repository.KeywordStats(ctx, filter.params(), normalized)
+	if err != nil {
+		return nil, err
+	}
+	byKeyword := make(map[string]repositories.KeywordStatsRow, len(rows))
+	for _, row := range rows {
+		byKeyword[row.Keyword] = row
+	}
+
+	stats := make([]models.KeywordStats, len(normalized))
+	for i, keyword := range normalized {
+		row, ok := byKeyword[keyword]
+		if !ok {
+			row = repositories.KeywordStatsRow{Keyword: keyword}
+		}
+		stats[i] = toKeywordStats(row)
+	}
+	return stats, nil
+}
+
+// RisingKeywords 返回与上一个等长时间窗口相比出现次数增长最快的关键词
+// 当前窗口中出现次数少于 minCount 的关键词被忽略，避免少量记录造成的波动
+func (s *SentimentService) RisingKeywords(ctx context.Context, filter StatsFilter, minCount, limit int) ([]models.RisingKeyword, error) {
+	rows, err := s.repository.RisingKeywords(ctx, filter.params(), minCount, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	keywords := make([]models.RisingKeyword, len(rows))
+	for i, row := range rows {
+		keywords[i] = models.RisingKeyword{
+			Keyword:       row.Keyword,
+			Count:         int(row.CurrentCount),
+			PreviousCount: int(row.PreviousCount),
+		}
+		if row.PreviousCount > 0 {
+			growth := float64(row.CurrentCount-row.PreviousCount) / float64(row.PreviousCount)
+			keywords[i].Growth = &growth
+		}
+	}
+	return keywords, nil
+}
+
+// toKeywordStats 转换关键词统计结果
+func toKeywordStats(row repositories.KeywordStatsRow) models.KeywordStats {
+	return models.KeywordStats{
+		Keyword:      row.Keyword,
+		Total:        int(row.Total),
+		Counts:       labelCounts(row.Positive, row.Negative, row.Neutral),
+		AverageScore: row.AvgScore,
+	}
+}
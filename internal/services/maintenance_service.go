@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// 导出格式
+const (
+	ExportFormatJSONL = "jsonl"
+	ExportFormatCSV   = "csv"
+)
+
+// MaintenanceService 提供运维命令使用的批量维护操作
+type MaintenanceService struct {
+	repository repositories.SentimentRepository
+	analyzer   Analyzer
+}
+
+// NewMaintenanceService 创建维护服务，analyzer 仅重新分析时需要，可以为nil
+func NewMaintenanceService(repo repositories.SentimentRepository, analyzer Analyzer) *MaintenanceService {
+	return &MaintenanceService{
+		repository: repo,
+		analyzer:   analyzer,
+	}
+}
+
+// RecordFilter 选择要处理的分析记录
+type RecordFilter struct {
+	UserID    string
+	Sentiment string
+	Since     *time.Time
+	Until     *time.Time
+}
+
+// params 转换为存储库查询参数
+func (f RecordFilter) params() repositories.FindAnalysesParams {
+	return repositories.FindAnalysesParams{
+		UserID:    f.UserID,
+		Sentiment: f.Sentiment,
+		StartTime: f.Since,
+		EndTime:   f.Until,
+	}
+}
+
+// ReanalyzeOptions 重新分析的参数
+type ReanalyzeOptions struct {
+	Filter RecordFilter
+	// BatchSize 每次从数据库读取的记录数
+	BatchSize int
+	// Limit 最多处理的记录数，0表示不限制
+	Limit int
+	// Timeout 单次分析的超时，0表示不限制
+	Timeout time.Duration
+	// DryRun 只分析并统计变化，不写回数据库
+	DryRun bool
+}
+
+// ReanalyzeSummary 重新分析的统计结果
+type ReanalyzeSummary struct {
+	Scanned int
+	Changed int
+	Updated int
+	Failed  int
+}
+
+// errLimitReached 达到处理上限时用于结束遍历
+var errLimitReached = errors.New("已达到处理上限")
+
+// Reanalyze 使用当前的算法服务重新分析已存储的文本，并更新情感、分数和关键词
+// 单条记录分析或更新失败只计数，不中断整个过程
+func (s *MaintenanceService) Reanalyze(ctx context.Context, opts ReanalyzeOptions) (*ReanalyzeSummary, error) {
+	if s.analyzer == nil {
+		return nil, errors.New("未配置情感分析算法客户端")
+	}
+
+	logger := logging.FromContext(ctx)
+	summary := &ReanalyzeSummary{}
+
+	err := s.repository.IterateAnalyses(ctx, opts.Filter.params(), opts.BatchSize, func(analyses []*models.SentimentAnalysis) error {
+		for _, analysis := range analyses {
+			if opts.Limit > 0 && summary.Scanned >= opts.Limit {
+				return errLimitReached
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			summary.Scanned++
+
+			entry := logger.WithFields(logrus.Fields{
+				"analysis_id": analysis.ID,
+				"request_id":  analysis.RequestID,
+			})
+
+			callCtx, cancel := withTimeout(ctx, opts.Timeout)
+			response, err := s.analyzer.AnalyzeSentiment(callCtx, analysis.Text, analysis.Language, analysis.RequestID)
+			cancel()
+			if err != nil {
+				entry.WithError(err).Error("重新分析失败")
+				summary.Failed++
+				continue
+			}
+
+			keywords := strings.Join(response.Keywords, ",")
+			if response.Sentiment == analysis.Sentiment && response.Score == analysis.Score && keywords == analysis.Keywords {
+				continue
+			}
+			summary.Changed++
+
+			entry.WithFields(logrus.Fields{
+				"old_sentiment": analysis.Sentiment,
+				"new_sentiment": response.Sentiment,
+				"old_score":     analysis.Score,
+				"new_score":     response.Score,
+			}).Debug("分析结果已变化")
+
+			if opts.DryRun {
+				continue
+			}
+
+			if err := s.repository.UpdateAnalysisResult(ctx, analysis.ID, response.Sentiment, response.Score, keywords); err != nil {
+				entry.WithError(err).Error("更新分析结果失败")
+				summary.Failed++
+				continue
+			}
+			summary.Updated++
+		}
+
+		logger.WithFields(logrus.Fields{
+			"scanned": summary.Scanned,
+			"changed": summary.Changed,
+			"failed":  summary.Failed,
+		}).Info("重新分析进度")
+		return nil
+	})
+	if errors.Is(err, errLimitReached) {
+		err = nil
+	}
+
+	return summary, err
+}
+
+// exportColumns CSV导出的固定列，元数据以JSON放在最后一列
+var exportColumns = []string{
+	"id", "request_id", "user_id", "language", "sentiment", "score", "keywords", "text", "created_at", "metadata",
+}
+
+// exportRecord JSONL导出的单条记录
+type exportRecord struct {
+	ID        string            `json:"id"`
+	RequestID string            `json:"request_id"`
+	UserID    string            `json:"user_id,omitempty"`
+	Language  string            `json:"language,omitempty"`
+	Sentiment string            `json:"sentiment"`
+	Score     float64           `json:"score"`
+	Keywords  []string          `json:"keywords"`
+	Text      string            `json:"text"`
+	CreatedAt time.Time         `json:"created_at"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// newExportRecord 将数据库记录转换为导出记录
+func newExportRecord(analysis *models.SentimentAnalysis) exportRecord {
+	record := exportRecord{
+		ID:        analysis.ID,
+		RequestID: analysis.RequestID,
+		UserID:    analysis.UserID,
+		Language:  analysis.Language,
+		Sentiment: analysis.Sentiment,
+		Score:     analysis.Score,
+		Keywords:  []string{},
+		Text:      analysis.Text,
+		CreatedAt: analysis.CreatedAt,
+	}
+
+	if analysis.Keywords != "" {
+		record.Keywords = strings.Split(analysis.Keywords, ",")
+	}
+
+	if len(analysis.Metadata) > 0 {
+		record.Metadata = make(map[string]string, len(analysis.Metadata))
+		for _, meta := range analysis.Metadata {
+			record.Metadata[meta.Key] = meta.Value
+		}
+	}
+
+	return record
+}
+
+// Export 按创建时间顺序将符合条件的分析记录写入 w，返回导出的记录数
+func (s *MaintenanceService) Export(ctx context.Context, filter RecordFilter, format string, w io.Writer) (int, error) {
+	var write func(record exportRecord) error
+	var flush func() error
+
+	switch format {
+	case ExportFormatJSONL:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		write = func(record exportRecord) error { return encoder.Encode(record) }
+		flush = func() error { return nil }
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportColumns); err != nil {
+			return 0, err
+		}
+		write = func(record exportRecord) error { return writer.Write(csvRow(record)) }
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		return 0, fmt.Errorf("不支持的导出格式: %s（可选 %s, %s）", format, ExportFormatJSONL, ExportFormatCSV)
+	}
+
+	exported := 0
+	err := s.repository.IterateAnalyses(ctx, filter.params(), 500, func(analyses []*models.SentimentAnalysis) error {
+		for _, analysis := range analyses {
+			if err := write(newExportRecord(analysis)); err != nil {
+				return fmt.Errorf("写入导出数据失败: %v", err)
+			}
+			exported++
+		}
+		return nil
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	return exported, flush()
+}
+
+// csvRow 将导出记录转换为CSV行
+func csvRow(record exportRecord) []string {
+	metadata := ""
+	if len(record.Metadata) > 0 {
+		// encoding/json 按键排序输出映射，保证输出稳定
+		data, _ := json.Marshal(record.Metadata)
+		metadata = string(data)
+	}
+
+	return []string{
+		record.ID,
+		record.RequestID,
+		record.UserID,
+		record.Language,
+		record.Sentiment,
+		strconv.FormatFloat(record.Score, 'f', 4, 64),
+		strings.Join(record.Keywords, ","),
+		record.Text,
+		record.CreatedAt.Format(time.RFC3339),
+		metadata,
+	}
+}
+
+// Purge 删除符合条件的分析记录，dryRun 时只返回将被删除的数量
+func (s *MaintenanceService) Purge(ctx context.Context, filter RecordFilter, hard bool, dryRun bool) (int64, error) {
+	if dryRun {
+		return s.repository.CountAnalyses(ctx, filter.params())
+	}
+	return s.repository.DeleteAnalyses(ctx, filter.params(), hard)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// ErrInvalidCursor 分页游标无法解析或与查询的排序方式不一致
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// PageQuery 分页参数，Cursor 和 Offset 不能同时使用
+type PageQuery struct {
+	Limit  int
+	Offset int
+	// Cursor 上一次响应中的 next_cursor 或 prev_cursor，为空时从第一页（或 Offset）开始
+	Cursor string
+	// IncludeTotal 统计符合条件的总数（大表上较慢）
+	IncludeTotal bool
+}
+
+// 游标类型，游标只能用于生成它的接口
+const (
+	cursorKindHistory      = "history"
+	cursorKindBatchResults = "batch_results"
+)
+
+// pageCursor 编码在游标中的内容：游标类型和分页位置
+type pageCursor struct {
+	Kind string `json:"kind"`
+	repositories.Cursor
+}
+
+// encodeCursor 将分页位置编码为不透明的字符串
+func encodeCursor(kind string, cursor repositories.Cursor) string {
+	data, _ := json.Marshal(pageCursor{Kind: kind, Cursor: cursor})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析 encodeCursor 生成的字符串，游标不是 kind 类型时返回 ErrInvalidCursor
+func decodeCursor(value, kind string) (*repositories.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Kind != kind {
+		return nil, fmt.Errorf("%w: 游标不属于该接口", ErrInvalidCursor)
+	}
+	return &cursor.Cursor, nil
+}
+
+// pageWindow 多查询一条记录以判断当前方向上是否还有更多记录
+func pageWindow(page PageQuery) int {
+	if page.Limit <= 0 {
+		return 0
+	}
+	return page.Limit + 1
+}
+
+// pageBounds 根据多查询的一条记录裁剪结果，返回保留的区间以及前后是否还有记录
+// n 是查询返回的记录数，结果已按显示顺序排列；向前翻页时多出的一条在开头
+func pageBounds(page PageQuery, cursor *repositories.Cursor, n int) (start, end int, hasPrev, hasNext bool) {
+	start, end = 0, n
+	more := page.Limit > 0 && n > page.Limit
+	backward := cursor != nil && cursor.Backward
+
+	if more {
+		if backward {
+			start = 1
+		} else {
+			end = page.Limit
+		}
+	}
+
+	if backward {
+		return start, end, more, true
+	}
+	return start, end, cursor != nil || page.Offset > 0, more
+}
+
+// analysisCursor 返回分析记录在给定排序下的分页位置
+func analysisCursor(analysis *models.SentimentAnalysis, sortBy, sortOrder string, backward bool) string {
+	return encodeCursor(cursorKindHistory, repositories.Cursor{
+		CreatedAt: analysis.CreatedAt,
+		Score:     analysis.Score,
+		ID:        analysis.ID,
+		SortBy:    sortBy,
+		SortOrder: sortOrder,
+		Backward:  backward,
+	})
+}
+
+// batchResultCursor 返回批处理结果的分页位置
+func batchResultCursor(order int, backward bool) string {
+	return encodeCursor(cursorKindBatchResults, repositories.Cursor{Order: order, Backward: backward})
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"sentiment-service/internal/repositories"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   string
+		cursor repositories.Cursor
+	}{
+		{
+			name: "历史记录",
+			kind: cursorKindHistory,
+			cursor: repositories.Cursor{
+				CreatedAt: time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC),
+				Score:     -0.75,
+				ID:        "6f1c2d3e-0000-4000-8000-000000000001",
+				SortBy:    repositories.SortByScore,
+				SortOrder: "asc",
+			},
+		},
+		{
+			name:   "批处理结果向前翻页",
+			kind:   cursorKindBatchResults,
+			cursor: repositories.Cursor{Order: 42, Backward: true},
+		},
+		{
+			name: "空游标",
+			kind: cursorKindBatchResults,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(encodeCursor(tt.kind, tt.cursor), tt.kind)
+			if err != nil {
+				t.Fatalf("decodeCursor() 返回错误: %v", err)
+			}
+			if !got.CreatedAt.Equal(tt.cursor.CreatedAt) {
+				t.Errorf("CreatedAt = %v，期望 %v", got.CreatedAt, tt.cursor.CreatedAt)
+			}
+			got.CreatedAt, tt.cursor.CreatedAt = time.Time{}, time.Time{}
+			if *got != tt.cursor {
+				t.Errorf("decodeCursor() = %+v，期望 %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name  string
+		value string
+		kind  string
+	}{
+		{"其他接口的游标", batchResultCursor(10, false), cursorKindHistory},
+		{"历史记录游标用于批处理结果", encodeCursor(cursorKindHistory, repositories.Cursor{ID: "x"}), cursorKindBatchResults},
+		{"没有类型的游标", encode(`{"order":10}`), cursorKindBatchResults},
+		{"不是base64", "not base64!", cursorKindHistory},
+		{"带填充的base64", base64.URLEncoding.EncodeToString([]byte(`{"kind":"history"}x`)), cursorKindHistory},
+		{"不是JSON", encode("order=10"), cursorKindBatchResults},
+		{"字段类型错误", encode(`{"kind":"batch_results","order":"10"}`), cursorKindBatchResults},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := decodeCursor(tt.value, tt.kind)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("decodeCursor() = %+v, %v，期望 ErrInvalidCursor", cursor, err)
+			}
+		})
+	}
+}
+
+func TestPageBounds(t *testing.T) {
+	forward := &repositories.Cursor{Order: 10}
+	backward := &repositories.Cursor{Order: 10, Backward: true}
+
+	tests := []struct {
+		name       string
+		page       PageQuery
+		cursor     *repositories.Cursor
+		n          int
+		start, end int
+		prev, next bool
+	}{
+		{"第一页还有更多", PageQuery{Limit: 2}, nil, 3, 0, 2, false, true},
+		{"第一页没有更多", PageQuery{Limit: 2}, nil, 2, 0, 2, false, false},
+		{"按偏移量翻页", PageQuery{Limit: 2, Offset: 4}, nil, 1, 0, 1, true, false},
+		{"向后翻页还有更多", PageQuery{Limit: 2}, forward, 3, 0, 2, true, true},
+		{"向后翻到最后一页", PageQuery{Limit: 2}, forward, 1, 0, 1, true, false},
+		{"向前翻页还有更多", PageQuery{Limit: 2}, backward, 3, 1, 3, true, true},
+		{"向前翻到第一页", PageQuery{Limit: 2}, backward, 2, 0, 2, false, true},
+		{"不限制数量", PageQuery{}, nil, 5, 0, 5, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, prev, next := pageBounds(tt.page, tt.cursor, tt.n)
+			if start != tt.start || end != tt.end || prev != tt.prev || next != tt.next {
+				t.Errorf("pageBounds() = (%d, %d, %v, %v)，期望 (%d, %d, %v, %v)",
+					start, end, prev, next, tt.start, tt.end, tt.prev, tt.next)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// SortByRelevance 按全文检索的相关度排序，只能用于检索
+const SortByRelevance = repositories.SortByRelevance
+
+// 摘要中高亮命中词的标记，摘要中的其余文字已做HTML转义，可以直接按HTML显示
+const (
+	HighlightStart = repositories.HighlightStart
+	HighlightStop  = repositories.HighlightStop
+)
+
+// snippetRadius 数据库无法生成摘要时（中文记录），命中词前后保留的字符数
+const snippetRadius = 40
+
+// SearchAnalyses 全文检索分析记录，query.Query 不能为空
+// 默认按相关度排序，只支持 Limit 和 Offset 分页
+func (s *SentimentService) SearchAnalyses(ctx context.Context, query HistoryQuery) (*models.SearchResult, error) {
+	if query.Cursor != "" {
+		return nil, ErrInvalidCursor
+	}
+
+	params := query.params()
+	if query.SortBy == "" {
+		params.SortBy = SortByRelevance
+	}
+	// 检索不使用游标，不需要多查询一条记录
+	params.Limit = query.Limit
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"query":    query.Query,
+		"language": query.Language,
+		"sort":     params.SortBy,
+		"limit":    query.Limit,
+		"offset":   query.Offset,
+	}).Debug("全文检索情感分析")
+
+	hits, count, err := s.repository.SearchAnalyses(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.SearchResult{
+		Hits:       make([]models.SearchHitRecord, len(hits)),
+		TotalCount: int(count),
+	}
+	for i, hit := range hits {
+		snippet := hit.Headline
+		if !strings.Contains(snippet, HighlightStart) {
+			snippet = highlightSnippet(hit.Analysis.Text, query.Query)
+		}
+		result.Hits[i] = models.SearchHitRecord{
+			AnalysisRecord: toAnalysisRecord(hit.Analysis),
+			Rank:           hit.Rank,
+			Snippet:        snippet,
+		}
+	}
+
+	return result, nil
+}
+
+// highlightSnippet 在原文中按字面（不区分大小写）高亮查询词，截取第一个命中词附近的文字
+// 高亮标记之外的文字做HTML转义，与 ts_headline 生成的摘要一致
+func highlightSnippet(text, q string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 找出不重叠的命中区间，较长的词优先
+	terms := searchTerms(q)
+	var matches [][2]int
+	for i := 0; i < len(lower); {
+		matched := 0
+		for _, term := range terms {
+			if len(term) > matched && hasPrefixRunes(lower[i:], term) {
+				matched = len(term)
+			}
+		}
+		if matched == 0 {
+			i++
+			continue
+		}
+		matches = append(matches, [2]int{i, i + matched})
+		i += matched
+	}
+
+	// 没有字面命中（例如英文词干匹配）时返回开头部分
+	if len(matches) == 0 {
+		if len(runes) > 2*snippetRadius {
+			return html.EscapeString(string(runes[:2*snippetRadius])) + "..."
+		}
+		return html.EscapeString(text)
+	}
+
+	start := matches[0][0] - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := matches[0][1] + snippetRadius
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	pos := start
+	for _, m := range matches {
+		if m[0] < start || m[1] > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:m[0]])))
+		b.WriteString(HighlightStart)
+		b.WriteString(html.EscapeString(string(runes[m[0]:m[1]])))
+		b.WriteString(HighlightStop)
+		pos = m[1]
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// searchTerms 从 websearch 语法的查询中取出需要高亮的词：引号内的短语作为一个词，忽略 or 和排除词（-词）
+func searchTerms(q string) [][]rune {
+	var terms [][]rune
+	add := func(term string) {
+		term = strings.TrimSpace(term)
+		if term == "" || strings.EqualFold(term, "or") || strings.HasPrefix(term, "-") {
+			return
+		}
+		terms = append(terms, []rune(strings.ToLower(term)))
+	}
+
+	for i, part := range strings.Split(q, `"`) {
+		// 奇数部分在引号内
+		if i%2 == 1 {
+			add(part)
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			add(word)
+		}
+	}
+	return terms
+}
+
+// hasPrefixRunes 判断 s 是否以 prefix 开头
+func hasPrefixRunes(s, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
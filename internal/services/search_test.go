@@ -0,0 +1,33 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	long := strings.Repeat("a", 2*snippetRadius)
+
+	tests := []struct {
+		name string
+		text string
+		q    string
+		want string
+	}{
+		{"高亮命中词", "Battery life is great", "battery", "<mark>Battery</mark> life is great"},
+		{"引号中的短语", "服务很好，价格也合适", `"价格"`, "服务很好，<mark>价格</mark>也合适"},
+		{"转义命中词前后的文字", `<script>alert("x")</script> good & cheap`, "good", `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>good</mark> &amp; cheap`},
+		{"转义命中词", "AT&T <b>", "at&t", "<mark>AT&amp;T</mark> &lt;b&gt;"},
+		{"原文中的高亮标记被转义", "<mark>fake</mark> real", "real", "&lt;mark&gt;fake&lt;/mark&gt; <mark>real</mark>"},
+		{"没有命中时转义原文", "a < b && c > d", "missing", "a &lt; b &amp;&amp; c &gt; d"},
+		{"没有命中时截取开头", long + "<tail>", "missing", long + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightSnippet(tt.text, tt.q); got != tt.want {
+				t.Errorf("highlightSnippet(%q, %q) = %q，期望 %q", tt.text, tt.q, got, tt.want)
+			}
+		})
+	}
+}
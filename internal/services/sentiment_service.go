@@ -6,64 +6,153 @@ import (
 	"fmt"
 	sentimentv1 "sentiment-service/internal/gen/sentiment/v1"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"sentiment-service/internal/grpc"
+	"sentiment-service/internal/logging"
 	"sentiment-service/internal/models"
 	"sentiment-service/internal/mq"
 	"sentiment-service/internal/repositories"
 )
 
+// ErrShuttingDown 服务正在关闭，不再接受新的异步任务
+var ErrShuttingDown = errors.New("服务正在关闭，不再接受异步任务")
+
+// ErrAnalysisNotFound 分析记录不存在
+var ErrAnalysisNotFound = errors.New("分析记录不存在")
+
+// ErrBatchNotFound 批处理记录不存在
+var ErrBatchNotFound = errors.New("批处理记录不存在")
+
+// ErrDuplicateRequestID 客户端提供的请求ID已被其他分析记录或进行中的异步任务使用
+var ErrDuplicateRequestID = errors.New("请求ID已被使用，请使用新的 X-Request-ID")
+
+// Analyzer 情感分析算法客户端（默认实现为 grpc.SentimentClient）
+type Analyzer interface {
+	// AnalyzeSentiment 分析单个文本
+	AnalyzeSentiment(ctx context.Context, text, language, requestID string) (*sentimentv1.SentimentResponse, error)
+
+	// BatchAnalyzeStream 创建批量分析流
+	BatchAnalyzeStream(ctx context.Context) (sentimentv1.SentimentAnalyzer_BatchAnalyzeSentimentClient, error)
+
+	// Close 关闭客户端连接
+	Close() error
+}
+
+// TaskQueue 异步分析任务队列（默认实现为 mq.SentimentMQ）
+type TaskQueue interface {
+	// PublishTask 发布分析任务，结果到达时调用回调；requestID 为空时自动生成，返回实际使用的请求ID
+	// 相同请求ID的任务仍在等待结果时返回 mq.ErrDuplicateTask
+	PublishTask(ctx context.Context, requestID, text, language string, callback mq.ResultCallback) (string, error)
+
+	// Drain 等待已发布任务的回调完成，返回被放弃的请求ID
+	Drain(ctx context.Context) []string
+
+	// Close 关闭队列连接，ctx 结束时不再等待进行中的回调
+	Close(ctx context.Context) error
+}
+
+// ResultWriter 异步写入分析记录（默认实现为 writebehind.Writer）
+type ResultWriter interface {
+	// Enqueue 将分析记录加入写入队列，不等待写入数据库
+	Enqueue(ctx context.Context, analysis *models.SentimentAnalysis) error
+}
+
 // SentimentService 定义了情感分析服务的操作
 type SentimentService struct {
 	repository  repositories.SentimentRepository
-	grpcClient  *grpc.SentimentClient
-	mqClient    *mq.SentimentMQ
+	grpcClient  Analyzer
+	mqClient    TaskQueue
 	callbackURL string
+
+	// 设置后单条分析结果异步写入数据库，为nil时同步写入
+	writer ResultWriter
+
+	// 关闭过程中置为true，拒绝新的异步任务
+	shuttingDown atomic.Bool
+
+	// 可在运行时调整的参数
+	options atomic.Pointer[Options]
+
+	// 后台运行的异步批处理任务
+	jobs *batchJobs
+}
+
+// Options 可在运行时调整的服务参数（配置热更新时通过 SetOptions 替换）
+type Options struct {
+	// AnalyzeTimeout 单次分析调用算法服务的超时，0表示不限制
+	AnalyzeTimeout time.Duration
+	// BatchTimeout 批量分析流的超时，0表示不限制
+	BatchTimeout time.Duration
+	// QueueOnAnalyzerError 同步分析失败时改为提交异步任务
+	QueueOnAnalyzerError bool
+	// ChunkSize 每个gRPC流负责的文本数
+	ChunkSize int
+	// Streams 一个批处理同时使用的gRPC流数量
+	Streams int
+	// MaxInFlight 每个gRPC流上已发送但未收到结果的最大请求数
+	MaxInFlight int
+	// MaxAsyncTexts 异步批处理允许的最大文本数，0表示不限制
+	MaxAsyncTexts int
+}
+
+// SetResultWriter 设置单条分析结果的异步写入器，需要在处理请求之前调用
+func (s *SentimentService) SetResultWriter(writer ResultWriter) {
+	s.writer = writer
+}
+
+// QueuedError 表示同步分析失败后已降级为异步任务
+type QueuedError struct {
+	RequestID string
+	Cause     error
+}
+
+// Error 实现error接口
+func (e *QueuedError) Error() string {
+	return fmt.Sprintf("同步分析失败，已改为异步处理（请求ID: %s）: %v", e.RequestID, e.Cause)
+}
+
+// Unwrap 返回导致降级的原始错误
+func (e *QueuedError) Unwrap() error {
+	return e.Cause
 }
 
 // NewSentimentService 创建情感分析服务
 func NewSentimentService(
 	repo repositories.SentimentRepository,
-	grpcAddr string,
-	amqpURL string,
-	taskQueue string,
-	resultQueue string,
-) (*SentimentService, error) {
-	// 记录初始化信息
-	logrus.Infof("正在初始化情感分析服务...")
-	logrus.Infof("gRPC地址: %s", grpcAddr)
-	logrus.Infof("RabbitMQ地址: %s", amqpURL)
-	logrus.Infof("任务队列: %s", taskQueue)
-	logrus.Infof("结果队列: %s", resultQueue)
-
-	// 创建gRPC客户端
-	grpcClient, err := grpc.NewSentimentClient(grpcAddr)
-	if err != nil {
-		return nil, fmt.Errorf("初始化gRPC客户端失败: %v", err)
+	analyzer Analyzer,
+	queue TaskQueue,
+) *SentimentService {
+	s := &SentimentService{
+		repository:  repo,
+		grpcClient:  analyzer,
+		mqClient:    queue,
+		callbackURL: "",
+		jobs:        newBatchJobs(),
 	}
-	logrus.Info("gRPC客户端初始化成功")
+	s.options.Store(&Options{})
+	return s
+}
 
-	// 创建消息队列客户端
-	mqClient, err := mq.NewSentimentMQ(
-		amqpURL,
-		taskQueue,
-		resultQueue,
-	)
-	if err != nil {
-		grpcClient.Close()
-		return nil, fmt.Errorf("初始化MQ客户端失败: %v", err)
-	}
-	logrus.Info("RabbitMQ客户端初始化成功")
+// SetOptions 替换运行时参数，对之后开始的请求生效
+func (s *SentimentService) SetOptions(opts Options) {
+	s.options.Store(&opts)
+}
 
-	return &SentimentService{
-		repository:  repo,
-		grpcClient:  grpcClient,
-		mqClient:    mqClient,
-		callbackURL: "",
-	}, nil
+// currentOptions 返回当前运行时参数
+func (s *SentimentService) currentOptions() Options {
+	return *s.options.Load()
+}
+
+// withTimeout 在超时大于0时为上下文设置超时
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 // AnalyzeSentiment 同步分析文本情感（使用gRPC）
@@ -78,22 +167,50 @@ func (s *SentimentService) AnalyzeSentiment(
 		return nil, errors.New("文本不能为空")
 	}
 
-	logrus.WithFields(logrus.Fields{
+	// 优先使用客户端提供的HTTP请求ID，便于跨层关联
+	requestID := logging.RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	} else if storeResult {
+		if err := s.checkRequestID(ctx, requestID); err != nil {
+			return nil, err
+		}
+	}
+
+	logger := logging.FromContext(ctx).WithField("analysis_request_id", requestID)
+	ctx = logging.WithLogger(ctx, logger)
+	logger.WithFields(logrus.Fields{
 		"text_length": len(text),
 		"language":    language,
 		"store":       storeResult,
 	}).Debug("开始分析情感")
 
-	// 生成请求ID
-	requestID := uuid.New().String()
+	opts := s.currentOptions()
 
 	// 调用gRPC服务
-	response, _ := s.grpcClient.AnalyzeSentiment(
-		ctx,
+	callCtx, cancel := withTimeout(ctx, opts.AnalyzeTimeout)
+	response, err := s.grpcClient.AnalyzeSentiment(
+		callCtx,
 		text,
 		language,
 		requestID,
 	)
+	cancel()
+	if err != nil {
+		logger.WithError(err).Error("调用gRPC分析服务失败")
+
+		// 降级为异步任务
+		if opts.QueueOnAnalyzerError {
+			queuedID, queueErr := s.AnalyzeSentimentAsync(ctx, text, language, storeResult, metadata)
+			if queueErr == nil {
+				logger.WithField("task_request_id", queuedID).Warn("同步分析失败，已降级为异步任务")
+				return nil, &QueuedError{RequestID: queuedID, Cause: err}
+			}
+			logger.WithError(queueErr).Error("降级为异步任务失败")
+		}
+
+		return nil, fmt.Errorf("调用gRPC分析服务失败: %v", err)
+	}
 
 	var result *models.SentimentResult
 
@@ -110,14 +227,31 @@ func (s *SentimentService) AnalyzeSentiment(
 
 	// 如果请求存储结果
 	if storeResult {
-		if err := s.storeAnalysisResult(ctx, result, language, metadata); err != nil {
-			logrus.WithError(err).Error("存储分析结果失败")
+		err := s.storeAnalysisResult(ctx, result, language, metadata)
+		if errors.Is(err, repositories.ErrDuplicateRequestID) {
+			// 检查之后其他请求使用了相同的请求ID
+			return nil, ErrDuplicateRequestID
+		}
+		if err != nil {
+			logger.WithError(err).Error("存储分析结果失败")
 		}
 	}
 
 	return result, nil
 }
 
+// checkRequestID 检查客户端提供的请求ID是否已被分析记录使用
+func (s *SentimentService) checkRequestID(ctx context.Context, requestID string) error {
+	existing, err := s.repository.GetAnalysisByRequestId(ctx, requestID)
+	if err != nil {
+		return fmt.Errorf("检查请求ID失败: %w", err)
+	}
+	if existing != nil {
+		return ErrDuplicateRequestID
+	}
+	return nil
+}
+
 // AnalyzeSentimentAsync 异步分析文本情感（使用消息队列）
 func (s *SentimentService) AnalyzeSentimentAsync(
 	ctx context.Context,
@@ -130,19 +264,35 @@ func (s *SentimentService) AnalyzeSentimentAsync(
 		return "", errors.New("文本不能为空")
 	}
 
+	if s.shuttingDown.Load() {
+		return "", ErrShuttingDown
+	}
+
+	// 优先使用客户端提供的HTTP请求ID，没有时由队列生成
+	requestID := logging.RequestIDFromContext(ctx)
+	if requestID != "" && storeResult {
+		if err := s.checkRequestID(ctx, requestID); err != nil {
+			return "", err
+		}
+	}
+
+	// 使用脱离请求的上下文，因为回调可能在请求上下文结束后发生，但保留请求级日志记录器
+	storeCtx := logging.Detach(ctx)
+
 	// 创建回调函数
 	callback := func(result *models.SentimentResult) {
 		if storeResult {
-			// 使用背景上下文，因为回调可能在请求上下文结束后发生
-			storeCtx := context.Background()
 			if err := s.storeAnalysisResult(storeCtx, result, language, metadata); err != nil {
-				logrus.WithError(err).Error("存储异步分析结果失败")
+				logging.FromContext(storeCtx).WithError(err).Error("存储异步分析结果失败")
 			}
 		}
 	}
 
 	// 发布到消息队列
-	requestID, err := s.mqClient.PublishTask(ctx, text, language, callback)
+	requestID, err := s.mqClient.PublishTask(ctx, requestID, text, language, callback)
+	if errors.Is(err, mq.ErrDuplicateTask) {
+		return "", ErrDuplicateRequestID
+	}
 	if err != nil {
 		return "", fmt.Errorf("发布异步任务失败: %v", err)
 	}
@@ -151,42 +301,63 @@ func (s *SentimentService) AnalyzeSentimentAsync(
 }
 
 // BatchAnalyzeSentiment 批量分析多个文本的情感（使用gRPC流）
+// 结果按输入顺序返回并带有项目ID（未提供时自动生成），相同的文本只分析一次；
+// 部分项目失败时不返回错误，每个项目带有自己的状态和失败原因，失败的项目会重试一次；
+// 需要存储结果但无法创建批处理记录时返回错误，不进行分析
 func (s *SentimentService) BatchAnalyzeSentiment(
 	ctx context.Context,
-	texts []string,
+	inputs []BatchInput,
 	language string,
 	storeResults bool,
 	metadata map[string]string,
 ) (*models.BatchSentimentResult, error) {
-	if len(texts) == 0 {
+	return s.StreamBatchAnalyzeSentiment(ctx, inputs, language, storeResults, metadata, nil)
+}
+
+// StreamBatchAnalyzeSentiment 与 BatchAnalyzeSentiment 相同，但每个项目得到分析结果后立即调用 emit，
+// 顺序为完成顺序而非输入顺序；emit 的调用是串行的，index 是项目在输入中的位置。
+// 结果在所有项目完成后批量存储：需要存储时分析成功的项目以 analyzed 状态输出，
+// 存储失败的项目在存储后以 failed 状态再输出一次，其余 analyzed 项目在返回时已存储成功
+func (s *SentimentService) StreamBatchAnalyzeSentiment(
+	ctx context.Context,
+	inputs []BatchInput,
+	language string,
+	storeResults bool,
+	metadata map[string]string,
+	emit func(index int, item models.BatchItemResult),
+) (*models.BatchSentimentResult, error) {
+	if len(inputs) == 0 {
 		return nil, errors.New("文本列表不能为空")
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"text_count": len(texts),
+	logger := logging.FromContext(ctx)
+	logger.WithFields(logrus.Fields{
+		"text_count": len(inputs),
 		"language":   language,
 		"store":      storeResults,
 	}).Debug("开始批量分析情感")
 
+	// 为未提供ID的项目生成ID
+	items := make([]BatchInput, len(inputs))
+	for i, input := range inputs {
+		if input.ID == "" {
+			input.ID = uuid.New().String()
+		}
+		items[i] = input
+	}
+
 	// 创建批处理ID
 	batchID := uuid.New().String()
 
-	// 创建批处理结果
-	batchResult := &models.BatchSentimentResult{
-		BatchID: batchID,
-		Results: make([]models.SentimentResult, 0, len(texts)),
-	}
-
 	// 存储批处理记录（如果请求）
 	var batchAnalysis *models.BatchAnalysis
-	var analysisIDs []string
-
+	startedAt := time.Now()
 	if storeResults {
-		// 创建批处理记录
 		batchAnalysis = &models.BatchAnalysis{
-			ID:     batchID,
-			Count:  len(texts),
-			Status: "pending",
+			ID:        batchID,
+			Count:     len(items),
+			Status:    models.BatchStatusProcessing,
+			StartedAt: &startedAt,
 		}
 
 		// 添加用户ID（如果可用）
@@ -194,136 +365,175 @@ func (s *SentimentService) BatchAnalyzeSentiment(
 			batchAnalysis.UserID = userID
 		}
 
-		// 存储批处理
+		// 批处理记录是查询进度和结果的入口，无法创建时不分析，避免返回查询不到的批处理ID
 		if err := s.repository.CreateBatchAnalysis(ctx, batchAnalysis); err != nil {
-			logrus.WithError(err).Error("存储批处理分析记录失败")
-		} else {
-			logrus.WithField("batch_id", batchID).Debug("批处理分析记录已存储")
+			logger.WithError(err).Error("存储批处理分析记录失败")
+			return nil, fmt.Errorf("存储批处理分析记录失败: %w", err)
 		}
+		logger.WithField("batch_id", batchID).Debug("批处理分析记录已存储")
+
+		// 处理期间定期刷新更新时间，避免被当作中断的批处理清理
+		s.jobs.track(batchID)
+		defer s.jobs.untrack(batchID)
 	}
 
-	// 尝试使用gRPC流
-	useGrpcStream := true
-	stream, err := s.grpcClient.BatchAnalyzeStream(ctx)
-	if err != nil {
-		logrus.WithError(err).Warn("创建gRPC流失败，使用本地分析")
-		useGrpcStream = false
-	}
-
-	if useGrpcStream {
-		// 发送所有请求
-		textToRequestID := make(map[string]string, len(texts))
-		for _, text := range texts {
-			requestID := uuid.New().String()
-			textToRequestID[text] = requestID
-
-			// 发送请求
-			// 修复: 使用正确的SentimentRequest类型
-			err := stream.Send(&sentimentv1.SentimentRequest{
-				Text:      text,
-				Language:  language,
-				RequestId: requestID,
-			})
-			if err != nil {
-				logrus.WithError(err).Error("发送流请求失败")
-				useGrpcStream = false
-				break
+	var onItem func(int, *models.BatchItemResult)
+	if emit != nil {
+		var mu sync.Mutex
+		onItem = func(i int, item *models.BatchItemResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			emitted := *item
+			// 尚未存储，不能报告为成功
+			if storeResults && emitted.Status == models.BatchItemStatusSucceeded {
+				emitted.Status = models.BatchItemStatusAnalyzed
 			}
+			emit(i, emitted)
 		}
+	}
+	results := s.analyzeItems(ctx, items, language, onItem)
 
-		// 如果所有请求都已发送
-		if useGrpcStream {
-			// 关闭发送方向
-			if err := stream.CloseSend(); err != nil {
-				logrus.WithError(err).Error("关闭发送流失败")
-				useGrpcStream = false
-			} else {
-				// 接收所有响应
-				for i := 0; i < len(texts); i++ {
-					resp, err := stream.Recv()
-					if err != nil {
-						logrus.WithError(err).Error("接收流响应失败")
-						useGrpcStream = false
-						break
-					}
-
-					// 查找对应的文本
-					var text string
-					for t, reqID := range textToRequestID {
-						if reqID == resp.RequestId {
-							text = t
-							break
-						}
-					}
-
-					// 创建结果
-					result := models.SentimentResult{
-						Text:             text,
-						Sentiment:        resp.Sentiment,
-						Score:            resp.Score,
-						ConfidenceScores: resp.ConfidenceScores,
-						Keywords:         resp.Keywords,
-						RequestID:        resp.RequestId,
-						Timestamp:        time.Now(),
-					}
-
-					// 添加到批量结果
-					batchResult.Results = append(batchResult.Results, result)
-
-					// 存储结果（如果需要）
-					if storeResults {
-						analysisID, err := s.storeAnalysisResultForBatch(ctx, &result, language, metadata, batchID)
-						if err != nil {
-							logrus.WithError(err).Error("存储批量分析结果失败")
-						} else {
-							analysisIDs = append(analysisIDs, analysisID)
-						}
-					}
-				}
+	// 所有结果在一个事务中批量存储，存储失败的项目计为失败
+	if storeResults {
+		failed := s.storeBatchResults(ctx, batchID, items, results, 0, language, metadata)
+		if emit != nil {
+			for _, i := range failed {
+				emit(i, results[i])
 			}
 		}
 	}
 
-	// 存储批处理项目（如果我们有批处理ID和分析ID）
-	if storeResults && len(analysisIDs) > 0 {
-		if err := s.repository.AddBatchItems(ctx, batchID, analysisIDs); err != nil {
-			logrus.WithError(err).Error("存储批处理项目失败")
-		} else {
-			// 更新批处理状态为已完成
-			if err := s.repository.UpdateBatchStatus(ctx, batchID, "completed"); err != nil {
-				logrus.WithError(err).Error("更新批处理状态失败")
-			}
-			logrus.WithField("batch_id", batchID).Debug("批处理项目已存储")
+	summary := summarizeItems(results)
+	batchResult := &models.BatchSentimentResult{
+		BatchID: batchID,
+		Results: results,
+		Status:  finalBatchStatus(summary.Total, summary.Failed, summary.Skipped),
+		Summary: summary,
+	}
+
+	// 记录批处理的最终状态
+	if batchAnalysis != nil {
+		completedAt := time.Now()
+		batchAnalysis.Status = batchResult.Status
+		batchAnalysis.ProcessedCount = summary.Total
+		batchAnalysis.FailedCount = summary.Failed
+		batchAnalysis.SkippedCount = summary.Skipped
+		batchAnalysis.Error = firstItemError(results)
+		batchAnalysis.CompletedAt = &completedAt
+		if err := s.repository.UpdateBatchProgress(ctx, batchAnalysis); err != nil {
+			logger.WithError(err).Error("更新批处理状态失败")
 		}
 	}
 
+	logger.WithFields(logrus.Fields{
+		"batch_id":  batchID,
+		"status":    batchResult.Status,
+		"succeeded": summary.Succeeded,
+		"failed":    summary.Failed,
+		"skipped":   summary.Skipped,
+	}).Debug("批量分析情感完成")
+
 	return batchResult, nil
 }
 
+// 历史记录的排序字段和方向
+const (
+	SortByCreatedAt = repositories.SortByCreatedAt
+	SortByScore     = repositories.SortByScore
+
+	SortAsc  = repositories.SortAsc
+	SortDesc = repositories.SortDesc
+)
+
+// HistoryQuery 历史记录的查询条件，为空的条件不参与过滤
+type HistoryQuery struct {
+	UserID    string
+	StartTime *time.Time
+	EndTime   *time.Time
+	Sentiment string
+	Language  string
+	MinScore  *float64
+	MaxScore  *float64
+	Keyword   string
+	BatchID   string
+	Metadata  map[string]string
+	// Query 全文检索查询（websearch 语法：空格表示同时包含，"短语"，or，-排除）
+	Query string
+	// SortBy 排序字段（SortByCreatedAt 或 SortByScore），默认按创建时间
+	SortBy string
+	// SortOrder 排序方向（SortAsc 或 SortDesc），默认倒序
+	SortOrder string
+	PageQuery
+}
+
+// params 转换为存储库查询参数
+func (q HistoryQuery) params() repositories.FindAnalysesParams {
+	return repositories.FindAnalysesParams{
+		UserID:    q.UserID,
+		StartTime: q.StartTime,
+		EndTime:   q.EndTime,
+		Sentiment: q.Sentiment,
+		Language:  q.Language,
+		MinScore:  q.MinScore,
+		MaxScore:  q.MaxScore,
+		Keyword:   q.Keyword,
+		BatchID:   q.BatchID,
+		Metadata:  q.Metadata,
+		Query:     q.Query,
+		SortBy:    q.sortBy(),
+		SortOrder: q.sortOrder(),
+		SkipCount: !q.IncludeTotal,
+		Limit:     pageWindow(q.PageQuery),
+		Offset:    q.Offset,
+	}
+}
+
+// sortBy 返回排序字段，未指定时按创建时间
+func (q HistoryQuery) sortBy() string {
+	if q.SortBy == "" {
+		return SortByCreatedAt
+	}
+	return q.SortBy
+}
+
+// sortOrder 返回排序方向，未指定时倒序
+func (q HistoryQuery) sortOrder() string {
+	if q.SortOrder == "" {
+		return SortDesc
+	}
+	return q.SortOrder
+}
+
 // GetAnalysisHistory 获取过去的情感分析历史
-func (s *SentimentService) GetAnalysisHistory(
-	ctx context.Context,
-	userId string,
-	startTime time.Time,
-	endTime time.Time,
-	limit int,
-	offset int,
-) (*models.AnalysisHistoryResult, error) {
-	// 构建搜索参数
-	params := repositories.FindAnalysesParams{
-		UserID:    userId,
-		StartTime: &startTime,
-		EndTime:   &endTime,
-		Limit:     limit,
-		Offset:    offset,
-	}
-
-	logrus.WithFields(logrus.Fields{
-		"user_id":    userId,
-		"start_time": startTime,
-		"end_time":   endTime,
-		"limit":      limit,
-		"offset":     offset,
+func (s *SentimentService) GetAnalysisHistory(ctx context.Context, query HistoryQuery) (*models.AnalysisHistoryResult, error) {
+	params := query.params()
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor, cursorKindHistory)
+		if err != nil {
+			return nil, err
+		}
+		// 游标中的排序值只对创建它的排序方式有意义
+		if cursor.SortBy != params.SortBy || cursor.SortOrder != params.SortOrder {
+			return nil, fmt.Errorf("%w: 游标与排序参数不一致", ErrInvalidCursor)
+		}
+		params.Cursor = cursor
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.WithFields(logrus.Fields{
+		"user_id":    query.UserID,
+		"start_time": query.StartTime,
+		"end_time":   query.EndTime,
+		"sentiment":  query.Sentiment,
+		"language":   query.Language,
+		"keyword":    query.Keyword,
+		"batch_id":   query.BatchID,
+		"metadata":   query.Metadata,
+		"sort":       params.SortBy,
+		"order":      params.SortOrder,
+		"limit":      query.Limit,
+		"offset":     query.Offset,
+		"cursor":     query.Cursor != "",
 	}).Debug("获取情感分析历史")
 
 	// 从存储库获取分析
@@ -332,6 +542,9 @@ func (s *SentimentService) GetAnalysisHistory(
 		return nil, err
 	}
 
+	start, end, hasPrev, hasNext := pageBounds(query.PageQuery, params.Cursor, len(analyses))
+	analyses = analyses[start:end]
+
 	// 构建结果
 	result := &models.AnalysisHistoryResult{
 		Records:    make([]models.AnalysisRecord, len(analyses)),
@@ -340,87 +553,253 @@ func (s *SentimentService) GetAnalysisHistory(
 
 	// 转换每个分析
 	for i, analysis := range analyses {
-		// 转换元数据为映射
-		metadata := make(map[string]string)
-		for _, meta := range analysis.Metadata {
-			metadata[meta.Key] = meta.Value
+		result.Records[i] = toAnalysisRecord(analysis)
+	}
+
+	if len(analyses) > 0 {
+		if hasNext {
+			result.NextCursor = analysisCursor(analyses[len(analyses)-1], params.SortBy, params.SortOrder, false)
+		}
+		if hasPrev {
+			result.PrevCursor = analysisCursor(analyses[0], params.SortBy, params.SortOrder, true)
+		}
+	}
+
+	return result, nil
+}
+
+// GetAnalysis 根据ID获取单条分析记录，不存在时返回 ErrAnalysisNotFound
+func (s *SentimentService) GetAnalysis(ctx context.Context, id string) (*models.AnalysisRecord, error) {
+	analysis, err := s.repository.GetAnalysisById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if analysis == nil {
+		return nil, ErrAnalysisNotFound
+	}
+
+	record := toAnalysisRecord(analysis)
+	return &record, nil
+}
+
+// GetAnalysisByRequestID 根据请求ID获取单条分析记录，不存在时返回 ErrAnalysisNotFound
+func (s *SentimentService) GetAnalysisByRequestID(ctx context.Context, requestID string) (*models.AnalysisRecord, error) {
+	analysis, err := s.repository.GetAnalysisByRequestId(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if analysis == nil {
+		return nil, ErrAnalysisNotFound
+	}
+
+	record := toAnalysisRecord(analysis)
+	return &record, nil
+}
+
+// GetBatch 获取批处理的状态，不存在时返回 ErrBatchNotFound
+func (s *SentimentService) GetBatch(ctx context.Context, batchID string) (*models.BatchRecord, error) {
+	batch, err := s.repository.GetBatchById(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, ErrBatchNotFound
+	}
+
+	itemCount, err := s.repository.CountBatchItems(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	record := toBatchRecord(batch)
+	record.ItemCount = int(itemCount)
+	return &record, nil
+}
+
+// GetBatchResults 按输入顺序分页获取批处理的分析结果，批处理不存在时返回 ErrBatchNotFound
+func (s *SentimentService) GetBatchResults(ctx context.Context, batchID string, page PageQuery) (*models.BatchResultsPage, error) {
+	params := repositories.FindBatchResultsParams{
+		Status:    models.BatchItemStatusSucceeded,
+		SkipCount: !page.IncludeTotal,
+		Limit:     pageWindow(page),
+		Offset:    page.Offset,
+	}
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor, cursorKindBatchResults)
+		if err != nil {
+			return nil, err
 		}
+		params.Cursor = cursor
+	}
 
-		// 添加到结果
-		result.Records[i] = models.AnalysisRecord{
-			ID:        analysis.ID,
-			Text:      analysis.Text,
-			Sentiment: analysis.Sentiment,
-			Score:     analysis.Score,
-			Timestamp: analysis.CreatedAt,
-			Metadata:  metadata,
+	batch, err := s.repository.GetBatchById(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, ErrBatchNotFound
+	}
+
+	items, count, err := s.repository.FindBatchResults(ctx, batchID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, hasPrev, hasNext := pageBounds(page, params.Cursor, len(items))
+	items = items[start:end]
+
+	result := &models.BatchResultsPage{
+		BatchID:    batchID,
+		Records:    make([]models.BatchResultRecord, 0, len(items)),
+		TotalCount: int(count),
+	}
+	for _, item := range items {
+		// 分析记录已被删除的项目不返回，游标仍按项目顺序前进
+		if item.Analysis == nil {
+			continue
+		}
+		result.Records = append(result.Records, models.BatchResultRecord{
+			Order:          item.Order,
+			ItemID:         item.ItemID,
+			AnalysisRecord: toAnalysisRecord(item.Analysis),
+		})
+	}
+
+	if len(items) > 0 {
+		if hasNext {
+			result.NextCursor = batchResultCursor(items[len(items)-1].Order, false)
+		}
+		if hasPrev {
+			result.PrevCursor = batchResultCursor(items[0].Order, true)
 		}
 	}
 
 	return result, nil
 }
 
-// 存储分析结果
+// ListBatches 获取批处理列表，按创建时间倒序
+func (s *SentimentService) ListBatches(ctx context.Context, userID, status string, limit, offset int) (*models.BatchListResult, error) {
+	batches, count, err := s.repository.FindBatches(ctx, repositories.FindBatchesParams{
+		UserID: userID,
+		Status: status,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.BatchListResult{
+		Batches:    make([]models.BatchRecord, len(batches)),
+		TotalCount: int(count),
+	}
+	for i, batch := range batches {
+		result.Batches[i] = toBatchRecord(batch)
+	}
+
+	return result, nil
+}
+
+// toBatchRecord 将数据库记录转换为服务层记录
+func toBatchRecord(batch *models.BatchAnalysis) models.BatchRecord {
+	return models.BatchRecord{
+		ID:             batch.ID,
+		UserID:         batch.UserID,
+		Status:         batch.Status,
+		Count:          batch.Count,
+		ProcessedCount: batch.ProcessedCount,
+		FailedCount:    batch.FailedCount,
+		SkippedCount:   batch.SkippedCount,
+		Error:          batch.Error,
+		StartedAt:      batch.StartedAt,
+		CompletedAt:    batch.CompletedAt,
+		EstimatedAt:    batch.EstimatedAt,
+		SourceFormat:   batch.SourceFormat,
+		CreatedAt:      batch.CreatedAt,
+		UpdatedAt:      batch.UpdatedAt,
+	}
+}
+
+// toAnalysisRecord 将数据库记录转换为服务层记录
+func toAnalysisRecord(analysis *models.SentimentAnalysis) models.AnalysisRecord {
+	// 转换元数据为映射
+	metadata := make(map[string]string)
+	for _, meta := range analysis.Metadata {
+		metadata[meta.Key] = meta.Value
+	}
+
+	// 关键词以逗号分隔存储
+	keywords := []string{}
+	if analysis.Keywords != "" {
+		keywords = strings.Split(analysis.Keywords, ",")
+	}
+
+	return models.AnalysisRecord{
+		ID:        analysis.ID,
+		RequestID: analysis.RequestID,
+		UserID:    analysis.UserID,
+		Text:      analysis.Text,
+		Sentiment: analysis.Sentiment,
+		Score:     analysis.Score,
+		Language:  analysis.Language,
+		Keywords:  keywords,
+		Timestamp: analysis.CreatedAt,
+		UpdatedAt: analysis.UpdatedAt,
+		Metadata:  metadata,
+	}
+}
+
+// StopAcceptingAsync 停止接受新的异步任务和批处理，进行中的批处理不再开始新的分块
+func (s *SentimentService) StopAcceptingAsync() {
+	s.shuttingDown.Store(true)
+}
+
+// DrainAsync 等待已提交异步任务的回调完成，返回超时后被放弃的请求ID
+func (s *SentimentService) DrainAsync(ctx context.Context) []string {
+	return s.mqClient.Drain(ctx)
+}
+
+// storeAnalysisResult 存储单条分析结果，设置了异步写入器时只加入写入队列
 func (s *SentimentService) storeAnalysisResult(
 	ctx context.Context,
 	result *models.SentimentResult,
 	language string,
 	metadata map[string]string,
 ) error {
-	// 将关键词转换为逗号分隔的字符串
-	keywordsStr := strings.Join(result.Keywords, ",")
-
-	// 创建分析记录
-	analysis := &models.SentimentAnalysis{
-		Text:      result.Text,
-		Sentiment: result.Sentiment,
-		Score:     result.Score,
-		Language:  language,
-		Keywords:  keywordsStr,
-		RequestID: result.RequestID,
-	}
-
-	// 添加用户ID（如果存在）
-	if userID, ok := metadata["user_id"]; ok {
-		analysis.UserID = userID
-	}
+	logger := logging.FromContext(ctx)
+	analysis := newAnalysisRecord(result, language, metadata)
 
-	// 添加元数据
-	for k, v := range metadata {
-		analysis.Metadata = append(analysis.Metadata, models.AnalysisMetadata{
-			Key:   k,
-			Value: v,
-		})
+	if s.writer != nil {
+		if err := s.writer.Enqueue(ctx, analysis); err != nil {
+			return err
+		}
+		logger.WithField("analysis_id", analysis.ID).Debug("情感分析记录已加入写入队列")
+		return nil
 	}
 
 	// 存储到数据库
 	if err := s.repository.CreateAnalysis(ctx, analysis); err != nil {
-		logrus.WithError(err).Error("存储情感分析记录失败")
+		logger.WithError(err).Error("存储情感分析记录失败")
 		return err
 	}
 
-	logrus.WithField("analysis_id", analysis.ID).Debug("情感分析记录已存储")
+	logger.WithField("analysis_id", analysis.ID).Debug("情感分析记录已存储")
 	return nil
 }
 
-// 为批处理存储分析结果，并返回分析ID
-func (s *SentimentService) storeAnalysisResultForBatch(
-	ctx context.Context,
+// newAnalysisRecord 为分析结果创建分析记录，ID在写入前生成，异步写入时也能用于去重
+func newAnalysisRecord(
 	result *models.SentimentResult,
 	language string,
 	metadata map[string]string,
-	batchID string,
-) (string, error) {
-	// 将关键词转换为逗号分隔的字符串
-	keywordsStr := strings.Join(result.Keywords, ",")
-
-	// 创建分析记录
+) *models.SentimentAnalysis {
 	analysis := &models.SentimentAnalysis{
+		ID:        uuid.New().String(),
 		Text:      result.Text,
 		Sentiment: result.Sentiment,
 		Score:     result.Score,
 		Language:  language,
-		Keywords:  keywordsStr,
+		Keywords:  strings.Join(result.Keywords, ","),
 		RequestID: result.RequestID,
 	}
 
@@ -430,6 +809,7 @@ func (s *SentimentService) storeAnalysisResultForBatch(
 	}
 
 	// 添加元数据
+	analysis.Metadata = make([]models.AnalysisMetadata, 0, len(metadata)+1)
 	for k, v := range metadata {
 		analysis.Metadata = append(analysis.Metadata, models.AnalysisMetadata{
 			Key:   k,
@@ -437,22 +817,93 @@ func (s *SentimentService) storeAnalysisResultForBatch(
 		})
 	}
 
-	// 添加批处理ID作为元数据
+	return analysis
+}
+
+// newBatchAnalysisRecord 为批处理中成功的结果创建分析记录，批处理ID作为元数据保存
+func newBatchAnalysisRecord(
+	result *models.SentimentResult,
+	language string,
+	metadata map[string]string,
+	batchID string,
+) *models.SentimentAnalysis {
+	analysis := newAnalysisRecord(result, language, metadata)
 	analysis.Metadata = append(analysis.Metadata, models.AnalysisMetadata{
 		Key:   "batch_id",
 		Value: batchID,
 	})
+	return analysis
+}
 
-	// 存储到数据库
-	if err := s.repository.CreateAnalysis(ctx, analysis); err != nil {
-		logrus.WithError(err).Error("存储情感分析记录失败")
-		return "", err
+// errStoreBatchResult 存储失败的项目的失败原因
+const errStoreBatchResult = "存储分析结果失败"
+
+// storeBatchResults 在一个事务中批量存储成功项目的分析记录和所有项目的状态及原始输入
+// startOrder 是 results 中第一个项目在批处理中的顺序；存储失败时成功的项目标记为失败，
+// 并尝试只记录各项目的状态，保证导出结果时每个输入都有对应的行。返回因存储失败而标记为失败的项目下标
+func (s *SentimentService) storeBatchResults(
+	ctx context.Context,
+	batchID string,
+	inputs []BatchInput,
+	results []models.BatchItemResult,
+	startOrder int,
+	language string,
+	metadata map[string]string,
+) []int {
+	var analyses []*models.SentimentAnalysis
+	items := make([]models.BatchItem, len(results))
+	var stored []int
+	for i := range results {
+		items[i] = models.BatchItem{
+			BatchID:   batchID,
+			Order:     startOrder + i,
+			ItemID:    results[i].ItemID,
+			Status:    results[i].Status,
+			Error:     results[i].Error,
+			SourceRow: inputs[i].Row,
+		}
+		if items[i].SourceRow == nil {
+			items[i].SourceRow = map[string]string{"text": inputs[i].Text}
+		}
+		if results[i].Status != models.BatchItemStatusSucceeded {
+			continue
+		}
+
+		result := results[i].Result
+		analysis := newBatchAnalysisRecord(result, language, mergeMetadata(metadata, result.Metadata), batchID)
+		analyses = append(analyses, analysis)
+		items[i].ItemID = result.ItemID
+		items[i].AnalysisID = &analysis.ID
+		stored = append(stored, i)
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"analysis_id": analysis.ID,
-		"batch_id":    batchID,
-	}).Debug("批处理情感分析记录已存储")
+	if len(items) == 0 {
+		return nil
+	}
 
-	return analysis.ID, nil
+	start := time.Now()
+	err := s.repository.CreateBatchResults(ctx, analyses, items)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("batch_id", batchID).Error("批量存储批处理结果失败")
+		// 数据库错误只记录在日志中，项目的失败原因会返回给客户端并保存
+		for _, i := range stored {
+			results[i].Status = models.BatchItemStatusFailed
+			results[i].Error = errStoreBatchResult
+			items[i].Status = results[i].Status
+			items[i].Error = results[i].Error
+			items[i].AnalysisID = nil
+		}
+		if err := s.repository.CreateBatchResults(ctx, nil, items); err != nil {
+			logging.FromContext(ctx).WithError(err).WithField("batch_id", batchID).Error("记录批处理项目状态失败")
+		}
+		return stored
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"batch_id":   batchID,
+		"count":      len(analyses),
+		"items":      len(items),
+		"elapsed_ms": time.Since(start).Milliseconds(),
+	}).Debug("批处理结果已存储")
+	return nil
 }
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// failingBatchRepository 无法创建批处理记录的仓库，其他方法不应被调用
+type failingBatchRepository struct {
+	repositories.SentimentRepository
+	err error
+}
+
+func (r *failingBatchRepository) CreateBatchAnalysis(context.Context, *models.BatchAnalysis) error {
+	return r.err
+}
+
+func TestBatchAnalyzeFailsWithoutBatchRecord(t *testing.T) {
+	dbErr := errors.New("connection refused")
+	// 分析器为nil，调用时会panic，用于确认没有开始分析
+	s := NewSentimentService(&failingBatchRepository{err: dbErr}, nil, nil)
+
+	var emitted int
+	result, err := s.StreamBatchAnalyzeSentiment(
+		context.Background(),
+		[]BatchInput{{Text: "很好"}, {Text: "很差"}},
+		"zh", true, nil,
+		func(int, models.BatchItemResult) { emitted++ },
+	)
+	if !errors.Is(err, dbErr) {
+		t.Fatalf("StreamBatchAnalyzeSentiment() 错误 = %v，期望包装 %v", err, dbErr)
+	}
+	if result != nil || emitted != 0 {
+		t.Errorf("无法创建批处理记录时返回了结果: %+v，输出 %d 项", result, emitted)
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"sentiment-service/internal/models"
+	"sentiment-service/internal/repositories"
+)
+
+// 趋势统计的时间粒度
+const (
+	IntervalMinute = repositories.IntervalMinute
+	IntervalHour   = repositories.IntervalHour
+	IntervalDay    = repositories.IntervalDay
+	IntervalWeek   = repositories.IntervalWeek
+)
+
+// IntervalDurations 每种时间粒度的时长（天和周按不考虑夏令时的长度计算），用于估算时间段数量
+var IntervalDurations = map[string]time.Duration{
+	IntervalMinute: time.Minute,
+	IntervalHour:   time.Hour,
+	IntervalDay:    24 * time.Hour,
+	IntervalWeek:   7 * 24 * time.Hour,
+}
+
+// StatsFilter 统计接口共用的过滤条件，时间范围为 [StartTime, EndTime)
+type StatsFilter struct {
+	StartTime time.Time
+	EndTime   time.Time
+	UserID    string
+	Language  string
+	Metadata  map[string]string
+}
+
+// params 转换为存储库的过滤条件
+func (f StatsFilter) params() repositories.StatsFilter {
+	return repositories.StatsFilter{
+		StartTime: f.StartTime,
+		EndTime:   f.EndTime,
+		UserID:    f.UserID,
+		Language:  f.Language,
+		Metadata:  f.Metadata,
+	}
+}
+
+// TrendQuery 情感趋势统计的条件
+type TrendQuery struct {
+	StatsFilter
+	Interval string
+	Location *time.Location
+}
+
+// SentimentTrend 按时间段统计各情感标签的数量和平均分数
+// 时间段按 query.Location 的本地时间对齐（例如按天统计时从当地零点开始，按周统计时从周一开始）
+func (s *SentimentService) SentimentTrend(ctx context.Context, query TrendQuery) (*models.TrendResult, error) {
+	location := query.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	rows, err := s.repository.SentimentTrend(ctx, repositories.TrendParams{
+		StatsFilter: query.params(),
+		Interval:    query.Interval,
+		Timezone:    location.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.TrendResult{Buckets: make([]models.TrendBucket, len(rows))}
+	for i, row := range rows {
+		result.Buckets[i] = models.TrendBucket{
+			Start:        row.Bucket.In(location),
+			Total:        int(row.Total),
+			Counts:       labelCounts(row.Positive, row.Negative, row.Neutral),
+			AverageScore: row.AvgScore,
+		}
+	}
+
+	return result, nil
+}
+
+// labelCounts 转换各情感标签的记录数
+func labelCounts(positive, negative, neutral int64) map[string]int {
+	return map[string]int{
+		"positive": int(positive),
+		"negative": int(negative),
+		"neutral":  int(neutral),
+	}
+}
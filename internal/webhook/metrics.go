@@ -0,0 +1,29 @@
+package webhook
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// 投递结果
+const (
+	resultDelivered = "delivered" // 接收方返回 2xx
+	resultRejected  = "rejected"  // 接收方返回 4xx 等不重试的状态码
+	resultFailed    = "failed"    // 重试次数用完仍然失败
+)
+
+var (
+	deliveriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "sentiment",
+		Subsystem: "webhook",
+		Name:      "deliveries_total",
+		Help:      "webhook 投递次数（不含重试）",
+	}, []string{"event", "result"})
+	retriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "sentiment",
+		Subsystem: "webhook",
+		Name:      "retries_total",
+		Help:      "因网络错误或 5xx 响应重试投递的次数",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(deliveriesTotal, retriesTotal)
+}
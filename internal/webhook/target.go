@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenTarget webhook 地址指向本机、链路本地或内网地址
+var ErrForbiddenTarget = errors.New("webhook 地址不能指向本机或内网")
+
+// forbiddenPrefixes 标准库未归类但同样不可路由到公网的地址段
+var forbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"), // 运营商级 NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF 协议分配
+	netip.MustParsePrefix("198.18.0.0/15"), // 网络基准测试
+}
+
+// allowedAddr 判断地址是否可以作为 webhook 目标
+func allowedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialControl 在建立连接前检查解析后的地址，避免域名解析到内网地址（包括解析结果在校验后改变）
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !allowedAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, addr)
+	}
+	return nil
+}
+
+// CheckURL 校验 webhook 地址：必须是 http 或 https 地址，且主机名解析出的所有地址都不是本机或内网地址
+// 发送时连接前还会再次检查，这里用于在保存规则时尽早拒绝
+func (s *Sender) CheckURL(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return errors.New("webhook_url 必须是 http 或 https 地址")
+	}
+	if s.opts.AllowPrivateTargets {
+		return nil
+	}
+
+	host := target.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !allowedAddr(addr) {
+			return ErrForbiddenTarget
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("无法解析 webhook 地址的主机名 %s", host)
+	}
+	for _, addr := range addrs {
+		if !allowedAddr(addr) {
+			return ErrForbiddenTarget
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
+)
+
+// 请求头
+const (
+	// HeaderEvent 事件类型
+	HeaderEvent = "X-Sentiment-Event"
+	// HeaderSignature 请求体的 HMAC-SHA256 签名，格式为 sha256=<十六进制>，未配置密钥时不发送
+	HeaderSignature = "X-Sentiment-Signature"
+)
+
+// maxDrainBody 读取并丢弃的响应体的最大字节数，读完响应体后连接可以复用
+// 响应体不会出现在错误信息中，避免通过告警记录读取接收方返回的内容
+const maxDrainBody = 64 << 10
+
+// Options webhook 投递的参数
+type Options struct {
+	// Timeout 单次请求的超时时间
+	Timeout time.Duration
+	// MaxRetries 网络错误或 5xx 响应的重试次数
+	MaxRetries int
+	// RetryBackoff 第一次重试前的等待时间，之后按次数线性增加
+	RetryBackoff time.Duration
+	// Secret 签名密钥，为空时不签名
+	Secret string
+	// AllowPrivateTargets 允许向本机和内网地址发送（仅用于开发和测试环境）
+	AllowPrivateTargets bool
+}
+
+// Sender 以 JSON POST 请求投递 webhook 事件
+type Sender struct {
+	client *http.Client
+	opts   Options
+}
+
+// New 创建 webhook 投递器
+// 默认拒绝连接本机和内网地址，不跟随重定向，也不使用环境变量中的代理（代理会绕过地址检查）
+func New(opts Options) *Sender {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivateTargets {
+		dialer.Control = dialControl
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Sender{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			// 重定向可能指向内网地址，3xx 响应按接收失败处理
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+	}
+}
+
+// Send 投递事件，网络错误和 5xx 响应按配置重试；接收方返回非 2xx 时返回错误
+func (s *Sender) Send(ctx context.Context, url, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化 webhook 内容失败: %w", err)
+	}
+
+	log := logging.FromContext(ctx).WithFields(logrus.Fields{
+		"url":   url,
+		"event": event,
+	})
+
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, url, event, body)
+		if err == nil {
+			deliveriesTotal.WithLabelValues(event, resultDelivered).Inc()
+			log.Debug("webhook 投递成功")
+			return nil
+		}
+		if !retry {
+			deliveriesTotal.WithLabelValues(event, resultRejected).Inc()
+			log.WithError(err).Warn("webhook 被接收方拒绝")
+			return err
+		}
+		if attempt >= s.opts.MaxRetries {
+			deliveriesTotal.WithLabelValues(event, resultFailed).Inc()
+			log.WithError(err).Warn("webhook 投递失败")
+			return err
+		}
+
+		retriesTotal.Inc()
+		log.WithError(err).WithField("attempt", attempt+1).Debug("webhook 投递失败，稍后重试")
+
+		select {
+		case <-ctx.Done():
+			deliveriesTotal.WithLabelValues(event, resultFailed).Inc()
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * s.opts.RetryBackoff):
+		}
+	}
+}
+
+// post 发送一次请求，返回错误是否可以重试
+func (s *Sender) post(ctx context.Context, url, event string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("创建 webhook 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	if s.opts.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(s.opts.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// 调用方取消或目标地址被拒绝时不再重试
+		retry := ctx.Err() == nil && !errors.Is(err, ErrForbiddenTarget)
+		return retry, fmt.Errorf("webhook 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBody))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return resp.StatusCode >= 500, fmt.Errorf("webhook 返回状态码 %d", resp.StatusCode)
+}
+
+// Sign 计算请求体的签名，接收方可用相同密钥验证 X-Sentiment-Signature
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
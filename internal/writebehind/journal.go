@@ -0,0 +1,148 @@
+package writebehind
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"sentiment-service/internal/models"
+)
+
+// 本地日志文件名格式，按创建时间排序即为写入顺序
+const (
+	segmentPattern = "journal-*.jsonl"
+	segmentFormat  = "journal-%020d.jsonl"
+)
+
+// journal 本地日志文件，每行一条JSON格式的分析记录
+// 记录追加到当前文件，重放前封存当前文件，之后的记录写入新文件
+type journal struct {
+	dir string
+
+	mu      sync.Mutex
+	current *os.File
+	closed  bool
+}
+
+// openJournal 打开本地日志文件目录，目录不存在时创建
+func openJournal(dir string) (*journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建本地日志文件目录失败: %v", err)
+	}
+	return &journal{dir: dir}, nil
+}
+
+// append 追加记录并同步到磁盘
+func (j *journal) append(records []*models.SentimentAnalysis) error {
+	var buf []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("编码分析记录失败: %v", err)
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return errors.New("本地日志文件已关闭")
+	}
+	if j.current == nil {
+		path := filepath.Join(j.dir, fmt.Sprintf(segmentFormat, time.Now().UnixNano()))
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		j.current = file
+	}
+
+	if _, err := j.current.Write(buf); err != nil {
+		return err
+	}
+	return j.current.Sync()
+}
+
+// seal 封存当前文件，返回所有已封存的文件，按写入顺序排列
+// 之后追加的记录写入新文件，不会出现在返回的列表中
+func (j *journal) seal() ([]string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.current != nil {
+		err := j.current.Close()
+		j.current = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	segments, err := filepath.Glob(filepath.Join(j.dir, segmentPattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// close 关闭当前文件，之后不再接受新记录
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.closed = true
+	if j.current == nil {
+		return nil
+	}
+	err := j.current.Close()
+	j.current = nil
+	return err
+}
+
+// readSegment 读取一个已封存的文件，返回其中的记录和无法解析的行数
+// 进程在写入过程中退出时最后一行可能不完整，按无法解析处理
+func readSegment(path string) ([]*models.SentimentAnalysis, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var records []*models.SentimentAnalysis
+	corrupt := 0
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			record := &models.SentimentAnalysis{}
+			if json.Unmarshal(line, record) == nil && record.ID != "" {
+				records = append(records, record)
+			} else {
+				corrupt++
+			}
+		}
+		if err == io.EOF {
+			return records, corrupt, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// removeSegment 删除已重放的文件
+func removeSegment(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package writebehind
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// 丢弃记录的原因
+const (
+	dropReasonRejected     = "rejected"      // 数据库拒绝写入（例如违反约束）
+	dropReasonJournalError = "journal_error" // 本地日志文件写入失败
+	dropReasonCorrupt      = "corrupt"       // 本地日志文件中的记录无法解析
+)
+
+var (
+	queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "sentiment",
+		Subsystem: "write_behind",
+		Name:      "queue_depth",
+		Help:      "等待写入数据库的分析记录数",
+	})
+	writtenTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "sentiment",
+		Subsystem: "write_behind",
+		Name:      "written_total",
+		Help:      "已写入数据库的分析记录数",
+	})
+	retriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "sentiment",
+		Subsystem: "write_behind",
+		Name:      "retries_total",
+		Help:      "因暂时性错误重试写入的次数",
+	})
+	journaledTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "sentiment",
+		Subsystem: "write_behind",
+		Name:      "journaled_total",
+		Help:      "写入本地日志文件的分析记录数",
+	})
+	replayedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "sentiment",
+		Subsystem: "write_behind",
+		Name:      "replayed_total",
+		Help:      "从本地日志文件重放到数据库的分析记录数",
+	})
+	droppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "sentiment",
+		Subsystem: "write_behind",
+		Name:      "dropped_total",
+		Help:      "被丢弃的分析记录数",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(queueDepth, writtenTotal, retriesTotal, journaledTotal, replayedTotal, droppedTotal)
+}
@@ -0,0 +1,340 @@
+package writebehind
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+
+	"sentiment-service/internal/logging"
+	"sentiment-service/internal/models"
+)
+
+// Store 批量写入分析记录的存储（默认实现为 repositories.SentimentRepository）
+// 同一条记录（按ID）可能被写入多次，实现必须跳过已存在的记录
+type Store interface {
+	InsertAnalyses(ctx context.Context, analyses []*models.SentimentAnalysis) error
+}
+
+// Options 异步写入的参数
+type Options struct {
+	// QueueSize 内存队列容量，队列满时记录直接写入本地日志文件
+	QueueSize int
+	// BatchSize 每次写入数据库的最大记录数
+	BatchSize int
+	// FlushInterval 未攒满一批时的最长等待时间
+	FlushInterval time.Duration
+	// MaxRetries 暂时性错误的重试次数，之后记录写入本地日志文件
+	MaxRetries int
+	// RetryBackoff 第一次重试前的等待时间，之后按次数线性增加
+	RetryBackoff time.Duration
+	// JournalDir 本地日志文件目录
+	JournalDir string
+	// ReplayInterval 检查并重放本地日志文件的间隔
+	ReplayInterval time.Duration
+}
+
+// Writer 在后台批量写入分析记录
+// 数据库暂时不可用时记录写入本地日志文件，恢复后按写入顺序重放
+type Writer struct {
+	store   Store
+	opts    Options
+	journal *journal
+
+	queue chan *models.SentimentAnalysis
+
+	// 保护 closed，Close 之后的记录直接写入本地日志文件
+	mu     sync.RWMutex
+	closed bool
+
+	// 关闭超时时取消，进行中的写入和重试立即结束
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// 写入数据库失败后置为true，之后的记录直接写入本地日志文件，直到重放成功（只在后台协程中访问）
+	degraded bool
+}
+
+// New 创建异步写入器并启动后台写入协程，启动时先重放上次运行遗留的本地日志文件
+func New(store Store, opts Options) (*Writer, error) {
+	journal, err := openJournal(opts.JournalDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Writer{
+		store:   store,
+		opts:    opts,
+		journal: journal,
+		queue:   make(chan *models.SentimentAnalysis, opts.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Enqueue 将分析记录加入写入队列，不等待写入数据库
+// 队列已满或写入器已关闭时直接写入本地日志文件，只有本地日志文件也写入失败时返回错误
+func (w *Writer) Enqueue(ctx context.Context, analysis *models.SentimentAnalysis) error {
+	now := time.Now()
+	if analysis.CreatedAt.IsZero() {
+		analysis.CreatedAt = now
+	}
+	if analysis.UpdatedAt.IsZero() {
+		analysis.UpdatedAt = now
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if !w.closed {
+		select {
+		case w.queue <- analysis:
+			queueDepth.Set(float64(len(w.queue)))
+			return nil
+		default:
+		}
+	}
+
+	logging.FromContext(ctx).WithField("analysis_id", analysis.ID).Debug("写入队列已满或已关闭，分析记录写入本地日志文件")
+	return w.spill(ctx, []*models.SentimentAnalysis{analysis})
+}
+
+// Close 停止接收新记录并写入队列中剩余的记录，ctx 结束时剩余记录写入本地日志文件
+func (w *Writer) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		logrus.Warn("关闭超时，剩余的分析记录写入本地日志文件")
+		w.cancel()
+		<-w.done
+	}
+	w.cancel()
+
+	return w.journal.close()
+}
+
+// run 后台写入协程：攒批写入数据库，并定期重放本地日志文件
+func (w *Writer) run() {
+	defer close(w.done)
+
+	w.replay()
+
+	flushTicker := time.NewTicker(w.opts.FlushInterval)
+	defer flushTicker.Stop()
+	replayTicker := time.NewTicker(w.opts.ReplayInterval)
+	defer replayTicker.Stop()
+
+	batch := make([]*models.SentimentAnalysis, 0, w.opts.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		w.flush(batch)
+		batch = make([]*models.SentimentAnalysis, 0, w.opts.BatchSize)
+	}
+
+	for {
+		select {
+		case analysis, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			queueDepth.Set(float64(len(w.queue)))
+			batch = append(batch, analysis)
+			if len(batch) >= w.opts.BatchSize {
+				flush()
+			}
+		case <-flushTicker.C:
+			flush()
+		case <-replayTicker.C:
+			w.replay()
+		}
+	}
+}
+
+// flush 写入一批记录，暂时性错误按配置重试，重试用尽后写入本地日志文件
+func (w *Writer) flush(batch []*models.SentimentAnalysis) {
+	if w.degraded {
+		w.spill(w.ctx, batch)
+		return
+	}
+
+	remaining := batch
+	for attempt := 0; ; attempt++ {
+		rest, err := w.write(remaining)
+		if err == nil {
+			return
+		}
+		remaining = rest
+
+		if attempt >= w.opts.MaxRetries || w.ctx.Err() != nil {
+			logrus.WithError(err).WithField("count", len(remaining)).Warn("数据库暂时不可用，分析记录写入本地日志文件")
+			w.degraded = true
+			w.spill(w.ctx, remaining)
+			return
+		}
+
+		retriesTotal.Inc()
+		logrus.WithError(err).WithField("attempt", attempt+1).Debug("写入分析记录失败，稍后重试")
+		w.sleep(time.Duration(attempt+1) * w.opts.RetryBackoff)
+	}
+}
+
+// write 写入一批记录，整批写入失败且不是暂时性错误时逐条写入并丢弃被拒绝的记录
+// 遇到暂时性错误时返回该错误和尚未写入的记录
+func (w *Writer) write(batch []*models.SentimentAnalysis) ([]*models.SentimentAnalysis, error) {
+	err := w.store.InsertAnalyses(w.ctx, batch)
+	if err == nil {
+		writtenTotal.Add(float64(len(batch)))
+		return nil, nil
+	}
+	if isTransient(err) {
+		return batch, err
+	}
+
+	// 整批被拒绝时无法确定是哪条记录的问题，逐条写入
+	for i, analysis := range batch {
+		err := w.store.InsertAnalyses(w.ctx, batch[i:i+1])
+		switch {
+		case err == nil:
+			writtenTotal.Inc()
+		case isTransient(err):
+			return batch[i:], err
+		default:
+			droppedTotal.WithLabelValues(dropReasonRejected).Inc()
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"analysis_id": analysis.ID,
+				"request_id":  analysis.RequestID,
+			}).Error("数据库拒绝写入分析记录，已丢弃")
+		}
+	}
+	return nil, nil
+}
+
+// replay 将本地日志文件中的记录按写入顺序写回数据库，全部写入后退出降级状态
+// 遇到暂时性错误时停止，未写完的文件保留到下次重放
+func (w *Writer) replay() {
+	segments, err := w.journal.seal()
+	if err != nil {
+		logrus.WithError(err).Error("读取本地日志文件失败")
+		return
+	}
+
+	for _, path := range segments {
+		records, corrupt, err := readSegment(path)
+		if err != nil {
+			logrus.WithError(err).WithField("path", path).Error("读取本地日志文件失败")
+			return
+		}
+		if corrupt > 0 {
+			droppedTotal.WithLabelValues(dropReasonCorrupt).Add(float64(corrupt))
+			logrus.WithFields(logrus.Fields{
+				"path":  path,
+				"count": corrupt,
+			}).Error("本地日志文件中有无法解析的记录，已丢弃")
+		}
+
+		for start := 0; start < len(records); start += w.opts.BatchSize {
+			end := start + w.opts.BatchSize
+			if end > len(records) {
+				end = len(records)
+			}
+			if _, err := w.write(records[start:end]); err != nil {
+				logrus.WithError(err).WithField("path", path).Debug("数据库仍不可用，稍后重放本地日志文件")
+				w.degraded = true
+				return
+			}
+		}
+
+		// 重放是幂等的，删除失败只会导致下次重复写入
+		if err := removeSegment(path); err != nil {
+			logrus.WithError(err).WithField("path", path).Error("删除已重放的本地日志文件失败")
+			return
+		}
+		replayedTotal.Add(float64(len(records)))
+		logrus.WithFields(logrus.Fields{
+			"path":  path,
+			"count": len(records),
+		}).Info("本地日志文件已重放")
+	}
+
+	if w.degraded {
+		logrus.Info("数据库已恢复，分析记录恢复直接写入")
+		w.degraded = false
+	}
+}
+
+// spill 将记录写入本地日志文件，失败时记录被丢弃
+func (w *Writer) spill(ctx context.Context, records []*models.SentimentAnalysis) error {
+	if err := w.journal.append(records); err != nil {
+		droppedTotal.WithLabelValues(dropReasonJournalError).Add(float64(len(records)))
+		logging.FromContext(ctx).WithError(err).WithField("count", len(records)).Error("写入本地日志文件失败，分析记录已丢弃")
+		return fmt.Errorf("写入本地日志文件失败: %v", err)
+	}
+	journaledTotal.Add(float64(len(records)))
+	return nil
+}
+
+// sleep 等待指定时间，写入器被取消时提前返回
+func (w *Writer) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-w.ctx.Done():
+	}
+}
+
+// isTransient 判断数据库错误是否可以通过重试恢复（连接失败、超时、数据库繁忙等）
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case strings.HasPrefix(pgErr.Code, "08"), // 连接异常
+			strings.HasPrefix(pgErr.Code, "53"),  // 资源不足
+			strings.HasPrefix(pgErr.Code, "57P"), // 数据库关闭或正在启动
+			pgErr.Code == "40001",                // 序列化失败
+			pgErr.Code == "40P01":                // 死锁
+			return true
+		}
+		return false
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	if pgconn.Timeout(err) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,306 @@
+package writebehind
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"sentiment-service/internal/models"
+)
+
+// fakeStore 按设定返回错误的存储
+type fakeStore struct {
+	mu sync.Mutex
+	// failures 接下来返回暂时性错误的次数
+	failures int
+	// reject 数据库拒绝写入的记录ID
+	reject map[string]bool
+	// written 已写入的记录ID，按写入顺序
+	written []string
+	calls   int
+}
+
+func (s *fakeStore) InsertAnalyses(_ context.Context, analyses []*models.SentimentAnalysis) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.failures > 0 {
+		s.failures--
+		return driver.ErrBadConn
+	}
+	for _, analysis := range analyses {
+		if s.reject[analysis.ID] {
+			return &pgconn.PgError{Code: "23514", Message: "违反检查约束"}
+		}
+	}
+	for _, analysis := range analyses {
+		s.written = append(s.written, analysis.ID)
+	}
+	return nil
+}
+
+func (s *fakeStore) writtenIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.written...)
+}
+
+// newTestWriter 创建不启动后台协程的写入器，测试直接调用 flush 和 replay
+func newTestWriter(t *testing.T, store Store, maxRetries int) *Writer {
+	t.Helper()
+	journal, err := openJournal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Writer{
+		store:   store,
+		opts:    Options{BatchSize: 2, MaxRetries: maxRetries},
+		journal: journal,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func analyses(ids ...string) []*models.SentimentAnalysis {
+	records := make([]*models.SentimentAnalysis, 0, len(ids))
+	for _, id := range ids {
+		records = append(records, &models.SentimentAnalysis{ID: id, Text: "text " + id})
+	}
+	return records
+}
+
+// journaledIDs 返回本地日志文件中的记录ID，按写入顺序
+func journaledIDs(t *testing.T, w *Writer) []string {
+	t.Helper()
+	segments, err := w.journal.seal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, path := range segments {
+		records, _, err := readSegment(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+	}
+	return ids
+}
+
+func TestFlush(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		reject     map[string]bool
+		maxRetries int
+		written    []string
+		journaled  []string
+		degraded   bool
+	}{
+		{
+			name:    "直接写入",
+			written: []string{"a", "b", "c"},
+		},
+		{
+			name:       "重试后写入",
+			failures:   2,
+			maxRetries: 2,
+			written:    []string{"a", "b", "c"},
+		},
+		{
+			name:       "重试用尽后写入本地日志文件",
+			failures:   3,
+			maxRetries: 2,
+			journaled:  []string{"a", "b", "c"},
+			degraded:   true,
+		},
+		{
+			name:    "丢弃被拒绝的记录",
+			reject:  map[string]bool{"b": true},
+			written: []string{"a", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{failures: tt.failures, reject: tt.reject}
+			w := newTestWriter(t, store, tt.maxRetries)
+
+			w.flush(analyses("a", "b", "c"))
+
+			if got := store.writtenIDs(); !reflect.DeepEqual(got, tt.written) {
+				t.Errorf("写入数据库的记录 = %v，期望 %v", got, tt.written)
+			}
+			if got := journaledIDs(t, w); !reflect.DeepEqual(got, tt.journaled) {
+				t.Errorf("写入本地日志文件的记录 = %v，期望 %v", got, tt.journaled)
+			}
+			if w.degraded != tt.degraded {
+				t.Errorf("degraded = %v，期望 %v", w.degraded, tt.degraded)
+			}
+		})
+	}
+}
+
+func TestFlushWhileDegraded(t *testing.T) {
+	store := &fakeStore{}
+	w := newTestWriter(t, store, 0)
+	w.degraded = true
+
+	w.flush(analyses("a", "b"))
+
+	if store.calls != 0 {
+		t.Errorf("降级时调用了数据库 %d 次", store.calls)
+	}
+	if got := journaledIDs(t, w); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("写入本地日志文件的记录 = %v", got)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	store := &fakeStore{failures: 1}
+	w := newTestWriter(t, store, 0)
+
+	// 数据库不可用，两批记录写入本地日志文件
+	w.flush(analyses("a", "b", "c"))
+	w.flush(analyses("d"))
+	if !w.degraded {
+		t.Fatal("写入失败后没有进入降级状态")
+	}
+
+	// 数据库仍不可用时保留本地日志文件
+	store.failures = 1
+	w.replay()
+	if !w.degraded || len(store.writtenIDs()) != 0 {
+		t.Fatalf("数据库不可用时重放结果不正确: degraded=%v written=%v", w.degraded, store.writtenIDs())
+	}
+
+	// 数据库恢复后按写入顺序重放并删除本地日志文件
+	w.replay()
+	if got, want := store.writtenIDs(), []string{"a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("重放的记录 = %v，期望 %v", got, want)
+	}
+	if w.degraded {
+		t.Error("重放成功后仍处于降级状态")
+	}
+	if segments, _ := w.journal.seal(); len(segments) != 0 {
+		t.Errorf("重放后仍有本地日志文件: %v", segments)
+	}
+}
+
+func TestReplaySkipsCorruptLines(t *testing.T) {
+	store := &fakeStore{}
+	w := newTestWriter(t, store, 0)
+
+	// 上次运行在写入最后一行时退出
+	content := `{"id":"a","text":"x"}` + "\n" + `not json` + "\n" + `{"text":"缺少ID"}` + "\n" + `{"id":"b","text":"x"}` + "\n" + `{"id":"c","te`
+	path := filepath.Join(w.journal.dir, fmt.Sprintf(segmentFormat, time.Now().UnixNano()))
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, corrupt, err := readSegment(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || corrupt != 3 {
+		t.Errorf("readSegment() 返回 %d 条记录、%d 行无法解析，期望 2 和 3", len(records), corrupt)
+	}
+
+	w.replay()
+	if got, want := store.writtenIDs(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("重放的记录 = %v，期望 %v", got, want)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("重放后本地日志文件仍然存在: %v", err)
+	}
+}
+
+func TestWriterReplaysJournalOnStart(t *testing.T) {
+	dir := t.TempDir()
+
+	// 上次运行遗留的本地日志文件
+	previous, err := openJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := previous.append(analyses("old-1", "old-2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := previous.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &fakeStore{}
+	w, err := New(store, Options{
+		QueueSize:      10,
+		BatchSize:      10,
+		FlushInterval:  time.Hour,
+		ReplayInterval: time.Hour,
+		JournalDir:     dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, analysis := range analyses("new-1", "new-2") {
+		if err := w.Enqueue(context.Background(), analysis); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := store.writtenIDs(), []string{"old-1", "old-2", "new-1", "new-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("写入的记录 = %v，期望 %v", got, want)
+	}
+
+	// 关闭后本地日志文件也已关闭，记录无法保存时返回错误
+	if err := w.Enqueue(context.Background(), analyses("late")[0]); err == nil {
+		t.Error("写入器关闭后 Enqueue 没有返回错误")
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"取消", context.Canceled, true},
+		{"超时", fmt.Errorf("写入失败: %w", context.DeadlineExceeded), true},
+		{"连接失效", driver.ErrBadConn, true},
+		{"连接异常", &pgconn.PgError{Code: "08006"}, true},
+		{"连接数过多", &pgconn.PgError{Code: "53300"}, true},
+		{"数据库正在关闭", &pgconn.PgError{Code: "57P01"}, true},
+		{"序列化失败", &pgconn.PgError{Code: "40001"}, true},
+		{"死锁", fmt.Errorf("写入失败: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{"违反唯一约束", &pgconn.PgError{Code: "23505"}, false},
+		{"数据过长", &pgconn.PgError{Code: "22001"}, false},
+		{"网络错误", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"其他错误", errors.New("invalid input"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v，期望 %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}